@@ -0,0 +1,91 @@
+package example
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// VisitedSet 记录已经见过的 URL，并发使用不安全。
+type VisitedSet interface {
+	// Add 添加 s，s 之前不在集合中时返回 true。
+	Add(s string) bool
+	Contains(s string) bool
+}
+
+// ExactSet 是基于 map 的 VisitedSet，内存占用随元素个数增长。
+type ExactSet map[string]struct{}
+
+func (es ExactSet) Add(s string) bool {
+	if _, ok := es[s]; ok {
+		return false
+	}
+	es[s] = struct{}{}
+	return true
+}
+
+func (es ExactSet) Contains(s string) bool {
+	_, ok := es[s]
+	return ok
+}
+
+// BloomFilter 是布隆过滤器，内存占用在创建时确定。Contains 可能把不在集合中的元素
+// 误判为在集合中（即 Add 可能误判为已存在并返回 false），但不会漏判。
+type BloomFilter struct {
+	bits []uint64
+	m    uint64 // 位数
+	k    uint64 // 哈希函数个数
+}
+
+// NewBloomFilter 返回可以容纳 n 个元素、误判率约为 fpRate 的布隆过滤器。
+func NewBloomFilter(n int, fpRate float64) *BloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+	m := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/float64(n)*math.Ln2))
+	bf := &BloomFilter{m: uint64(m), k: uint64(k)}
+	bf.bits = make([]uint64, (bf.m+63)/64)
+	return bf
+}
+
+// SizeBytes 返回位数组占用的字节数。
+func (bf *BloomFilter) SizeBytes() int {
+	return len(bf.bits) * 8
+}
+
+// locations 使用双重哈希 h1 + i*h2 生成 k 个位置。
+func (bf *BloomFilter) locations(s string, f func(idx uint64) bool) {
+	ha, hb := fnv.New64a(), fnv.New64()
+	ha.Write([]byte(s))
+	hb.Write([]byte(s))
+	h1, h2 := ha.Sum64(), hb.Sum64()|1 // h2 为奇数，避免步长为 0
+	for i := range bf.k {
+		if !f((h1 + i*h2) % bf.m) {
+			return
+		}
+	}
+}
+
+func (bf *BloomFilter) Add(s string) bool {
+	added := false
+	bf.locations(s, func(idx uint64) bool {
+		if bf.bits[idx/64]&(1<<(idx%64)) == 0 {
+			bf.bits[idx/64] |= 1 << (idx % 64)
+			added = true
+		}
+		return true
+	})
+	return added
+}
+
+func (bf *BloomFilter) Contains(s string) bool {
+	found := true
+	bf.locations(s, func(idx uint64) bool {
+		found = bf.bits[idx/64]&(1<<(idx%64)) != 0
+		return found
+	})
+	return found
+}
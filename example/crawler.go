@@ -0,0 +1,246 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContextFetcher 是可以被 ctx 取消的 Fetcher。Crawler 在 Fetcher 实现了该接口时
+// 会优先调用 FetchContext。
+type ContextFetcher interface {
+	Fetcher
+	FetchContext(ctx context.Context, url string) (body string, urls []string, err error)
+}
+
+// CrawlResult 是 Crawler 爬取一个页面的结果。Err 不为 nil 时 Body 和 URLs 为空，
+// 此时 URL 也为空表示 Frontier 出错，Crawl 随即结束。
+type CrawlResult struct {
+	URL   string
+	Depth int // 种子 URL 的深度为 0
+	Body  string
+	URLs  []string
+	Err   error
+}
+
+// CrawlerConfig 是 NewCrawler 的参数，零值字段使用默认值。
+type CrawlerConfig struct {
+	// MaxDepth 是爬取的最大深度，深度大于 MaxDepth 的 URL 不会被爬取，默认 0，即只爬取种子 URL。
+	MaxDepth int
+	// Concurrency 是同时进行的 Fetch 的最大数量，默认 4。
+	Concurrency int
+	// AllowedDomains 不为空时，只爬取 host 等于其中某个域名或是其子域名的 URL。
+	AllowedDomains []string
+	// PolitenessDelay 是对同一个 host 的两次 Fetch 之间的最小间隔。
+	PolitenessDelay time.Duration
+	// MaxPages 大于 0 时，每次 Crawl 最多爬取 MaxPages 个页面。
+	MaxPages int
+	// Frontier 不为 nil 时用于保存已见过和待爬取的 URL，Crawl 结束后可以用同一个
+	// Frontier 继续爬取；为 nil 时每次 Crawl 使用新的 MemoryFrontier。
+	// Crawl 不会关闭 Frontier，同一个 Frontier 不能同时被多个 Crawl 使用。
+	Frontier Frontier
+}
+
+// Crawler 是可复用的并发爬虫，同一个 Crawler 可以多次调用 Crawl，每次调用互不影响。
+type Crawler struct {
+	fetcher Fetcher
+	cfg     CrawlerConfig
+}
+
+func NewCrawler(fetcher Fetcher, cfg CrawlerConfig) *Crawler {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	return &Crawler{fetcher: fetcher, cfg: cfg}
+}
+
+// Crawl 从 seeds 开始广度优先地爬取页面，每个 URL 最多爬取一次。返回的 channel 在
+// 所有页面爬取完毕或 ctx 结束后被关闭，调用者需要读完或取消 ctx，否则爬虫会阻塞。
+// 使用 CrawlerConfig.Frontier 时，seeds 可以为空，表示继续爬取 Frontier 中剩余的 URL。
+func (c *Crawler) Crawl(ctx context.Context, seeds ...string) <-chan CrawlResult {
+	out := make(chan CrawlResult)
+	go c.run(ctx, seeds, out)
+	return out
+}
+
+func (c *Crawler) run(ctx context.Context, seeds []string, out chan<- CrawlResult) {
+	ctx, cancel := context.WithCancel(ctx)
+	tasks := make(chan FrontierItem)
+	done := make(chan CrawlResult)
+	gate := newHostGate(c.cfg.PolitenessDelay)
+	var wg sync.WaitGroup
+	wg.Add(c.cfg.Concurrency)
+	for range c.cfg.Concurrency {
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				r := c.fetch(ctx, gate, t)
+				select {
+				case done <- r:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	defer func() {
+		// 先让空闲的 worker 退出，再让阻塞在 done 上的 worker 退出
+		close(tasks)
+		cancel()
+		wg.Wait()
+		close(out)
+	}()
+
+	frontier := c.cfg.Frontier
+	if frontier == nil {
+		frontier = NewMemoryFrontier(nil)
+	}
+	fail := func(err error) {
+		select {
+		case out <- CrawlResult{Err: fmt.Errorf("frontier: %w", err)}:
+		case <-ctx.Done():
+		}
+	}
+	enqueue := func(u string, depth int) error {
+		if depth > c.cfg.MaxDepth || !c.allowed(u) {
+			return nil
+		}
+		_, err := frontier.Add(FrontierItem{URL: u, Depth: depth, Priority: depth})
+		return err
+	}
+	for _, s := range seeds {
+		if err := enqueue(s, 0); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	inFlight, fetched := 0, 0
+	var next *FrontierItem // 已从 frontier 取出但还没有发给 worker 的 URL
+	for {
+		if next == nil && (c.cfg.MaxPages <= 0 || fetched < c.cfg.MaxPages) {
+			item, ok, err := frontier.Next()
+			if err != nil {
+				fail(err)
+				return
+			}
+			if ok {
+				next = &item
+			}
+		}
+		if next == nil && inFlight == 0 {
+			return
+		}
+		// 没有待发送的 URL 时 sendCh 为 nil，select 不会选中发送的 case
+		var sendCh chan FrontierItem
+		var item FrontierItem
+		if next != nil {
+			sendCh, item = tasks, *next
+		}
+		select {
+		case sendCh <- item:
+			next = nil
+			inFlight++
+			fetched++
+		case r := <-done:
+			inFlight--
+			if ctx.Err() != nil {
+				// 结果可能因取消而不完整，不调用 Done，下次继续爬取时会重新爬取
+				return
+			}
+			if r.Err == nil {
+				for _, u := range r.URLs {
+					if err := enqueue(u, r.Depth+1); err != nil {
+						fail(err)
+						return
+					}
+				}
+			}
+			if err := frontier.Done(r.URL); err != nil {
+				fail(err)
+				return
+			}
+			select {
+			case out <- r:
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Crawler) fetch(ctx context.Context, gate *hostGate, t FrontierItem) CrawlResult {
+	r := CrawlResult{URL: t.URL, Depth: t.Depth}
+	if r.Err = gate.wait(ctx, hostOf(t.URL)); r.Err != nil {
+		return r
+	}
+	if cf, ok := c.fetcher.(ContextFetcher); ok {
+		r.Body, r.URLs, r.Err = cf.FetchContext(ctx, t.URL)
+	} else {
+		r.Body, r.URLs, r.Err = c.fetcher.Fetch(t.URL)
+	}
+	if r.Err != nil {
+		r.Body, r.URLs = "", nil
+	}
+	return r
+}
+
+func (c *Crawler) allowed(rawURL string) bool {
+	if len(c.cfg.AllowedDomains) == 0 {
+		return true
+	}
+	host := hostOf(rawURL)
+	for _, d := range c.cfg.AllowedDomains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOf 返回 URL 中不含端口的 host，解析失败时返回空字符串。
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// hostGate 保证对同一个 host 的两次请求之间至少间隔 delay。
+type hostGate struct {
+	delay time.Duration
+	mu    sync.Mutex
+	next  map[string]time.Time // 每个 host 下一次可以请求的时间
+}
+
+func newHostGate(delay time.Duration) *hostGate {
+	return &hostGate{delay: delay, next: make(map[string]time.Time)}
+}
+
+// wait 为 host 预约下一个可用的时间点并等待到那时。
+func (g *hostGate) wait(ctx context.Context, host string) error {
+	if g.delay <= 0 {
+		return ctx.Err()
+	}
+	g.mu.Lock()
+	now := time.Now()
+	at := g.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	g.next[host] = at.Add(g.delay)
+	g.mu.Unlock()
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
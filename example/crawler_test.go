@@ -0,0 +1,182 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// localSite 是一个链接成环的本地网站：/ -> /a, /b；/a -> /, /a/1；/b -> /a, /missing, 外部链接。
+// hits 记录每个路径被访问的时间，不包括 /robots.txt。
+type localSite struct {
+	*httptest.Server
+	mu   sync.Mutex
+	hits map[string][]time.Time
+}
+
+func newLocalSite(t *testing.T, handlerDelay time.Duration) *localSite {
+	pages := map[string]string{
+		"/":    `<a href="/a">a</a> <a href="b">b</a>`,
+		"/a":   `<a href="/">home</a> <a href="/a/1">a1</a>`,
+		"/a/1": `leaf`,
+		"/b":   `<a href="/a">a</a> <a href="/missing">x</a> <a href="http://example.com/">ext</a>`,
+	}
+	site := &localSite{hits: make(map[string][]time.Time)}
+	site.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			site.mu.Lock()
+			site.hits[r.URL.Path] = append(site.hits[r.URL.Path], time.Now())
+			site.mu.Unlock()
+		}
+		select {
+		case <-time.After(handlerDelay):
+		case <-r.Context().Done():
+			return
+		}
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, page)
+	}))
+	t.Cleanup(site.Close)
+	return site
+}
+
+func (site *localSite) Hits() map[string][]time.Time {
+	site.mu.Lock()
+	defer site.mu.Unlock()
+	ret := make(map[string][]time.Time, len(site.hits))
+	for k, v := range site.hits {
+		ret[k] = Clone(v)
+	}
+	return ret
+}
+
+func (site *localSite) Fetcher() *HTTPFetcher {
+	return NewHTTPFetcher(HTTPFetcherConfig{Client: site.Client()})
+}
+
+func collect(ch <-chan CrawlResult) (ok, failed []string) {
+	for r := range ch {
+		if r.Err != nil {
+			failed = append(failed, r.URL)
+		} else {
+			ok = append(ok, r.URL)
+		}
+	}
+	sort.Strings(ok)
+	sort.Strings(failed)
+	return
+}
+
+func TestCrawlerDepth(t *testing.T) {
+	site := newLocalSite(t, 0)
+	fetcher := site.Fetcher()
+	host := hostOf(site.URL)
+	testcases := []struct {
+		depth      int
+		ok, failed []string
+	}{
+		{0, []string{"/"}, nil},
+		{1, []string{"/", "/a", "/b"}, nil},
+		{2, []string{"/", "/a", "/a/1", "/b"}, []string{"/missing"}},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprint(tc.depth), func(t *testing.T) {
+			c := NewCrawler(fetcher, CrawlerConfig{MaxDepth: tc.depth, Concurrency: 3, AllowedDomains: []string{host}})
+			ok, failed := collect(c.Crawl(context.Background(), site.URL+"/"))
+			want := func(paths []string) []string {
+				ret := make([]string, len(paths))
+				for i, p := range paths {
+					ret[i] = site.URL + p
+				}
+				return ret
+			}
+			if !equalStrings(ok, want(tc.ok)) {
+				t.Errorf("ok: want %v, but %v", want(tc.ok), ok)
+			}
+			if !equalStrings(failed, want(tc.failed)) {
+				t.Errorf("failed: want %v, but %v", want(tc.failed), failed)
+			}
+		})
+	}
+	// "/" is linked from "/a" but each crawl should visit it only once
+	if hits := site.Hits()["/"]; len(hits) != len(testcases) {
+		t.Errorf("want %d visits of /, but %d", len(testcases), len(hits))
+	}
+}
+
+func TestCrawlerMaxPages(t *testing.T) {
+	site := newLocalSite(t, 0)
+	c := NewCrawler(site.Fetcher(), CrawlerConfig{MaxDepth: 10, MaxPages: 2, AllowedDomains: []string{hostOf(site.URL)}})
+	ok, failed := collect(c.Crawl(context.Background(), site.URL+"/"))
+	if len(ok)+len(failed) != 2 {
+		t.Errorf("want 2 pages, but %v %v", ok, failed)
+	}
+}
+
+func TestCrawlerPoliteness(t *testing.T) {
+	const delay = 40 * time.Millisecond
+	site := newLocalSite(t, 0)
+	c := NewCrawler(site.Fetcher(), CrawlerConfig{
+		MaxDepth:        2,
+		Concurrency:     4,
+		AllowedDomains:  []string{hostOf(site.URL)},
+		PolitenessDelay: delay,
+	})
+	collect(c.Crawl(context.Background(), site.URL+"/"))
+	var all []time.Time
+	for _, hits := range site.Hits() {
+		all = append(all, hits...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Before(all[j]) })
+	if len(all) != 5 {
+		t.Fatalf("want 5 requests, but %d", len(all))
+	}
+	// the server sees a request some time after the gate lets it go, so allow
+	// generous jitter on each gap; without the gate the gaps would be close to 0
+	for i := 1; i < len(all); i++ {
+		if gap := all[i].Sub(all[i-1]); gap < delay/2 {
+			t.Errorf("request %d came %v after the previous one", i, gap)
+		}
+	}
+	if span := all[len(all)-1].Sub(all[0]); span < 4*delay-delay/2 {
+		t.Errorf("5 requests took %v, want at least about %v", span, 4*delay)
+	}
+}
+
+func TestCrawlerCancel(t *testing.T) {
+	site := newLocalSite(t, time.Hour)
+	c := NewCrawler(site.Fetcher(), CrawlerConfig{MaxDepth: 2})
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	ch := c.Crawl(ctx, site.URL+"/", site.URL+"/a")
+	time.AfterFunc(20*time.Millisecond, cancelFunc)
+	done := make(chan struct{})
+	go func() {
+		collect(ch)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Crawl did not stop after cancel")
+	}
+}
+
+func TestCrawlerFakeFetcher(t *testing.T) {
+	c := NewCrawler(fetcher, CrawlerConfig{MaxDepth: 4, AllowedDomains: []string{"golang.org"}})
+	ok, failed := collect(c.Crawl(context.Background(), "https://golang.org/"))
+	wantOK := []string{"https://golang.org/", "https://golang.org/pkg/", "https://golang.org/pkg/fmt/", "https://golang.org/pkg/os/"}
+	if !equalStrings(ok, wantOK) || !equalStrings(failed, []string{"https://golang.org/cmd/"}) {
+		t.Errorf("unexpected result: %v %v", ok, failed)
+	}
+}
@@ -47,6 +47,7 @@ func mapUsage() {
 	fmt.Println("The value:", v, "Present?", ok)
 }
 
+// wordCount 只适合内存中的小段英文文本，流式统计、中文分词和 n-gram 见 example/wordfreq。
 func wordCount(s string) (m map[string]int) {
 	words := strings.Fields(s)
 	m = make(map[string]int)
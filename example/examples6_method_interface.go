@@ -7,6 +7,9 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example/geom"
+	"github.com/RinkoTaketsuki/GolangLearning/example/ipaddr"
 )
 
 // 方法不过是把 this 当成一个参数。
@@ -21,6 +24,11 @@ func (v *Vertex) Scale(f float64) {
 	v.Y = v.Y * f
 }
 
+// Vec2 将 Vertex 转换为 geom.Vec2，后者还支持加减、点积、叉积和仿射变换
+func (v Vertex) Vec2() geom.Vec2[float64] {
+	return geom.Vec2[float64]{X: v.X, Y: v.Y}
+}
+
 // Vertex 是 Abser，但 *Vertex 也是 Abser ?
 type Abser interface {
 	Abs() float64
@@ -74,6 +82,11 @@ func (ipAddr IPAddr) String() string {
 	return fmt.Sprintf("%d.%d.%d.%d", ipAddr[0], ipAddr[1], ipAddr[2], ipAddr[3])
 }
 
+// Addr 将 IPAddr 转换为 ipaddr.Addr，后者同时支持 IPv6、CIDR 前缀和黑白名单
+func (ipAddr IPAddr) Addr() ipaddr.Addr {
+	return ipaddr.AddrFrom4(ipAddr)
+}
+
 // 实现 error 接口的类型可以被 %v 格式化输出
 type MyError struct {
 	When time.Time
@@ -106,16 +119,16 @@ type rot13Reader struct {
 	r io.Reader
 }
 
+// Read 即使 err 不为 nil 也要处理 b[:n]，io.Reader 允许在返回 io.EOF 等错误的
+// 同时返回数据。更多的变换见 example/transform。
 func (rtr *rot13Reader) Read(b []byte) (int, error) {
 	n, err := rtr.r.Read(b)
-	if err == nil {
-		for i := 0; i < n; i++ {
-			c := b[i]
-			if 'A' <= c && c <= 'Z' {
-				b[i] = ((c - 'A' + 13) % 26) + 'A'
-			} else if 'a' <= c && c <= 'z' {
-				b[i] = ((c - 'a' + 13) % 26) + 'a'
-			}
+	for i := 0; i < n; i++ {
+		c := b[i]
+		if 'A' <= c && c <= 'Z' {
+			b[i] = ((c - 'A' + 13) % 26) + 'A'
+		} else if 'a' <= c && c <= 'z' {
+			b[i] = ((c - 'a' + 13) % 26) + 'a'
 		}
 	}
 	return n, err
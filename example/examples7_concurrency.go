@@ -1,6 +1,7 @@
 package example
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -57,7 +58,8 @@ func fibonacciSelectCh(c chan int, quit chan bool) {
 	}
 }
 
-// SafeCounter 的并发使用是安全的。
+// SafeCounter 的并发使用是安全的。所有 key 共用一把锁，key 很多且访问频繁时锁竞争
+// 会很严重，Registry 在它的基础上按 key 分片加锁，并支持标签、仪表盘和直方图。
 type SafeCounter struct {
 	v   map[string]int
 	mux sync.Mutex
@@ -151,11 +153,16 @@ loop:
 	}
 	time.Sleep(time.Second * 3)
 	fmt.Println(sc.Value("somekey"))
-	// 并发爬虫示范
-	if !us.testAndInsert("https://golang.org/") {
-		go Crawl("https://golang.org/", 4, fetcher)
+	// 并发爬虫示范，Crawl 只能靠 sleep 等待爬取完毕，而 Crawler 返回的 channel
+	// 关闭即表示爬取完毕
+	crawler := NewCrawler(fetcher, CrawlerConfig{MaxDepth: 3})
+	for r := range crawler.Crawl(context.Background(), "https://golang.org/") {
+		if r.Err != nil {
+			fmt.Println(r.Err)
+			continue
+		}
+		fmt.Printf("found: %s %q\n", r.URL, r.Body)
 	}
-	time.Sleep(time.Second * 10)
 }
 
 type Fetcher interface {
@@ -163,22 +170,20 @@ type Fetcher interface {
 	Fetch(url string) (body string, urls []string, err error)
 }
 
+// urlSet 在内存中记录所有见过的 URL，只适合小规模的爬取，大规模的爬取请使用
+// Crawler 和 FileFrontier。
 type urlSet struct {
 	mu sync.Mutex
-	s  map[string]bool
+	s  VisitedSet
 }
 
 func (us *urlSet) testAndInsert(url string) bool {
 	defer us.mu.Unlock()
 	us.mu.Lock()
-	if !us.s[url] {
-		us.s[url] = true
-		return false
-	}
-	return true
+	return !us.s.Add(url)
 }
 
-var us *urlSet = &urlSet{s: make(map[string]bool)}
+var us *urlSet = &urlSet{s: make(ExactSet)}
 
 // Crawl 使用 fetcher 从某个 URL 开始递归的爬取页面，直到达到最大深度。
 func Crawl(url string, depth int, fetcher Fetcher) {
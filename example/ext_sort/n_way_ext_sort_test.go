@@ -1,15 +1,16 @@
 package ext_sort
 
 import (
-	"crypto/rand"
 	"fmt"
 	"io"
-	"math/big"
+	"math/rand/v2"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example/rng/rngtest"
 )
 
 func TestExtMergeSort2way(t *testing.T) {
@@ -20,19 +21,11 @@ func TestExtMergeSort2way(t *testing.T) {
 		{1, 2},
 		{2, 1},
 	}
+	r := rngtest.Rand(t)
 	for i := 0; i < 100; i++ {
-		dataLenBig, err := rand.Int(rand.Reader, big.NewInt(1000))
-		if err != nil {
-			t.Error(err)
-		}
-		dataLen := dataLenBig.Uint64()
-		data := make([]uint64, dataLen)
+		data := make([]uint64, r.IntN(1000))
 		for i := range data {
-			val, err := rand.Int(rand.Reader, big.NewInt(100000))
-			if err != nil {
-				panic(err)
-			}
-			data[i] = val.Uint64()
+			data[i] = r.Uint64N(100000)
 		}
 		testcases = append(testcases, data)
 	}
@@ -48,14 +41,21 @@ func TestExtMergeSort2way(t *testing.T) {
 	}
 }
 
-func WriteFixedLengthRandomNumbersToFile(f *os.File, num int, upperBound *big.Int) error {
+// RandomNumbers 用 r 生成 num 个 [0, upperBound) 中的数。r 应当来自 rngtest.Rand，
+// 并且在所有子测试运行之前生成全部数据，这样用 -run 只重放一个子测试时数据也相同。
+func RandomNumbers(r *rand.Rand, num int, upperBound uint64) []uint64 {
+	nums := make([]uint64, num)
+	for i := range nums {
+		nums[i] = r.Uint64N(upperBound)
+	}
+	return nums
+}
+
+// WriteFixedLengthNumbersToFile 把 nums 从头写入 f，每个数占 8 字节。
+func WriteFixedLengthNumbersToFile(f *os.File, nums []uint64) error {
 	var sb strings.Builder
-	for range num {
-		i, err := rand.Int(rand.Reader, upperBound)
-		if err != nil {
-			return err
-		}
-		sb.WriteString(fmt.Sprintf("%08d", i.Uint64()))
+	for _, num := range nums {
+		sb.WriteString(fmt.Sprintf("%08d", num))
 	}
 	_, err := f.Seek(0, io.SeekStart)
 	if err != nil {
@@ -74,41 +74,37 @@ func TestExtMergeSortNWay(t *testing.T) {
 	defer file.Close()
 	type Case struct {
 		num        int
-		upperBound *big.Int
+		upperBound uint64
 		n          int
+		nums       []uint64
 	}
 	testcases := []Case{
-		{0, big.NewInt(10), 2},
-		{1, big.NewInt(10), 2},
-		{2, big.NewInt(10), 2},
-		{3, big.NewInt(10), 2},
-		{4, big.NewInt(10), 2},
+		{num: 0, upperBound: 10, n: 2},
+		{num: 1, upperBound: 10, n: 2},
+		{num: 2, upperBound: 10, n: 2},
+		{num: 3, upperBound: 10, n: 2},
+		{num: 4, upperBound: 10, n: 2},
 	}
+	r := rngtest.Rand(t)
 	for range 100 {
-		numBig, err := rand.Int(rand.Reader, big.NewInt(16))
-		if err != nil {
-			t.Fatal(err)
-		}
-		num := int(numBig.Int64())
-		upperBound, err := rand.Int(rand.Reader, big.NewInt(16))
-		if err != nil {
-			t.Fatal(err)
-		}
-		upperBound.Add(upperBound, big.NewInt(1))
-		n, err := rand.Int(rand.Reader, big.NewInt(8))
-		if err != nil {
-			t.Fatal(err)
-		}
-		if n.Cmp(big.NewInt(2)) < 0 {
+		num := r.IntN(16)
+		upperBound := r.Uint64N(16) + 1
+		n := r.IntN(8)
+		if n < 2 {
 			continue
 		}
-		testcases = append(testcases, Case{num, upperBound, int(n.Int64())})
+		testcases = append(testcases, Case{num: num, upperBound: upperBound, n: n})
+	}
+	for i := range testcases {
+		testcases[i].nums = RandomNumbers(r, testcases[i].num, testcases[i].upperBound)
 	}
 	for _, testcase := range testcases {
 		if !t.Run(
 			fmt.Sprintf("n: %d, number of elements: %d, element range: [0, %d)",
-				testcase.n, testcase.num, testcase.upperBound.Int64()), func(t *testing.T) {
-				WriteFixedLengthRandomNumbersToFile(file, testcase.num, testcase.upperBound)
+				testcase.n, testcase.num, testcase.upperBound), func(t *testing.T) {
+				if err := WriteFixedLengthNumbersToFile(file, testcase.nums); err != nil {
+					t.Fatal(err)
+				}
 				if err := ExtMergeSortNWay(file, 8, func(b1, b2 []byte) bool {
 					return strings.Compare(string(b1), string(b2)) < 0
 				}, testcase.n); err != nil {
@@ -0,0 +1,381 @@
+package example
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FrontierItem 是 Frontier 中一个待爬取的 URL。
+type FrontierItem struct {
+	URL   string
+	Depth int
+	// Priority 越小越先被取出，相同时先加入的先取出。
+	Priority int
+}
+
+// Frontier 保存爬虫已经见过的 URL 和待爬取的 URL，并发使用不安全。
+type Frontier interface {
+	// Add 在 item.URL 没有被添加过时将其加入待爬取队列并返回 true。
+	Add(item FrontierItem) (bool, error)
+	// Next 取出优先级最高的待爬取 URL，没有待爬取的 URL 时 ok 为 false。
+	Next() (item FrontierItem, ok bool, err error)
+	// Done 表示 Next 取出的 url 已经处理完毕。
+	Done(url string) error
+	// Len 返回待爬取的 URL 个数，不包括已被 Next 取出的。
+	Len() int
+	Close() error
+}
+
+// frontierHeap 是按 Priority 和加入顺序排序的小顶堆。
+type frontierHeap []frontierEntry
+
+type frontierEntry struct {
+	FrontierItem
+	seq uint64
+}
+
+func (h frontierHeap) Len() int { return len(h) }
+func (h frontierHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority < h[j].Priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h frontierHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *frontierHeap) Push(x any)   { *h = append(*h, x.(frontierEntry)) }
+func (h *frontierHeap) Pop() any {
+	var e frontierEntry
+	*h, e = PopBack(*h)
+	return e
+}
+
+// MemoryFrontier 是保存在内存中的 Frontier，Crawl 默认使用它。
+type MemoryFrontier struct {
+	visited VisitedSet
+	queue   frontierHeap
+	seq     uint64
+}
+
+// NewMemoryFrontier 返回使用 visited 记录已见过的 URL 的 MemoryFrontier，
+// visited 为 nil 时使用 ExactSet。
+func NewMemoryFrontier(visited VisitedSet) *MemoryFrontier {
+	if visited == nil {
+		visited = make(ExactSet)
+	}
+	return &MemoryFrontier{visited: visited}
+}
+
+func (mf *MemoryFrontier) Add(item FrontierItem) (bool, error) {
+	if !mf.visited.Add(item.URL) {
+		return false, nil
+	}
+	heap.Push(&mf.queue, frontierEntry{item, mf.seq})
+	mf.seq++
+	return true, nil
+}
+
+func (mf *MemoryFrontier) Next() (FrontierItem, bool, error) {
+	if len(mf.queue) == 0 {
+		return FrontierItem{}, false, nil
+	}
+	return heap.Pop(&mf.queue).(frontierEntry).FrontierItem, true, nil
+}
+
+func (mf *MemoryFrontier) Done(string) error { return nil }
+func (mf *MemoryFrontier) Len() int          { return len(mf.queue) }
+func (mf *MemoryFrontier) Close() error      { return nil }
+
+// FileFrontierOptions 是 OpenFileFrontier 的参数，零值字段使用默认值。
+type FileFrontierOptions struct {
+	// Bloom 为 true 时使用布隆过滤器记录已见过的 URL，内存占用固定，
+	// 但可能因为误判而漏掉少量 URL；为 false 时使用 ExactSet。
+	Bloom bool
+	// ExpectedURLs 是布隆过滤器预计容纳的 URL 个数，默认 1000000。
+	ExpectedURLs int
+	// FalsePositiveRate 是布隆过滤器的误判率，默认 0.001。
+	FalsePositiveRate float64
+	// CheckpointEvery 是两次保存读取进度之间 Done 的次数，默认 100。
+	// 进程崩溃时最多会重复爬取这么多个 URL。
+	CheckpointEvery int
+}
+
+const frontierCursorFile = "cursor.json"
+
+// FileFrontier 是保存在目录中的 Frontier。每个优先级的待爬取队列是一个只追加的文件，
+// 每行是 "depth url"，内存中只保存每个队列的读取位置和已见过的 URL 集合。
+// 重新打开同一个目录即可从上次停止的地方继续爬取，被 Next 取出但没有 Done 的 URL
+// 会被再次取出。
+type FileFrontier struct {
+	dir        string
+	opts       FileFrontierOptions
+	visited    VisitedSet
+	queues     map[int]*fileQueue
+	priorities []int // 升序
+	taken      map[string]takenItem
+	pending    int
+	dones      int
+}
+
+// fileQueue 是一个优先级的队列文件。
+type fileQueue struct {
+	w        *os.File
+	r        *os.File
+	br       *bufio.Reader
+	readOff  int64
+	writeOff int64
+	taken    map[int64]int // 被取出但没有 Done 的行的起始位置，值为引用计数
+}
+
+type takenItem struct {
+	priority int
+	off      int64
+}
+
+// OpenFileFrontier 打开 dir 中的 FileFrontier，dir 不存在时会被创建。
+func OpenFileFrontier(dir string, opts FileFrontierOptions) (*FileFrontier, error) {
+	if opts.ExpectedURLs <= 0 {
+		opts.ExpectedURLs = 1000000
+	}
+	if opts.FalsePositiveRate <= 0 || opts.FalsePositiveRate >= 1 {
+		opts.FalsePositiveRate = 0.001
+	}
+	if opts.CheckpointEvery <= 0 {
+		opts.CheckpointEvery = 100
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	ff := &FileFrontier{
+		dir:    dir,
+		opts:   opts,
+		queues: make(map[int]*fileQueue),
+		taken:  make(map[string]takenItem),
+	}
+	if opts.Bloom {
+		ff.visited = NewBloomFilter(opts.ExpectedURLs, opts.FalsePositiveRate)
+	} else {
+		ff.visited = make(ExactSet)
+	}
+	cursors := make(map[int]int64)
+	if b, err := os.ReadFile(filepath.Join(dir, frontierCursorFile)); err == nil {
+		if err := json.Unmarshal(b, &cursors); err != nil {
+			return nil, fmt.Errorf("frontier cursor: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*.q"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		p, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(name), ".q"))
+		if err != nil {
+			continue
+		}
+		if err := ff.replay(p, cursors[p]); err != nil {
+			ff.closeFiles()
+			return nil, err
+		}
+	}
+	return ff, nil
+}
+
+func (ff *FileFrontier) queuePath(priority int) string {
+	return filepath.Join(ff.dir, strconv.Itoa(priority)+".q")
+}
+
+// replay 读取一个队列文件，将其中所有 URL 加入 visited，并统计 cursor 之后待爬取的个数。
+// 文件末尾不完整的行会被截断。
+func (ff *FileFrontier) replay(priority int, cursor int64) error {
+	q, err := ff.queue(priority)
+	if err != nil {
+		return err
+	}
+	var off int64
+	br := bufio.NewReader(io.NewSectionReader(q.r, 0, q.writeOff))
+	for {
+		line, err := br.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		if item, ok := parseFrontierLine(line); ok {
+			ff.visited.Add(item.URL)
+			if off >= cursor {
+				ff.pending++
+			}
+		}
+		off += int64(len(line))
+	}
+	if off != q.writeOff {
+		if err := q.w.Truncate(off); err != nil {
+			return err
+		}
+		q.writeOff = off
+	}
+	if cursor > off {
+		cursor = off
+	}
+	q.readOff = cursor
+	_, err = q.r.Seek(cursor, io.SeekStart)
+	q.br.Reset(q.r)
+	return err
+}
+
+// queue 返回优先级为 priority 的队列，不存在时创建。
+func (ff *FileFrontier) queue(priority int) (*fileQueue, error) {
+	if q, ok := ff.queues[priority]; ok {
+		return q, nil
+	}
+	path := ff.queuePath(priority)
+	w, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	r, err := os.Open(path)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	fi, err := w.Stat()
+	if err != nil {
+		w.Close()
+		r.Close()
+		return nil, err
+	}
+	q := &fileQueue{w: w, r: r, br: bufio.NewReader(r), writeOff: fi.Size(), taken: make(map[int64]int)}
+	ff.queues[priority] = q
+	i, _ := slices.BinarySearch(ff.priorities, priority)
+	ff.priorities = slices.Insert(ff.priorities, i, priority)
+	return q, nil
+}
+
+func parseFrontierLine(line string) (FrontierItem, bool) {
+	line, ok := strings.CutSuffix(line, "\n")
+	if !ok {
+		return FrontierItem{}, false
+	}
+	depth, u, ok := strings.Cut(line, " ")
+	if !ok {
+		return FrontierItem{}, false
+	}
+	d, err := strconv.Atoi(depth)
+	if err != nil {
+		return FrontierItem{}, false
+	}
+	return FrontierItem{URL: u, Depth: d}, true
+}
+
+func (ff *FileFrontier) Add(item FrontierItem) (bool, error) {
+	if strings.ContainsAny(item.URL, "\r\n") {
+		return false, fmt.Errorf("frontier: invalid URL %q", item.URL)
+	}
+	if ff.visited.Contains(item.URL) {
+		return false, nil
+	}
+	q, err := ff.queue(item.Priority)
+	if err != nil {
+		return false, err
+	}
+	n, err := fmt.Fprintf(q.w, "%d %s\n", item.Depth, item.URL)
+	q.writeOff += int64(n)
+	if err != nil {
+		return false, err
+	}
+	ff.visited.Add(item.URL)
+	ff.pending++
+	return true, nil
+}
+
+func (ff *FileFrontier) Next() (FrontierItem, bool, error) {
+	for _, p := range ff.priorities {
+		q := ff.queues[p]
+		if q.readOff >= q.writeOff {
+			continue
+		}
+		line, err := q.br.ReadString('\n')
+		if err != nil {
+			return FrontierItem{}, false, err
+		}
+		item, ok := parseFrontierLine(line)
+		if !ok {
+			return FrontierItem{}, false, fmt.Errorf("frontier: corrupt line %q in %s", line, ff.queuePath(p))
+		}
+		item.Priority = p
+		q.taken[q.readOff]++
+		ff.taken[item.URL] = takenItem{p, q.readOff}
+		q.readOff += int64(len(line))
+		ff.pending--
+		return item, true, nil
+	}
+	return FrontierItem{}, false, nil
+}
+
+func (ff *FileFrontier) Done(url string) error {
+	t, ok := ff.taken[url]
+	if !ok {
+		return nil
+	}
+	delete(ff.taken, url)
+	q := ff.queues[t.priority]
+	if q.taken[t.off]--; q.taken[t.off] == 0 {
+		delete(q.taken, t.off)
+	}
+	if ff.dones++; ff.dones >= ff.opts.CheckpointEvery {
+		return ff.checkpoint()
+	}
+	return nil
+}
+
+func (ff *FileFrontier) Len() int {
+	return ff.pending
+}
+
+// checkpoint 保存每个队列中最早的没有 Done 的行的位置。
+func (ff *FileFrontier) checkpoint() error {
+	ff.dones = 0
+	cursors := make(map[int]int64, len(ff.queues))
+	for p, q := range ff.queues {
+		cursors[p] = q.readOff
+		for off := range q.taken {
+			cursors[p] = min(cursors[p], off)
+		}
+	}
+	b, err := json.Marshal(cursors)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(ff.dir, frontierCursorFile+".tmp")
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(ff.dir, frontierCursorFile))
+}
+
+// Close 保存读取进度并关闭所有文件，重复调用没有效果。
+func (ff *FileFrontier) Close() error {
+	if ff.queues == nil {
+		return nil
+	}
+	return errors.Join(ff.checkpoint(), ff.closeFiles())
+}
+
+func (ff *FileFrontier) closeFiles() error {
+	var err error
+	for _, q := range ff.queues {
+		err = errors.Join(err, q.w.Close(), q.r.Close())
+	}
+	ff.queues = nil
+	return err
+}
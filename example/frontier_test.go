@@ -0,0 +1,171 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBloomFilter(t *testing.T) {
+	const n, fpRate = 10000, 0.01
+	bf := NewBloomFilter(n, fpRate)
+	for i := range n {
+		bf.Add(fmt.Sprint("in", i))
+	}
+	for i := range n {
+		if !bf.Contains(fmt.Sprint("in", i)) {
+			t.Fatalf("false negative for %d", i)
+		}
+	}
+	fp := 0
+	for i := range n {
+		if bf.Contains(fmt.Sprint("out", i)) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / n; rate > 2*fpRate {
+		t.Errorf("false positive rate %v, want about %v", rate, fpRate)
+	}
+	// m = -n*ln(p)/ln(2)^2 ≈ 9.6 bits per element
+	if sz := bf.SizeBytes(); sz > n*10/8+8 {
+		t.Errorf("unexpected size %d", sz)
+	}
+}
+
+// drainFrontier 取出 f 中所有的 URL 并调用 Done。
+func drainFrontier(t *testing.T, f Frontier) []string {
+	t.Helper()
+	var urls []string
+	for {
+		item, ok, err := f.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			return urls
+		}
+		urls = append(urls, item.URL)
+		if err := f.Done(item.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFrontierOrder(t *testing.T) {
+	ff, err := OpenFileFrontier(t.TempDir(), FileFrontierOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ff.Close()
+	for name, f := range map[string]Frontier{"memory": NewMemoryFrontier(nil), "file": ff} {
+		t.Run(name, func(t *testing.T) {
+			items := []FrontierItem{{"a", 0, 2}, {"b", 0, 1}, {"c", 0, 2}, {"b", 0, 0}, {"d", 0, 1}, {"e", 0, -1}}
+			wantAdded := []bool{true, true, true, false, true, true}
+			for i, item := range items {
+				if added, err := f.Add(item); err != nil || added != wantAdded[i] {
+					t.Errorf("Add(%v): want %v, but %v %v", item, wantAdded[i], added, err)
+				}
+			}
+			if f.Len() != 5 {
+				t.Errorf("want Len 5, but %d", f.Len())
+			}
+			want := []string{"e", "b", "d", "a", "c"}
+			if got := drainFrontier(t, f); !equalStrings(got, want) {
+				t.Errorf("want %v, but %v", want, got)
+			}
+			if added, _ := f.Add(FrontierItem{URL: "a"}); added {
+				t.Error("visited URL added again")
+			}
+		})
+	}
+}
+
+func TestFileFrontierResume(t *testing.T) {
+	dir := t.TempDir()
+	for _, bloom := range []bool{false, true} {
+		t.Run(fmt.Sprint("bloom=", bloom), func(t *testing.T) {
+			dir := filepath.Join(dir, fmt.Sprint(bloom))
+			opts := FileFrontierOptions{Bloom: bloom, ExpectedURLs: 100, CheckpointEvery: 1}
+			ff, err := OpenFileFrontier(dir, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range 6 {
+				ff.Add(FrontierItem{URL: fmt.Sprint("u", i), Depth: i % 2, Priority: i % 2})
+			}
+			// u0 is done, u2 is taken but not done
+			for _, done := range []bool{true, false} {
+				item, _, _ := ff.Next()
+				if done {
+					ff.Done(item.URL)
+				}
+			}
+			if err := ff.Close(); err != nil {
+				t.Fatal(err)
+			}
+			// simulate a torn write at the tail
+			f, err := os.OpenFile(filepath.Join(dir, "1.q"), os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteString("1 torn")
+			f.Close()
+
+			ff, err = OpenFileFrontier(dir, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ff.Close()
+			if ff.Len() != 5 {
+				t.Errorf("want Len 5, but %d", ff.Len())
+			}
+			if added, _ := ff.Add(FrontierItem{URL: "u0"}); added {
+				t.Error("u0 added again after resume")
+			}
+			if added, _ := ff.Add(FrontierItem{URL: "torn", Priority: 1}); !added {
+				t.Error("torn URL should not be visited")
+			}
+			want := []string{"u2", "u4", "u1", "u3", "u5", "torn"}
+			if got := drainFrontier(t, ff); !equalStrings(got, want) {
+				t.Errorf("want %v, but %v", want, got)
+			}
+		})
+	}
+}
+
+func TestCrawlerResume(t *testing.T) {
+	site := newLocalSite(t, 0)
+	dir := t.TempDir()
+	cfg := CrawlerConfig{MaxDepth: 2, Concurrency: 1, MaxPages: 2, AllowedDomains: []string{hostOf(site.URL)}}
+	var all []string
+	for i := 0; ; i++ {
+		ff, err := OpenFileFrontier(dir, FileFrontierOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Frontier = ff
+		var seeds []string
+		if i == 0 {
+			seeds = []string{site.URL + "/"}
+		}
+		ok, failed := collect(NewCrawler(site.Fetcher(), cfg).Crawl(context.Background(), seeds...))
+		if err := ff.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if len(ok)+len(failed) == 0 {
+			break
+		}
+		all = append(all, ok...)
+		all = append(all, failed...)
+	}
+	if len(all) != 5 {
+		t.Errorf("want 5 pages, but %v", all)
+	}
+	for path, hits := range site.Hits() {
+		if len(hits) != 1 {
+			t.Errorf("%s fetched %d times", path, len(hits))
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package example
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// EarthRadius 是地球的平均半径，单位为米。
+const EarthRadius = 6371008.8
+
+func toRadians(deg float64) float64 { return deg * math.Pi / 180 }
+func toDegrees(rad float64) float64 { return rad * 180 / math.Pi }
+
+// Valid 判断纬度是否在 [-90, 90] 内且经度是否在 [-180, 180] 内。
+func (c Coordinate) Valid() bool {
+	return c.Lat >= -90 && c.Lat <= 90 && c.Long >= -180 && c.Long <= 180
+}
+
+// DistanceTo 用 haversine 公式计算 c 到 o 的大圆距离，单位为米。
+func (c Coordinate) DistanceTo(o Coordinate) float64 {
+	lat1, lat2 := toRadians(c.Lat), toRadians(o.Lat)
+	dLat, dLong := lat2-lat1, toRadians(o.Long-c.Long)
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLong/2), 2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// normalizeLong 将经度转换到 [-180, 180) 内。
+func normalizeLong(long float64) float64 {
+	long = math.Mod(long+180, 360)
+	if long < 0 {
+		long += 360
+	}
+	return long - 180
+}
+
+// BoundingBox 是经纬度围成的矩形。Min.Long 大于 Max.Long 时表示矩形跨越了 180 度经线。
+type BoundingBox struct {
+	Min, Max Coordinate
+}
+
+// BoundingBoxOf 返回包含所有 points 的最小的矩形，不考虑跨越 180 度经线的情况。
+func BoundingBoxOf(points ...Coordinate) BoundingBox {
+	if len(points) == 0 {
+		return BoundingBox{}
+	}
+	bb := BoundingBox{points[0], points[0]}
+	for _, p := range points[1:] {
+		bb.Min.Lat, bb.Min.Long = min(bb.Min.Lat, p.Lat), min(bb.Min.Long, p.Long)
+		bb.Max.Lat, bb.Max.Long = max(bb.Max.Lat, p.Lat), max(bb.Max.Long, p.Long)
+	}
+	return bb
+}
+
+// BoundingBoxAround 返回包含以 center 为圆心、radius 米为半径的圆的矩形。
+// 圆包含极点时矩形覆盖所有经度。
+func BoundingBoxAround(center Coordinate, radius float64) BoundingBox {
+	dLat := toDegrees(radius / EarthRadius)
+	bb := BoundingBox{
+		Min: Coordinate{Lat: center.Lat - dLat},
+		Max: Coordinate{Lat: center.Lat + dLat},
+	}
+	if bb.Min.Lat <= -90 || bb.Max.Lat >= 90 {
+		bb.Min.Lat, bb.Max.Lat = max(bb.Min.Lat, -90), min(bb.Max.Lat, 90)
+		bb.Min.Long, bb.Max.Long = -180, 180
+		return bb
+	}
+	// 圆上经度跨度最大的点的经度差
+	dLong := toDegrees(math.Asin(math.Sin(radius/EarthRadius) / math.Cos(toRadians(center.Lat))))
+	if math.IsNaN(dLong) || dLong >= 180 {
+		bb.Min.Long, bb.Max.Long = -180, 180
+		return bb
+	}
+	bb.Min.Long, bb.Max.Long = normalizeLong(center.Long-dLong), normalizeLong(center.Long+dLong)
+	if bb.Max.Long == -180 {
+		bb.Max.Long = 180
+	}
+	return bb
+}
+
+// CrossesAntimeridian 判断矩形是否跨越了 180 度经线。
+func (bb BoundingBox) CrossesAntimeridian() bool {
+	return bb.Min.Long > bb.Max.Long
+}
+
+func (bb BoundingBox) Contains(c Coordinate) bool {
+	if c.Lat < bb.Min.Lat || c.Lat > bb.Max.Lat {
+		return false
+	}
+	if bb.CrossesAntimeridian() {
+		return c.Long >= bb.Min.Long || c.Long <= bb.Max.Long
+	}
+	return c.Long >= bb.Min.Long && c.Long <= bb.Max.Long
+}
+
+// split 将跨越 180 度经线的矩形分为两个不跨越的矩形。
+func (bb BoundingBox) split() []BoundingBox {
+	if !bb.CrossesAntimeridian() {
+		return []BoundingBox{bb}
+	}
+	return []BoundingBox{
+		{bb.Min, Coordinate{bb.Max.Lat, 180}},
+		{Coordinate{bb.Min.Lat, -180}, bb.Max},
+	}
+}
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// GeohashEncode 返回 c 的长度为 precision 的 geohash。
+func GeohashEncode(c Coordinate, precision int) string {
+	latRange, longRange := [2]float64{-90, 90}, [2]float64{-180, 180}
+	var sb strings.Builder
+	even, bit, ch := true, 0, 0
+	for sb.Len() < precision {
+		// 偶数位编码经度，奇数位编码纬度
+		rng, v := &latRange, c.Lat
+		if even {
+			rng, v = &longRange, c.Long
+		}
+		mid := (rng[0] + rng[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			rng[0] = mid
+		} else {
+			rng[1] = mid
+		}
+		even = !even
+		if bit++; bit == 5 {
+			sb.WriteByte(geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return sb.String()
+}
+
+// GeohashDecode 返回 geohash 对应的矩形。
+func GeohashDecode(hash string) (BoundingBox, error) {
+	latRange, longRange := [2]float64{-90, 90}, [2]float64{-180, 180}
+	even := true
+	for i := 0; i < len(hash); i++ {
+		ch := strings.IndexByte(geohashAlphabet, hash[i])
+		if ch < 0 {
+			return BoundingBox{}, fmt.Errorf("invalid geohash %q", hash)
+		}
+		for b := 4; b >= 0; b-- {
+			rng := &latRange
+			if even {
+				rng = &longRange
+			}
+			mid := (rng[0] + rng[1]) / 2
+			if ch&(1<<b) != 0 {
+				rng[0] = mid
+			} else {
+				rng[1] = mid
+			}
+			even = !even
+		}
+	}
+	return BoundingBox{Coordinate{latRange[0], longRange[0]}, Coordinate{latRange[1], longRange[1]}}, nil
+}
+
+// geohashCellSize 返回长度为 precision 的 geohash 对应的矩形的纬度和经度跨度。
+func geohashCellSize(precision int) (dLat, dLong float64) {
+	bits := 5 * precision
+	return 180 / math.Pow(2, float64(bits/2)), 360 / math.Pow(2, float64((bits+1)/2))
+}
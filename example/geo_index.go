@@ -0,0 +1,174 @@
+package example
+
+import (
+	"math"
+	"sort"
+)
+
+// Place 是一个有名字的地点。Properties 在读写 GeoJSON 时保留，CSV 中不保存。
+type Place struct {
+	Name string
+	Coordinate
+	Properties map[string]any
+}
+
+// PlaceDistance 是查询结果中的地点和它到查询中心的距离，单位为米。
+type PlaceDistance struct {
+	Place
+	Distance float64
+}
+
+// GeoIndex 是按 geohash 分格的内存空间索引，每个格子对应一个长度为 precision 的 geohash，
+// 查询时只检查与查询范围相交的格子。并发使用不安全。
+type GeoIndex struct {
+	precision int
+	cells     map[string][]Place
+	n         int
+}
+
+// NewGeoIndex 返回格子的 geohash 长度为 precision 的 GeoIndex，precision 不在 [1, 12]
+// 内时使用 6，此时每个格子约为 1.2km × 0.6km。
+func NewGeoIndex(precision int) *GeoIndex {
+	if precision < 1 || precision > 12 {
+		precision = 6
+	}
+	return &GeoIndex{precision: precision, cells: make(map[string][]Place)}
+}
+
+func (gi *GeoIndex) Len() int {
+	return gi.n
+}
+
+func (gi *GeoIndex) Insert(places ...Place) {
+	for _, p := range places {
+		h := GeohashEncode(p.Coordinate, gi.precision)
+		gi.cells[h] = append(gi.cells[h], p)
+		gi.n++
+	}
+}
+
+// Remove 删除一个名字为 name、坐标为 c 的地点，返回是否找到了它。
+func (gi *GeoIndex) Remove(name string, c Coordinate) bool {
+	h := GeohashEncode(c, gi.precision)
+	cell := gi.cells[h]
+	for i, p := range cell {
+		if p.Name == name && p.Coordinate == c {
+			if cell = Delete(cell, i); len(cell) == 0 {
+				delete(gi.cells, h)
+			} else {
+				gi.cells[h] = cell
+			}
+			gi.n--
+			return true
+		}
+	}
+	return false
+}
+
+// All 返回所有的地点，顺序不确定。
+func (gi *GeoIndex) All() []Place {
+	ret := make([]Place, 0, gi.n)
+	for _, cell := range gi.cells {
+		ret = append(ret, cell...)
+	}
+	return ret
+}
+
+// scan 对与 bb 相交的格子中的每个地点调用 f。需要检查的格子比地点还多时直接遍历所有格子。
+func (gi *GeoIndex) scan(bb BoundingBox, f func(Place)) {
+	dLat, dLong := geohashCellSize(gi.precision)
+	var hashes []string
+	total := 0
+	for _, part := range bb.split() {
+		rows := math.Floor(part.Max.Lat/dLat) - math.Floor(part.Min.Lat/dLat) + 1
+		cols := math.Floor(part.Max.Long/dLong) - math.Floor(part.Min.Long/dLong) + 1
+		if total += int(rows * cols); total > len(gi.cells) {
+			hashes = nil
+			break
+		}
+		seen := make(map[string]bool)
+		for lat := part.Min.Lat; ; lat += dLat {
+			lat = min(lat, part.Max.Lat)
+			for long := part.Min.Long; ; long += dLong {
+				long = min(long, part.Max.Long)
+				if h := GeohashEncode(Coordinate{lat, long}, gi.precision); !seen[h] {
+					seen[h] = true
+					hashes = append(hashes, h)
+				}
+				if long == part.Max.Long {
+					break
+				}
+			}
+			if lat == part.Max.Lat {
+				break
+			}
+		}
+	}
+	if total > len(gi.cells) {
+		for _, cell := range gi.cells {
+			for _, p := range cell {
+				if bb.Contains(p.Coordinate) {
+					f(p)
+				}
+			}
+		}
+		return
+	}
+	for _, h := range hashes {
+		for _, p := range gi.cells[h] {
+			if bb.Contains(p.Coordinate) {
+				f(p)
+			}
+		}
+	}
+}
+
+// InBox 返回在 bb 中的地点，顺序不确定。
+func (gi *GeoIndex) InBox(bb BoundingBox) []Place {
+	var ret []Place
+	gi.scan(bb, func(p Place) { ret = append(ret, p) })
+	return ret
+}
+
+// sortByDistance 按距离从近到远排序，距离相同时按名字排序。
+func sortByDistance(pds []PlaceDistance) {
+	sort.Slice(pds, func(i, j int) bool {
+		if pds[i].Distance != pds[j].Distance {
+			return pds[i].Distance < pds[j].Distance
+		}
+		return pds[i].Name < pds[j].Name
+	})
+}
+
+// Within 返回到 center 的距离不超过 radius 米的地点，按距离从近到远排序，距离相同时按名字排序。
+func (gi *GeoIndex) Within(center Coordinate, radius float64) []PlaceDistance {
+	var ret []PlaceDistance
+	gi.scan(BoundingBoxAround(center, radius), func(p Place) {
+		if d := center.DistanceTo(p.Coordinate); d <= radius {
+			ret = append(ret, PlaceDistance{p, d})
+		}
+	})
+	sortByDistance(ret)
+	return ret
+}
+
+// Nearest 返回离 center 最近的 k 个地点，按距离从近到远排序。
+// 它从一个格子大小的半径开始，每次将半径加倍，直到范围内有至少 k 个地点。
+func (gi *GeoIndex) Nearest(center Coordinate, k int) []PlaceDistance {
+	if k <= 0 || gi.n == 0 {
+		return nil
+	}
+	dLat, _ := geohashCellSize(gi.precision)
+	radius := toRadians(dLat) * EarthRadius
+	for {
+		// 半个地球周长可以覆盖所有的点
+		if radius >= math.Pi*EarthRadius {
+			radius = math.Pi * EarthRadius
+		}
+		ret := gi.Within(center, radius)
+		if len(ret) >= k || radius == math.Pi*EarthRadius {
+			return ret[:min(k, len(ret))]
+		}
+		radius *= 2
+	}
+}
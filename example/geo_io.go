@@ -0,0 +1,129 @@
+package example
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// geoJSONFeature 是 GeoJSON 中只包含 Point 的 Feature。
+type geoJSONFeature struct {
+	Type     string `json:"type"`
+	Geometry struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+// ReadGeoJSON 读取 GeoJSON 的 FeatureCollection 中所有 Point 类型的 Feature，
+// 其他类型的 Feature 被忽略。properties 中的 name 作为 Place 的 Name。
+func ReadGeoJSON(r io.Reader) ([]Place, error) {
+	var fc geoJSONCollection
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return nil, err
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("geojson: want FeatureCollection, but %q", fc.Type)
+	}
+	var places []Place
+	for i, f := range fc.Features {
+		if f.Geometry.Type != "Point" {
+			continue
+		}
+		// GeoJSON 中坐标的顺序是经度、纬度
+		if len(f.Geometry.Coordinates) < 2 {
+			return nil, fmt.Errorf("geojson: feature %d: invalid coordinates %v", i, f.Geometry.Coordinates)
+		}
+		p := Place{Coordinate: Coordinate{Lat: f.Geometry.Coordinates[1], Long: f.Geometry.Coordinates[0]}}
+		if !p.Valid() {
+			return nil, fmt.Errorf("geojson: feature %d: invalid coordinate %v", i, p.Coordinate)
+		}
+		if name, ok := f.Properties["name"].(string); ok {
+			p.Name = name
+		}
+		delete(f.Properties, "name")
+		if len(f.Properties) > 0 {
+			p.Properties = f.Properties
+		}
+		places = append(places, p)
+	}
+	return places, nil
+}
+
+// WriteGeoJSON 将 places 写为 GeoJSON 的 FeatureCollection。
+func WriteGeoJSON(w io.Writer, places []Place) error {
+	fc := geoJSONCollection{Type: "FeatureCollection", Features: make([]geoJSONFeature, len(places))}
+	for i, p := range places {
+		f := &fc.Features[i]
+		f.Type = "Feature"
+		f.Geometry.Type = "Point"
+		f.Geometry.Coordinates = []float64{p.Long, p.Lat}
+		f.Properties = make(map[string]any, len(p.Properties)+1)
+		for k, v := range p.Properties {
+			f.Properties[k] = v
+		}
+		f.Properties["name"] = p.Name
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fc)
+}
+
+var csvHeader = []string{"name", "lat", "long"}
+
+// ReadCSV 读取以 name,lat,long 为表头的 CSV。
+func ReadCSV(r io.Reader) ([]Place, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i, h := range header {
+		if !strings.EqualFold(strings.TrimSpace(h), csvHeader[i]) {
+			return nil, fmt.Errorf("csv: want header %v, but %v", csvHeader, header)
+		}
+	}
+	var places []Place
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return places, nil
+		} else if err != nil {
+			return nil, err
+		}
+		lat, err1 := strconv.ParseFloat(strings.TrimSpace(rec[1]), 64)
+		long, err2 := strconv.ParseFloat(strings.TrimSpace(rec[2]), 64)
+		p := Place{Name: rec[0], Coordinate: Coordinate{lat, long}}
+		if err := errors.Join(err1, err2); err != nil || !p.Valid() {
+			line, _ := cr.FieldPos(0)
+			return nil, fmt.Errorf("csv: line %d: invalid coordinate %q, %q", line, rec[1], rec[2])
+		}
+		places = append(places, p)
+	}
+}
+
+// WriteCSV 将 places 写为以 name,lat,long 为表头的 CSV。
+func WriteCSV(w io.Writer, places []Place) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, p := range places {
+		cw.Write([]string{
+			p.Name,
+			strconv.FormatFloat(p.Lat, 'f', -1, 64),
+			strconv.FormatFloat(p.Long, 'f', -1, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
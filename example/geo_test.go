@@ -0,0 +1,197 @@
+package example
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestHaversine(t *testing.T) {
+	testcases := []struct {
+		a, b Coordinate
+		want float64 // km
+	}{
+		{exampleMap["Bell Labs"], exampleMap["Google"], 4082},
+		{Coordinate{51.5007, 0.1246}, Coordinate{40.6892, 74.0445}, 5575},
+		{Coordinate{0, 179.5}, Coordinate{0, -179.5}, 111.2},
+		{Coordinate{90, 0}, Coordinate{-90, 0}, math.Pi * EarthRadius / 1000},
+		{Coordinate{10, 10}, Coordinate{10, 10}, 0},
+	}
+	for _, tc := range testcases {
+		if got := tc.a.DistanceTo(tc.b) / 1000; math.Abs(got-tc.want) > tc.want*0.005+0.001 {
+			t.Errorf("%v -> %v: want about %vkm, but %vkm", tc.a, tc.b, tc.want, got)
+		}
+	}
+}
+
+func TestBoundingBoxAround(t *testing.T) {
+	centers := []Coordinate{{0, 0}, {60, 10}, {-45, 179.9}, {89.99, 0}, {10, -179.99}}
+	rnd := rand.New(rand.NewSource(1))
+	for _, c := range centers {
+		const radius = 50000
+		bb := BoundingBoxAround(c, radius)
+		// every point on the circle must be inside the box
+		for range 360 {
+			bearing := rnd.Float64() * 2 * math.Pi
+			p := destination(c, bearing, radius*0.999)
+			if !bb.Contains(p) {
+				t.Errorf("center %v: %v not in %+v", c, p, bb)
+			}
+		}
+	}
+	if bb := BoundingBoxAround(Coordinate{0, 179.9}, 50000); !bb.CrossesAntimeridian() {
+		t.Errorf("want box crossing the antimeridian, but %+v", bb)
+	}
+}
+
+// destination 返回从 c 出发沿 bearing 方向走 d 米到达的点。
+func destination(c Coordinate, bearing, d float64) Coordinate {
+	lat1, long1, ad := toRadians(c.Lat), toRadians(c.Long), d/EarthRadius
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(ad) + math.Cos(lat1)*math.Sin(ad)*math.Cos(bearing))
+	long2 := long1 + math.Atan2(math.Sin(bearing)*math.Sin(ad)*math.Cos(lat1), math.Cos(ad)-math.Sin(lat1)*math.Sin(lat2))
+	return Coordinate{toDegrees(lat2), normalizeLong(toDegrees(long2))}
+}
+
+func TestGeohash(t *testing.T) {
+	c := Coordinate{57.64911, 10.40744}
+	if h := GeohashEncode(c, 11); h != "u4pruydqqvj" {
+		t.Errorf("want u4pruydqqvj, but %s", h)
+	}
+	bb, err := GeohashDecode("u4pruydqqvj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bb.Contains(c) {
+		t.Errorf("%v not in %+v", c, bb)
+	}
+	dLat, dLong := geohashCellSize(11)
+	if math.Abs(bb.Max.Lat-bb.Min.Lat-dLat) > 1e-12 || math.Abs(bb.Max.Long-bb.Min.Long-dLong) > 1e-12 {
+		t.Errorf("cell size mismatch: %+v, %v %v", bb, dLat, dLong)
+	}
+	if _, err := GeohashDecode("abc"); err == nil {
+		t.Error("want error for invalid geohash")
+	}
+}
+
+func randomPlaces(rnd *rand.Rand, n int, around Coordinate, spread float64) []Place {
+	places := make([]Place, n)
+	for i := range places {
+		places[i] = Place{
+			Name: fmt.Sprint("p", i),
+			Coordinate: Coordinate{
+				Lat:  max(-90, min(90, around.Lat+(rnd.Float64()-0.5)*spread)),
+				Long: normalizeLong(around.Long + (rnd.Float64()-0.5)*spread),
+			},
+		}
+	}
+	return places
+}
+
+// bruteWithin 是 GeoIndex.Within 的参照实现。
+func bruteWithin(places []Place, center Coordinate, radius float64) []string {
+	var ret []PlaceDistance
+	for _, p := range places {
+		if d := center.DistanceTo(p.Coordinate); d <= radius {
+			ret = append(ret, PlaceDistance{p, d})
+		}
+	}
+	sortByDistance(ret)
+	return placeNames(ret)
+}
+
+func placeNames(pds []PlaceDistance) []string {
+	names := make([]string, len(pds))
+	for i, pd := range pds {
+		names[i] = pd.Name
+	}
+	return names
+}
+
+func TestGeoIndexQueries(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	centers := []Coordinate{{31.23, 121.47}, {0, 179.9}, {-89.9, 0}, {40.68, -74.4}}
+	for _, center := range centers {
+		places := randomPlaces(rnd, 2000, center, 2)
+		gi := NewGeoIndex(5)
+		gi.Insert(places...)
+		if gi.Len() != len(places) {
+			t.Fatalf("want Len %d, but %d", len(places), gi.Len())
+		}
+		for _, radius := range []float64{0, 1000, 20000, 100000, 3e7} {
+			want := bruteWithin(places, center, radius)
+			if got := placeNames(gi.Within(center, radius)); strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("Within(%v, %v): want %d places, but %d", center, radius, len(want), len(got))
+			}
+		}
+		for _, k := range []int{1, 10, 2000, 3000} {
+			want := bruteWithin(places, center, math.Inf(1))
+			want = want[:min(k, len(want))]
+			if got := placeNames(gi.Nearest(center, k)); strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("Nearest(%v, %d): want %v, but %v", center, k, want[:min(k, 5)], got[:min(k, 5, len(got))])
+			}
+		}
+	}
+}
+
+func TestGeoIndexRemoveAndBox(t *testing.T) {
+	gi := NewGeoIndex(0)
+	for name, c := range exampleMap {
+		gi.Insert(Place{Name: name, Coordinate: c})
+	}
+	usEast := BoundingBox{Coordinate{35, -80}, Coordinate{45, -70}}
+	if got := gi.InBox(usEast); len(got) != 1 || got[0].Name != "Bell Labs" {
+		t.Errorf("unexpected InBox result %v", got)
+	}
+	if gi.Remove("Google", exampleMap["Bell Labs"]) {
+		t.Error("removed a place with mismatched coordinate")
+	}
+	if !gi.Remove("Google", exampleMap["Google"]) || gi.Len() != 1 || len(gi.All()) != 1 {
+		t.Error("Remove failed")
+	}
+}
+
+func TestGeoIO(t *testing.T) {
+	places := []Place{
+		{Name: "Bell Labs", Coordinate: exampleMap["Bell Labs"], Properties: map[string]any{"kind": "lab"}},
+		{Name: "Google, Inc.", Coordinate: exampleMap["Google"]},
+	}
+	var buf bytes.Buffer
+	if err := WriteGeoJSON(&buf, places); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "-74.39967,\n") {
+		t.Errorf("want longitude first in GeoJSON:\n%s", buf.String())
+	}
+	got, err := ReadGeoJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Name != places[0].Name || got[0].Coordinate != places[0].Coordinate ||
+		got[0].Properties["kind"] != "lab" || got[1].Properties != nil {
+		t.Errorf("GeoJSON round trip: %+v", got)
+	}
+
+	buf.Reset()
+	if err := WriteCSV(&buf, places); err != nil {
+		t.Fatal(err)
+	}
+	got, err = ReadCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1].Name != places[1].Name || got[1].Coordinate != places[1].Coordinate {
+		t.Errorf("CSV round trip: %+v", got)
+	}
+
+	for _, bad := range []string{"name,lat,long\nx,91,0\n", "name,lat,long\nx,abc,0\n", "a,b,c\n"} {
+		if _, err := ReadCSV(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+	if _, err := ReadGeoJSON(strings.NewReader(`{"type":"Feature"}`)); err == nil {
+		t.Error("want error for non-collection GeoJSON")
+	}
+}
@@ -0,0 +1,156 @@
+package geom
+
+import "math"
+
+// Affine2 是平面上的仿射变换，表示 3×3 齐次矩阵的前两行，最后一行总是 [0 0 1]：
+//
+//	x' = m[0][0]*x + m[0][1]*y + m[0][2]
+//	y' = m[1][0]*x + m[1][1]*y + m[1][2]
+type Affine2[T Float] [2][3]T
+
+func Identity2[T Float]() Affine2[T] {
+	return Affine2[T]{{1, 0, 0}, {0, 1, 0}}
+}
+
+func Translate2[T Float](d Vec2[T]) Affine2[T] {
+	return Affine2[T]{{1, 0, d.X}, {0, 1, d.Y}}
+}
+
+func Scale2[T Float](sx, sy T) Affine2[T] {
+	return Affine2[T]{{sx, 0, 0}, {0, sy, 0}}
+}
+
+// Rotate2 返回绕原点逆时针旋转 angle 弧度的变换。
+func Rotate2[T Float](angle float64) Affine2[T] {
+	sin, cos := math.Sincos(angle)
+	s, c := T(sin), T(cos)
+	return Affine2[T]{{c, -s, 0}, {s, c, 0}}
+}
+
+// Mul 返回先做 n 再做 m 的变换，即矩阵乘积 m·n。
+func (m Affine2[T]) Mul(n Affine2[T]) Affine2[T] {
+	var r Affine2[T]
+	for i := range 2 {
+		for j := range 3 {
+			r[i][j] = m[i][0]*n[0][j] + m[i][1]*n[1][j]
+		}
+		r[i][2] += m[i][2]
+	}
+	return r
+}
+
+// Apply 返回变换后的点。
+func (m Affine2[T]) Apply(p Vec2[T]) Vec2[T] {
+	return Vec2[T]{
+		m[0][0]*p.X + m[0][1]*p.Y + m[0][2],
+		m[1][0]*p.X + m[1][1]*p.Y + m[1][2],
+	}
+}
+
+// ApplyVec 返回变换后的向量，与 Apply 不同，它不受平移的影响。
+func (m Affine2[T]) ApplyVec(v Vec2[T]) Vec2[T] {
+	return Vec2[T]{m[0][0]*v.X + m[0][1]*v.Y, m[1][0]*v.X + m[1][1]*v.Y}
+}
+
+// Det 返回线性部分的行列式，它是变换对面积的缩放比例，为负时变换翻转了方向。
+func (m Affine2[T]) Det() T {
+	return m[0][0]*m[1][1] - m[0][1]*m[1][0]
+}
+
+// Inverse 返回逆变换，变换不可逆时返回 false。
+func (m Affine2[T]) Inverse() (Affine2[T], bool) {
+	det := m.Det()
+	if det == 0 {
+		return Affine2[T]{}, false
+	}
+	var r Affine2[T]
+	r[0][0], r[0][1] = m[1][1]/det, -m[0][1]/det
+	r[1][0], r[1][1] = -m[1][0]/det, m[0][0]/det
+	t := r.ApplyVec(Vec2[T]{m[0][2], m[1][2]})
+	r[0][2], r[1][2] = -t.X, -t.Y
+	return r, true
+}
+
+// Affine3 是空间中的仿射变换，表示 4×4 齐次矩阵的前三行，最后一行总是 [0 0 0 1]。
+type Affine3[T Float] [3][4]T
+
+func Identity3[T Float]() Affine3[T] {
+	return Affine3[T]{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}}
+}
+
+func Translate3[T Float](d Vec3[T]) Affine3[T] {
+	return Affine3[T]{{1, 0, 0, d.X}, {0, 1, 0, d.Y}, {0, 0, 1, d.Z}}
+}
+
+func Scale3[T Float](sx, sy, sz T) Affine3[T] {
+	return Affine3[T]{{sx, 0, 0, 0}, {0, sy, 0, 0}, {0, 0, sz, 0}}
+}
+
+// Rotate3 返回绕过原点的 axis 轴旋转 angle 弧度的变换，从 axis 指向的方向看过去是逆时针。
+// axis 是零向量时返回恒等变换。
+func Rotate3[T Float](axis Vec3[T], angle float64) Affine3[T] {
+	u, ok := axis.Normalize()
+	if !ok {
+		return Identity3[T]()
+	}
+	sin, cos := math.Sincos(angle)
+	s, c := T(sin), T(cos)
+	t := 1 - c
+	return Affine3[T]{
+		{t*u.X*u.X + c, t*u.X*u.Y - s*u.Z, t*u.X*u.Z + s*u.Y, 0},
+		{t*u.X*u.Y + s*u.Z, t*u.Y*u.Y + c, t*u.Y*u.Z - s*u.X, 0},
+		{t*u.X*u.Z - s*u.Y, t*u.Y*u.Z + s*u.X, t*u.Z*u.Z + c, 0},
+	}
+}
+
+// Mul 返回先做 n 再做 m 的变换，即矩阵乘积 m·n。
+func (m Affine3[T]) Mul(n Affine3[T]) Affine3[T] {
+	var r Affine3[T]
+	for i := range 3 {
+		for j := range 4 {
+			r[i][j] = m[i][0]*n[0][j] + m[i][1]*n[1][j] + m[i][2]*n[2][j]
+		}
+		r[i][3] += m[i][3]
+	}
+	return r
+}
+
+func (m Affine3[T]) Apply(p Vec3[T]) Vec3[T] {
+	return m.ApplyVec(p).Add(Vec3[T]{m[0][3], m[1][3], m[2][3]})
+}
+
+// ApplyVec 返回变换后的向量，与 Apply 不同，它不受平移的影响。
+func (m Affine3[T]) ApplyVec(v Vec3[T]) Vec3[T] {
+	return Vec3[T]{
+		m[0][0]*v.X + m[0][1]*v.Y + m[0][2]*v.Z,
+		m[1][0]*v.X + m[1][1]*v.Y + m[1][2]*v.Z,
+		m[2][0]*v.X + m[2][1]*v.Y + m[2][2]*v.Z,
+	}
+}
+
+// Det 返回线性部分的行列式，它是变换对体积的缩放比例。
+func (m Affine3[T]) Det() T {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+// Inverse 返回逆变换，变换不可逆时返回 false。线性部分用伴随矩阵求逆。
+func (m Affine3[T]) Inverse() (Affine3[T], bool) {
+	det := m.Det()
+	if det == 0 {
+		return Affine3[T]{}, false
+	}
+	var r Affine3[T]
+	for i := range 3 {
+		for j := range 3 {
+			// 余子式 C[j][i]，下标取模实现了代数余子式的符号
+			a, b := (j+1)%3, (j+2)%3
+			c, d := (i+1)%3, (i+2)%3
+			r[i][j] = (m[a][c]*m[b][d] - m[a][d]*m[b][c]) / det
+		}
+	}
+	t := r.ApplyVec(Vec3[T]{m[0][3], m[1][3], m[2][3]})
+	r[0][3], r[1][3], r[2][3] = -t.X, -t.Y, -t.Z
+	return r, true
+}
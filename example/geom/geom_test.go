@@ -0,0 +1,307 @@
+package geom
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+	"testing/quick"
+)
+
+const eps = 1e-6
+
+// pt 是 quick.Check 使用的随机点，一半的点落在 0.5 的整数倍上，以便经常出现共线和重合的情况。
+type pt Vec2[float64]
+
+func randCoord(r *rand.Rand) float64 {
+	if r.Intn(2) == 0 {
+		return float64(r.Intn(41)-20) / 2
+	}
+	return r.Float64()*200 - 100
+}
+
+func (pt) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(pt{randCoord(r), randCoord(r)})
+}
+
+func (p pt) v() Vec2[float64] { return Vec2[float64](p) }
+
+type pt3 Vec3[float64]
+
+func (pt3) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(pt3{randCoord(r), randCoord(r), randCoord(r)})
+}
+
+func (p pt3) v() Vec3[float64] { return Vec3[float64](p) }
+
+// cloud 是 0 到 40 个随机点。
+type cloud []Vec2[float64]
+
+func (cloud) Generate(r *rand.Rand, _ int) reflect.Value {
+	c := make(cloud, r.Intn(41))
+	for i := range c {
+		c[i] = pt{}.Generate(r, 0).Interface().(pt).v()
+	}
+	return reflect.ValueOf(c)
+}
+
+// affine2 是由缩放、旋转和平移组合而成的随机可逆变换。
+type affine2 Affine2[float64]
+
+func (affine2) Generate(r *rand.Rand, _ int) reflect.Value {
+	m := Scale2(0.1+r.Float64()*3, 0.1+r.Float64()*3)
+	if r.Intn(2) == 0 {
+		m = Scale2[float64](-1, 1).Mul(m)
+	}
+	m = Rotate2[float64](r.Float64() * 2 * math.Pi).Mul(m)
+	m = Translate2(Vec2[float64]{randCoord(r), randCoord(r)}).Mul(m)
+	return reflect.ValueOf(affine2(m))
+}
+
+type affine3 Affine3[float64]
+
+func (affine3) Generate(r *rand.Rand, _ int) reflect.Value {
+	m := Scale3(0.1+r.Float64()*3, 0.1+r.Float64()*3, 0.1+r.Float64()*3)
+	axis := Vec3[float64]{randCoord(r), randCoord(r), randCoord(r)}
+	m = Rotate3(axis, r.Float64()*2*math.Pi).Mul(m)
+	m = Translate3(Vec3[float64]{randCoord(r), randCoord(r), randCoord(r)}).Mul(m)
+	return reflect.ValueOf(affine3(m))
+}
+
+func check(t *testing.T, name string, f any) {
+	t.Helper()
+	cfg := &quick.Config{MaxCount: 500, Rand: rand.New(rand.NewSource(1))}
+	if err := quick.Check(f, cfg); err != nil {
+		t.Errorf("%s: %v", name, err)
+	}
+}
+
+func TestVecProperties(t *testing.T) {
+	check(t, "dot commutes", func(a, b pt) bool {
+		return a.v().Dot(b.v()) == b.v().Dot(a.v())
+	})
+	check(t, "2d cross anticommutes", func(a, b pt) bool {
+		return a.v().Cross(b.v()) == -b.v().Cross(a.v())
+	})
+	check(t, "triangle inequality", func(a, b pt) bool {
+		return a.v().Add(b.v()).Abs() <= a.v().Abs()+b.v().Abs()+eps
+	})
+	check(t, "normalize", func(a pt) bool {
+		n, ok := a.v().Normalize()
+		if !ok {
+			return a.v() == Vec2[float64]{}
+		}
+		return ApproxEqual(n.Abs(), 1, eps) && ApproxEqual(n.Dot(a.v()), a.v().Abs(), eps)
+	})
+	check(t, "perp is orthogonal", func(a pt) bool {
+		return ApproxEqual(a.v().Dot(a.v().Perp()), 0, eps) && a.v().Cross(a.v().Perp()) >= 0
+	})
+	check(t, "3d cross is orthogonal", func(a, b pt3) bool {
+		c := a.v().Cross(b.v())
+		return ApproxEqual(c.Dot(a.v()), 0, eps) && ApproxEqual(c.Dot(b.v()), 0, eps)
+	})
+	check(t, "lagrange identity", func(a, b pt3) bool {
+		c, d := a.v().Cross(b.v()), a.v().Dot(b.v())
+		return ApproxEqual(c.Dot(c), a.v().Dot(a.v())*b.v().Dot(b.v())-d*d, eps)
+	})
+	check(t, "scale", func(a pt, f float64) bool {
+		v := a.v()
+		v.Scale(f)
+		return v == a.v().Mul(f)
+	})
+}
+
+func TestAffine2Properties(t *testing.T) {
+	check(t, "mul composes", func(m, n affine2, p pt) bool {
+		mm, nn := Affine2[float64](m), Affine2[float64](n)
+		return mm.Mul(nn).Apply(p.v()).ApproxEqual(mm.Apply(nn.Apply(p.v())), eps)
+	})
+	check(t, "det multiplies", func(m, n affine2) bool {
+		mm, nn := Affine2[float64](m), Affine2[float64](n)
+		return ApproxEqual(mm.Mul(nn).Det(), mm.Det()*nn.Det(), eps)
+	})
+	check(t, "inverse", func(m affine2, p pt) bool {
+		inv, ok := Affine2[float64](m).Inverse()
+		return ok && inv.Apply(Affine2[float64](m).Apply(p.v())).ApproxEqual(p.v(), eps)
+	})
+	check(t, "rotation keeps distance", func(a, b pt, angle float64) bool {
+		r := Rotate2[float64](angle)
+		return ApproxEqual(r.Apply(a.v()).Dist(r.Apply(b.v())), a.v().Dist(b.v()), eps)
+	})
+	if _, ok := Scale2[float64](0, 1).Inverse(); ok {
+		t.Error("want singular matrix not invertible")
+	}
+	if p := Rotate2[float64](math.Pi / 2).Apply(Vec2[float64]{1, 0}); !p.ApproxEqual(Vec2[float64]{0, 1}, eps) {
+		t.Errorf("want counterclockwise rotation, but %v", p)
+	}
+}
+
+func TestAffine3Properties(t *testing.T) {
+	check(t, "mul composes", func(m, n affine3, p pt3) bool {
+		mm, nn := Affine3[float64](m), Affine3[float64](n)
+		return mm.Mul(nn).Apply(p.v()).ApproxEqual(mm.Apply(nn.Apply(p.v())), eps)
+	})
+	check(t, "inverse", func(m affine3, p pt3) bool {
+		inv, ok := Affine3[float64](m).Inverse()
+		return ok && inv.Apply(Affine3[float64](m).Apply(p.v())).ApproxEqual(p.v(), eps) &&
+			ApproxEqual(inv.Det()*Affine3[float64](m).Det(), 1, eps)
+	})
+	check(t, "rotation keeps length and axis", func(axis, p pt3, angle float64) bool {
+		r := Rotate3(axis.v(), angle)
+		return ApproxEqual(r.Apply(p.v()).Abs(), p.v().Abs(), eps) &&
+			r.Apply(axis.v()).ApproxEqual(axis.v(), eps) && ApproxEqual(r.Det(), 1, eps)
+	})
+	check(t, "rotation about z is rotate2", func(p pt3, angle float64) bool {
+		return Rotate3(Vec3[float64]{0, 0, 1}, angle).Apply(p.v()).XY().ApproxEqual(Rotate2[float64](angle).Apply(p.v().XY()), eps)
+	})
+}
+
+func TestShapes(t *testing.T) {
+	c := Circle[float64]{Vec2[float64]{1, 1}, 2}
+	if !ApproxEqual(c.Area(), 4*math.Pi, eps) || !ApproxEqual(c.Perimeter(), 4*math.Pi, eps) {
+		t.Errorf("circle: area %v, perimeter %v", c.Area(), c.Perimeter())
+	}
+	if !c.Contains(Vec2[float64]{3, 1}) || c.Contains(Vec2[float64]{2.5, 2.5}) || c.Bounds() != (Rect[float64]{Vec2[float64]{-1, -1}, Vec2[float64]{3, 3}}) {
+		t.Error("circle: unexpected Contains or Bounds")
+	}
+
+	r := RectOf(Vec2[float64]{4, 3}, Vec2[float64]{0, 0})
+	if r.Area() != 12 || r.Perimeter() != 14 || r.Polygon().SignedArea() != 12 || r.Polygon().Perimeter() != 14 {
+		t.Errorf("rect: area %v, perimeter %v", r.Area(), r.Perimeter())
+	}
+	r.Scale(-1)
+	if r != RectOf(Vec2[float64]{-4, -3}, Vec2[float64]{0, 0}) {
+		t.Errorf("rect: unexpected Scale result %v", r)
+	}
+
+	// L 形的凹多边形，顺时针
+	l := Polygon[float64]{{0, 0}, {0, 2}, {1, 2}, {1, 1}, {2, 1}, {2, 0}}
+	if l.Area() != 3 || l.SignedArea() != -3 || l.Perimeter() != 8 || l.IsConvex() {
+		t.Errorf("L: area %v, perimeter %v, convex %v", l.Area(), l.Perimeter(), l.IsConvex())
+	}
+	for p, want := range map[Vec2[float64]]bool{
+		{0.5, 0.5}: true, {1.5, 1.5}: false, {1, 1.5}: true, {2, 0}: true, {0.5, 1}: true, {-1, 1}: false, {3, 0}: false, {1.5, 1}: true,
+	} {
+		if l.Contains(p) != want {
+			t.Errorf("L contains %v: want %v", p, want)
+		}
+	}
+	if c := l.Centroid(); !c.ApproxEqual(Vec2[float64]{5.0 / 6, 5.0 / 6}, eps) {
+		t.Errorf("L: unexpected centroid %v", c)
+	}
+
+	// 泛型参数也可以是 float32
+	sq := Rect[float32]{Max: Vec2[float32]{2, 2}}.Polygon()
+	var s Shape[float32] = sq
+	if s.Area() != 4 || !sq.IsConvex() || !s.Contains(Vec2[float32]{1, 2}) {
+		t.Error("float32 square: unexpected result")
+	}
+
+	// 指针都满足 example.Scaler
+	type scaler interface{ Scale(float64) }
+	for _, s := range []scaler{&Vec2[float64]{}, &Vec3[float64]{}, &Rect[float64]{}, &Circle[float64]{}, Polygon[float64]{}} {
+		s.Scale(2)
+	}
+}
+
+func TestIntersection(t *testing.T) {
+	v := func(x, y float64) Vec2[float64] { return Vec2[float64]{x, y} }
+	segments := []struct {
+		a1, a2, b1, b2 Vec2[float64]
+		want           bool
+	}{
+		{v(0, 0), v(2, 2), v(0, 2), v(2, 0), true},
+		{v(0, 0), v(1, 1), v(1, 1), v(2, 0), true},  // 端点接触
+		{v(0, 0), v(2, 0), v(1, 0), v(3, 0), true},  // 共线重叠
+		{v(0, 0), v(1, 0), v(2, 0), v(3, 0), false}, // 共线不重叠
+		{v(0, 0), v(1, 1), v(0, 1), v(1, 2), false}, // 平行
+		{v(0, 0), v(2, 0), v(1, 1), v(1, 0.1), false},
+	}
+	for _, tc := range segments {
+		if got := SegmentsIntersect(tc.a1, tc.a2, tc.b1, tc.b2); got != tc.want {
+			t.Errorf("%v-%v and %v-%v: want %v", tc.a1, tc.a2, tc.b1, tc.b2, tc.want)
+		}
+		if got := SegmentsIntersect(tc.b2, tc.b1, tc.a1, tc.a2); got != tc.want {
+			t.Errorf("%v-%v and %v-%v: want %v", tc.b2, tc.b1, tc.a1, tc.a2, tc.want)
+		}
+	}
+
+	square := Polygon[float64]{v(0, 0), v(4, 0), v(4, 4), v(0, 4)}
+	polygons := []struct {
+		p    Polygon[float64]
+		want bool
+	}{
+		{Polygon[float64]{v(1, 1), v(2, 1), v(2, 2)}, true}, // 在里面
+		{Polygon[float64]{v(-1, -1), v(5, -1), v(5, 5), v(-1, 5)}, true},
+		{Polygon[float64]{v(4, 4), v(5, 4), v(5, 5)}, true}, // 顶点接触
+		{Polygon[float64]{v(5, 5), v(6, 5), v(6, 6)}, false},
+		{Polygon[float64]{v(3.5, 5), v(5, 3.5), v(5, 5)}, false}, // 外接矩形相交但多边形不相交
+	}
+	for _, tc := range polygons {
+		if square.Intersects(tc.p) != tc.want || tc.p.Intersects(square) != tc.want {
+			t.Errorf("square and %v: want %v", tc.p, tc.want)
+		}
+	}
+
+	clipped := square.ClipConvex(Polygon[float64]{v(2, 2), v(2, 6), v(6, 6), v(6, 2)})
+	if clipped.Area() != 4 {
+		t.Errorf("want clipped area 4, but %v: %v", clipped.Area(), clipped)
+	}
+	if got := square.ClipConvex(polygons[3].p); got != nil {
+		t.Errorf("want nil for disjoint polygons, but %v", got)
+	}
+}
+
+func TestHullProperties(t *testing.T) {
+	check(t, "hull", func(c cloud) bool {
+		hull := ConvexHull(c)
+		for _, p := range hull {
+			if !slices.Contains(c, p) {
+				return false
+			}
+		}
+		if len(hull) >= 3 && (!hull.IsConvex() || hull.SignedArea() <= 0) {
+			return false
+		}
+		if len(hull) >= 2 {
+			for _, p := range c {
+				if !hull.Contains(p) {
+					return false
+				}
+			}
+		}
+		return slices.Equal(ConvexHull(hull), hull)
+	})
+	check(t, "hull commutes with transform", func(c cloud, m affine2) bool {
+		mm := Affine2[float64](m)
+		a := ConvexHull(c).Transform(mm).Area()
+		b := ConvexHull(Polygon[float64](c).Transform(mm)).Area()
+		return ApproxEqual(a, b, eps) && ApproxEqual(a, ConvexHull(c).Area()*abs(mm.Det()), eps)
+	})
+	check(t, "clip convex", func(a, b cloud) bool {
+		ha, hb := ConvexHull(a), ConvexHull(b)
+		if len(ha) < 3 || len(hb) < 3 {
+			return true
+		}
+		ab, ba := ha.ClipConvex(hb), hb.ClipConvex(ha)
+		if !ApproxEqual(ab.Area(), ba.Area(), eps) || ab.Area() > min(ha.Area(), hb.Area())+eps {
+			return false
+		}
+		// 交集有面积时多边形一定相交
+		if ab.Area() > eps && !ha.Intersects(hb) {
+			return false
+		}
+		// 外接矩形的交集是交集的上界
+		ra, rb := ha.Bounds(), hb.Bounds()
+		return !ra.Overlaps(rb) && ab == nil || ab.Area() <= ra.Intersect(rb).Area()+eps
+	})
+	check(t, "rect clip", func(a, b, c, d pt) bool {
+		ra, rb := RectOf(a.v(), b.v()), RectOf(c.v(), d.v())
+		want := 0.0
+		if i := ra.Intersect(rb); !i.Empty() {
+			want = i.Area()
+		}
+		return ApproxEqual(ra.Polygon().ClipConvex(rb.Polygon()).Area(), want, eps)
+	})
+}
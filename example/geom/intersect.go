@@ -0,0 +1,119 @@
+package geom
+
+import (
+	"cmp"
+	"slices"
+)
+
+// orientation 返回 c 相对有向线段 ab 的方向：左侧（逆时针）为 1，右侧为 -1，共线为 0。
+func orientation[T Float](a, b, c Vec2[T]) int {
+	switch x := b.Sub(a).Cross(c.Sub(a)); {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// onSegment 判断 p 是否在线段 ab 上。
+func onSegment[T Float](a, b, p Vec2[T]) bool {
+	return orientation(a, b, p) == 0 && RectOf(a, b).Contains(p)
+}
+
+// SegmentsIntersect 判断线段 a1a2 和 b1b2 是否有公共点，端点接触和共线重叠都算相交。
+func SegmentsIntersect[T Float](a1, a2, b1, b2 Vec2[T]) bool {
+	o1, o2 := orientation(a1, a2, b1), orientation(a1, a2, b2)
+	o3, o4 := orientation(b1, b2, a1), orientation(b1, b2, a2)
+	if o1 != o2 && o3 != o4 {
+		return true
+	}
+	return onSegment(a1, a2, b1) || onSegment(a1, a2, b2) || onSegment(b1, b2, a1) || onSegment(b1, b2, a2)
+}
+
+// Intersects 判断两个多边形是否有公共点：要么有边相交，要么一个在另一个里面。
+// 先用外接矩形快速排除。
+func (pg Polygon[T]) Intersects(o Polygon[T]) bool {
+	if len(pg) == 0 || len(o) == 0 || !pg.Bounds().Overlaps(o.Bounds()) {
+		return false
+	}
+	for i := range pg {
+		a1, a2 := pg.edge(i)
+		for j := range o {
+			b1, b2 := o.edge(j)
+			if SegmentsIntersect(a1, a2, b1, b2) {
+				return true
+			}
+		}
+	}
+	return pg.Contains(o[0]) || o.Contains(pg[0])
+}
+
+// ClipConvex 用 Sutherland–Hodgman 算法返回 pg 与凸多边形 clip 的交集，pg 可以是凹的，
+// 但此时结果可能包含面积为 0 的连接边。没有交集时返回 nil。clip 不是凸多边形时结果无意义。
+func (pg Polygon[T]) ClipConvex(clip Polygon[T]) Polygon[T] {
+	if len(clip) < 3 {
+		return nil
+	}
+	// 统一按逆时针处理 clip，“内侧”是每条边的左侧
+	dir := 1
+	if clip.SignedArea() < 0 {
+		dir = -1
+	}
+	out := slices.Clone(pg)
+	for i := range clip {
+		c1, c2 := clip.edge(i)
+		in := out
+		out = nil
+		for j := range in {
+			p, q := in[j], in[(j+1)%len(in)]
+			pIn, qIn := orientation(c1, c2, p)*dir >= 0, orientation(c1, c2, q)*dir >= 0
+			if pIn {
+				out = append(out, p)
+			}
+			if pIn != qIn {
+				out = append(out, lineIntersection(c1, c2, p, q))
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+	}
+	return out
+}
+
+// lineIntersection 返回直线 ab 与线段 pq 的交点，调用者保证 p、q 在直线两侧。
+func lineIntersection[T Float](a, b, p, q Vec2[T]) Vec2[T] {
+	d := b.Sub(a)
+	t := d.Cross(a.Sub(p)) / d.Cross(q.Sub(p))
+	return p.Lerp(q, t)
+}
+
+// ConvexHull 用 Andrew 单调链算法返回包含所有 points 的最小凸多边形，顶点按逆时针排列，
+// 从最左下的点开始，不包含共线的点。所有点共线时返回两个端点，少于两个不同的点时返回这些点。
+func ConvexHull[T Float](points []Vec2[T]) Polygon[T] {
+	ps := slices.Clone(points)
+	slices.SortFunc(ps, func(a, b Vec2[T]) int {
+		return cmp.Or(cmp.Compare(a.X, b.X), cmp.Compare(a.Y, b.Y))
+	})
+	ps = slices.Compact(ps)
+	if len(ps) < 3 {
+		return Polygon[T](ps)
+	}
+	hull := make(Polygon[T], 0, 2*len(ps))
+	// 下凸包从左到右，上凸包从右到左，只保留严格左转的点
+	for range 2 {
+		start := len(hull)
+		for _, p := range ps {
+			for len(hull) >= start+2 && orientation(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		// 最后一个点是另一条链的起点
+		hull = hull[:len(hull)-1]
+		slices.Reverse(ps)
+	}
+	return hull
+}
@@ -0,0 +1,218 @@
+package geom
+
+import "math"
+
+// Measurer 是可以计算面积和周长的形状，example.Square 的 Area 也符合它的前一半。
+type Measurer[T Float] interface {
+	Area() T
+	Perimeter() T
+}
+
+// Container 判断一个点是否在形状中，边界上的点也算在形状中。
+type Container[T Float] interface {
+	Contains(p Vec2[T]) bool
+}
+
+// Shape 是平面上的封闭形状。
+type Shape[T Float] interface {
+	Measurer[T]
+	Container[T]
+	// Bounds 返回包含形状的最小的矩形
+	Bounds() Rect[T]
+}
+
+var (
+	_ Shape[float64] = Rect[float64]{}
+	_ Shape[float64] = Circle[float64]{}
+	_ Shape[float64] = Polygon[float64]{}
+)
+
+// Rect 是边与坐标轴平行的矩形，Min 的两个分量都不大于 Max 时有效。
+type Rect[T Float] struct {
+	Min, Max Vec2[T]
+}
+
+// RectOf 返回以 a、b 为对角顶点的矩形。
+func RectOf[T Float](a, b Vec2[T]) Rect[T] {
+	return Rect[T]{Vec2[T]{min(a.X, b.X), min(a.Y, b.Y)}, Vec2[T]{max(a.X, b.X), max(a.Y, b.Y)}}
+}
+
+func (r Rect[T]) Dx() T           { return r.Max.X - r.Min.X }
+func (r Rect[T]) Dy() T           { return r.Max.Y - r.Min.Y }
+func (r Rect[T]) Area() T         { return r.Dx() * r.Dy() }
+func (r Rect[T]) Perimeter() T    { return 2 * (r.Dx() + r.Dy()) }
+func (r Rect[T]) Bounds() Rect[T] { return r }
+func (r Rect[T]) Center() Vec2[T] { return r.Min.Lerp(r.Max, 0.5) }
+func (r Rect[T]) Empty() bool     { return r.Min.X >= r.Max.X || r.Min.Y >= r.Max.Y }
+
+// Polygon 返回从 Min 开始逆时针排列的四个顶点。
+func (r Rect[T]) Polygon() Polygon[T] {
+	return Polygon[T]{r.Min, {r.Max.X, r.Min.Y}, r.Max, {r.Min.X, r.Max.Y}}
+}
+
+func (r Rect[T]) Contains(p Vec2[T]) bool {
+	return p.X >= r.Min.X && p.X <= r.Max.X && p.Y >= r.Min.Y && p.Y <= r.Max.Y
+}
+
+// Intersect 返回两个矩形的交集，不相交时返回的矩形 Empty。
+func (r Rect[T]) Intersect(o Rect[T]) Rect[T] {
+	return Rect[T]{
+		Vec2[T]{max(r.Min.X, o.Min.X), max(r.Min.Y, o.Min.Y)},
+		Vec2[T]{min(r.Max.X, o.Max.X), min(r.Max.Y, o.Max.Y)},
+	}
+}
+
+// Overlaps 判断两个矩形是否有公共点，只有边界接触也算。
+func (r Rect[T]) Overlaps(o Rect[T]) bool {
+	i := r.Intersect(o)
+	return i.Min.X <= i.Max.X && i.Min.Y <= i.Max.Y
+}
+
+// Scale 以原点为中心将矩形放大 f 倍，f 为负时矩形仍然有效。
+func (r *Rect[T]) Scale(f float64) {
+	r.Min.Scale(f)
+	r.Max.Scale(f)
+	*r = RectOf(r.Min, r.Max)
+}
+
+// Circle 是圆。
+type Circle[T Float] struct {
+	Center Vec2[T]
+	Radius T
+}
+
+func (c Circle[T]) Area() T      { return T(math.Pi) * c.Radius * c.Radius }
+func (c Circle[T]) Perimeter() T { return 2 * T(math.Pi) * c.Radius }
+
+func (c Circle[T]) Contains(p Vec2[T]) bool {
+	d := p.Sub(c.Center)
+	return d.Dot(d) <= c.Radius*c.Radius
+}
+
+func (c Circle[T]) Bounds() Rect[T] {
+	r := Vec2[T]{c.Radius, c.Radius}
+	return Rect[T]{c.Center.Sub(r), c.Center.Add(r)}
+}
+
+// Scale 以原点为中心将圆放大 f 倍。
+func (c *Circle[T]) Scale(f float64) {
+	c.Center.Scale(f)
+	c.Radius = abs(c.Radius * T(f))
+}
+
+// Polygon 是按顺序连接各个顶点得到的简单多边形（边不自交），顶点可以是顺时针或逆时针的，
+// 最后一个顶点与第一个顶点相连，不需要重复第一个顶点。
+type Polygon[T Float] []Vec2[T]
+
+// SignedArea 用鞋带公式计算有向面积，顶点逆时针排列时为正。
+func (pg Polygon[T]) SignedArea() T {
+	var s T
+	for i, p := range pg {
+		s += p.Cross(pg[(i+1)%len(pg)])
+	}
+	return s / 2
+}
+
+func (pg Polygon[T]) Area() T {
+	return abs(pg.SignedArea())
+}
+
+func (pg Polygon[T]) Perimeter() T {
+	var s T
+	for i, p := range pg {
+		s += p.Dist(pg[(i+1)%len(pg)])
+	}
+	return s
+}
+
+func (pg Polygon[T]) Bounds() Rect[T] {
+	if len(pg) == 0 {
+		return Rect[T]{}
+	}
+	r := Rect[T]{pg[0], pg[0]}
+	for _, p := range pg[1:] {
+		r.Min.X, r.Min.Y = min(r.Min.X, p.X), min(r.Min.Y, p.Y)
+		r.Max.X, r.Max.Y = max(r.Max.X, p.X), max(r.Max.Y, p.Y)
+	}
+	return r
+}
+
+// edge 返回第 i 条边的两个端点。
+func (pg Polygon[T]) edge(i int) (Vec2[T], Vec2[T]) {
+	return pg[i], pg[(i+1)%len(pg)]
+}
+
+// Contains 用射线法判断 p 是否在多边形中，边界上的点也算在多边形中。
+func (pg Polygon[T]) Contains(p Vec2[T]) bool {
+	inside := false
+	for i := range pg {
+		a, b := pg.edge(i)
+		if onSegment(a, b, p) {
+			return true
+		}
+		// 向 x 正方向的射线与边相交，左闭右开地处理经过顶点的情况
+		if (a.Y > p.Y) != (b.Y > p.Y) {
+			x := a.X + (p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+			if p.X < x {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// Centroid 返回多边形的重心，面积为 0 时返回顶点的平均值。
+func (pg Polygon[T]) Centroid() Vec2[T] {
+	if len(pg) == 0 {
+		return Vec2[T]{}
+	}
+	a := pg.SignedArea()
+	var c Vec2[T]
+	if a == 0 {
+		for _, p := range pg {
+			c = c.Add(p)
+		}
+		return c.Mul(1 / T(len(pg)))
+	}
+	for i := range pg {
+		p, q := pg.edge(i)
+		c = c.Add(p.Add(q).Mul(p.Cross(q)))
+	}
+	return c.Mul(1 / (6 * a))
+}
+
+// IsConvex 判断多边形是否是凸的，共线的相邻边不影响结果。少于 3 个顶点的多边形不是凸的。
+func (pg Polygon[T]) IsConvex() bool {
+	if len(pg) < 3 {
+		return false
+	}
+	sign := 0
+	for i := range pg {
+		a, b := pg.edge(i)
+		c := pg[(i+2)%len(pg)]
+		switch o := orientation(a, b, c); {
+		case o == 0:
+		case sign == 0:
+			sign = o
+		case o != sign:
+			return false
+		}
+	}
+	return sign != 0
+}
+
+// Transform 返回对每个顶点应用 m 后的多边形。
+func (pg Polygon[T]) Transform(m Affine2[T]) Polygon[T] {
+	ret := make(Polygon[T], len(pg))
+	for i, p := range pg {
+		ret[i] = m.Apply(p)
+	}
+	return ret
+}
+
+// Scale 以原点为中心将多边形原地放大 f 倍。
+func (pg Polygon[T]) Scale(f float64) {
+	for i := range pg {
+		pg[i].Scale(f)
+	}
+}
@@ -0,0 +1,126 @@
+// Package geom 是 examples6_method_interface.go 中 Vertex、Abser 和 Scaler 的泛型版本：
+// 二维和三维向量、仿射变换矩阵，以及实现了 Shape 接口的多边形、圆和矩形。
+//
+// 所有类型都以浮点数类型 T 为参数，通常使用 float64；需要节省内存时可以使用 float32。
+// 向量和形状的 Scale 方法与 example.Scaler 的签名相同，因此它们的指针都是 Scaler。
+package geom
+
+import "math"
+
+// Float 是向量坐标可以使用的类型。
+type Float interface {
+	~float32 | ~float64
+}
+
+// Epsilon 是 ApproxEqual 等判断使用的默认误差。
+const Epsilon = 1e-9
+
+// ApproxEqual 判断 a 和 b 的差是否不超过 eps 或二者中较大者的 eps 倍。
+func ApproxEqual[T Float](a, b, eps T) bool {
+	d := a - b
+	if d < 0 {
+		d = -d
+	}
+	return d <= eps || d <= eps*max(abs(a), abs(b))
+}
+
+func abs[T Float](x T) T {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func sqrt[T Float](x T) T {
+	return T(math.Sqrt(float64(x)))
+}
+
+// Vec2 是二维向量，也用来表示平面上的点。
+type Vec2[T Float] struct {
+	X, Y T
+}
+
+func (v Vec2[T]) Add(o Vec2[T]) Vec2[T] { return Vec2[T]{v.X + o.X, v.Y + o.Y} }
+func (v Vec2[T]) Sub(o Vec2[T]) Vec2[T] { return Vec2[T]{v.X - o.X, v.Y - o.Y} }
+func (v Vec2[T]) Mul(f T) Vec2[T]       { return Vec2[T]{v.X * f, v.Y * f} }
+func (v Vec2[T]) Neg() Vec2[T]          { return Vec2[T]{-v.X, -v.Y} }
+func (v Vec2[T]) Dot(o Vec2[T]) T       { return v.X*o.X + v.Y*o.Y }
+
+// Cross 返回 v 和 o 叉积的 z 分量，即 v 和 o 张成的平行四边形的有向面积，
+// o 在 v 的逆时针方向时为正。
+func (v Vec2[T]) Cross(o Vec2[T]) T { return v.X*o.Y - v.Y*o.X }
+
+// Abs 返回向量的长度，与 Vertex.Abs 相同。
+func (v Vec2[T]) Abs() T { return T(math.Hypot(float64(v.X), float64(v.Y))) }
+
+// Dist 返回两点之间的距离。
+func (v Vec2[T]) Dist(o Vec2[T]) T { return v.Sub(o).Abs() }
+
+// Scale 将向量原地放大 f 倍，与 Vertex.Scale 相同。
+func (v *Vec2[T]) Scale(f float64) {
+	v.X *= T(f)
+	v.Y *= T(f)
+}
+
+// Normalize 返回与 v 同方向的单位向量，v 是零向量时返回零向量和 false。
+func (v Vec2[T]) Normalize() (Vec2[T], bool) {
+	l := v.Abs()
+	if l == 0 {
+		return Vec2[T]{}, false
+	}
+	return Vec2[T]{v.X / l, v.Y / l}, true
+}
+
+// Perp 返回 v 逆时针旋转 90 度得到的向量。
+func (v Vec2[T]) Perp() Vec2[T] { return Vec2[T]{-v.Y, v.X} }
+
+// Lerp 返回从 v 到 o 的线段上参数为 t 的点，t 为 0 时是 v，为 1 时是 o。
+func (v Vec2[T]) Lerp(o Vec2[T], t T) Vec2[T] { return v.Add(o.Sub(v).Mul(t)) }
+
+// ApproxEqual 判断两个向量的每个分量是否都近似相等。
+func (v Vec2[T]) ApproxEqual(o Vec2[T], eps T) bool {
+	return ApproxEqual(v.X, o.X, eps) && ApproxEqual(v.Y, o.Y, eps)
+}
+
+// Vec3 是三维向量，也用来表示空间中的点。
+type Vec3[T Float] struct {
+	X, Y, Z T
+}
+
+func (v Vec3[T]) Add(o Vec3[T]) Vec3[T] { return Vec3[T]{v.X + o.X, v.Y + o.Y, v.Z + o.Z} }
+func (v Vec3[T]) Sub(o Vec3[T]) Vec3[T] { return Vec3[T]{v.X - o.X, v.Y - o.Y, v.Z - o.Z} }
+func (v Vec3[T]) Mul(f T) Vec3[T]       { return Vec3[T]{v.X * f, v.Y * f, v.Z * f} }
+func (v Vec3[T]) Neg() Vec3[T]          { return Vec3[T]{-v.X, -v.Y, -v.Z} }
+func (v Vec3[T]) Dot(o Vec3[T]) T       { return v.X*o.X + v.Y*o.Y + v.Z*o.Z }
+
+// Cross 返回 v 和 o 的叉积，它垂直于 v 和 o，方向满足右手定则。
+func (v Vec3[T]) Cross(o Vec3[T]) Vec3[T] {
+	return Vec3[T]{v.Y*o.Z - v.Z*o.Y, v.Z*o.X - v.X*o.Z, v.X*o.Y - v.Y*o.X}
+}
+
+func (v Vec3[T]) Abs() T           { return sqrt(v.Dot(v)) }
+func (v Vec3[T]) Dist(o Vec3[T]) T { return v.Sub(o).Abs() }
+
+func (v *Vec3[T]) Scale(f float64) {
+	v.X *= T(f)
+	v.Y *= T(f)
+	v.Z *= T(f)
+}
+
+// Normalize 返回与 v 同方向的单位向量，v 是零向量时返回零向量和 false。
+func (v Vec3[T]) Normalize() (Vec3[T], bool) {
+	l := v.Abs()
+	if l == 0 {
+		return Vec3[T]{}, false
+	}
+	return Vec3[T]{v.X / l, v.Y / l, v.Z / l}, true
+}
+
+func (v Vec3[T]) Lerp(o Vec3[T], t T) Vec3[T] { return v.Add(o.Sub(v).Mul(t)) }
+
+// XY 返回 v 在 xy 平面上的投影。
+func (v Vec3[T]) XY() Vec2[T] { return Vec2[T]{v.X, v.Y} }
+
+func (v Vec3[T]) ApproxEqual(o Vec3[T], eps T) bool {
+	return ApproxEqual(v.X, o.X, eps) && ApproxEqual(v.Y, o.Y, eps) && ApproxEqual(v.Z, o.Z, eps)
+}
@@ -0,0 +1,257 @@
+package example
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/RinkoTaketsuki/GolangLearning/http_example/httpclient"
+	"golang.org/x/net/html"
+)
+
+var (
+	ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+	ErrBodyTooLarge       = errors.New("response body too large")
+	ErrContentType        = errors.New("unsupported content type")
+)
+
+// HTTPFetcherConfig 是 NewHTTPFetcher 的参数，零值字段使用默认值。
+type HTTPFetcherConfig struct {
+	// Client 默认为 httpclient.New()。
+	Client *http.Client
+	// UserAgent 会被放在请求头中，同时用于匹配 robots.txt 中的规则，默认 "GolangLearningBot"。
+	UserAgent string
+	// MaxBodySize 是响应体的最大字节数，默认 1 MiB。
+	MaxBodySize int64
+	// AllowedContentTypes 是允许的 MIME 类型，默认只允许 text/html。
+	AllowedContentTypes []string
+	// IgnoreRobots 为 true 时不获取 robots.txt。
+	IgnoreRobots bool
+}
+
+// HTTPFetcher 是通过 HTTP GET 获取页面的 Fetcher，会解析 HTML 中的 <a href> 链接。
+// 并发使用是安全的。
+type HTTPFetcher struct {
+	cfg HTTPFetcherConfig
+
+	mu     sync.Mutex
+	robots map[string]*RobotsRules // key 是 scheme://host
+}
+
+var _ ContextFetcher = (*HTTPFetcher)(nil)
+
+func NewHTTPFetcher(cfg HTTPFetcherConfig) *HTTPFetcher {
+	if cfg.Client == nil {
+		cfg.Client = httpclient.New()
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "GolangLearningBot"
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+	if len(cfg.AllowedContentTypes) == 0 {
+		cfg.AllowedContentTypes = []string{"text/html"}
+	}
+	return &HTTPFetcher{cfg: cfg, robots: make(map[string]*RobotsRules)}
+}
+
+func (f *HTTPFetcher) Fetch(rawURL string) (string, []string, error) {
+	return f.FetchContext(context.Background(), rawURL)
+}
+
+// FetchContext 返回页面内容和页面中经过 NormalizeURL 处理并去重的链接。
+func (f *HTTPFetcher) FetchContext(ctx context.Context, rawURL string) (string, []string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", nil, err
+	}
+	if !f.cfg.IgnoreRobots {
+		rules, err := f.robotsFor(ctx, u)
+		if err != nil {
+			return "", nil, err
+		}
+		if !rules.Allowed(u.RequestURI()) {
+			return "", nil, fmt.Errorf("fetch %s: %w", rawURL, ErrDisallowedByRobots)
+		}
+	}
+	resp, err := f.get(ctx, u.String())
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("fetch %s: %s", rawURL, resp.Status)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !slices.Contains(f.cfg.AllowedContentTypes, mediaType) {
+		return "", nil, fmt.Errorf("fetch %s: %w %q", rawURL, ErrContentType, resp.Header.Get("Content-Type"))
+	}
+	body, err := f.readBody(resp)
+	if err != nil {
+		return "", nil, fmt.Errorf("fetch %s: %w", rawURL, err)
+	}
+	// 重定向后的 URL 才是解析相对链接的基准
+	links, err := ExtractLinks(resp.Request.URL, strings.NewReader(body))
+	if err != nil {
+		return "", nil, fmt.Errorf("fetch %s: %w", rawURL, err)
+	}
+	return body, links, nil
+}
+
+func (f *HTTPFetcher) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.cfg.UserAgent)
+	return f.cfg.Client.Do(req)
+}
+
+func (f *HTTPFetcher) readBody(resp *http.Response) (string, error) {
+	if resp.ContentLength > f.cfg.MaxBodySize {
+		return "", ErrBodyTooLarge
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, f.cfg.MaxBodySize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(b)) > f.cfg.MaxBodySize {
+		return "", ErrBodyTooLarge
+	}
+	return string(b), nil
+}
+
+// robotsFor 返回 u 所在站点的 robots.txt 规则，结果按站点缓存。
+// robots.txt 返回 4xx 时允许访问所有路径，返回 5xx 时禁止访问所有路径。
+func (f *HTTPFetcher) robotsFor(ctx context.Context, u *url.URL) (*RobotsRules, error) {
+	site := u.Scheme + "://" + u.Host
+	f.mu.Lock()
+	rules, ok := f.robots[site]
+	f.mu.Unlock()
+	if ok {
+		return rules, nil
+	}
+	resp, err := f.get(ctx, site+"/robots.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		rules = robotsDisallowAll
+	case resp.StatusCode >= 400:
+		rules = robotsAllowAll
+	default:
+		rules, err = ParseRobots(io.LimitReader(resp.Body, f.cfg.MaxBodySize), f.cfg.UserAgent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.mu.Lock()
+	f.robots[site] = rules
+	f.mu.Unlock()
+	return rules, nil
+}
+
+// NormalizeURL 将 URL 规范化，以便对同一页面的不同写法去重：scheme 和 host 转为小写，
+// 去掉默认端口和 fragment，空路径视为 "/"。
+func NormalizeURL(u *url.URL) string {
+	n := *u
+	n.Scheme = strings.ToLower(n.Scheme)
+	host, port := strings.ToLower(n.Hostname()), n.Port()
+	if (n.Scheme == "http" && port == "80") || (n.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	n.Host = host
+	n.Fragment, n.RawFragment = "", ""
+	if n.Path == "" {
+		n.Path, n.RawPath = "/", ""
+	}
+	return n.String()
+}
+
+// ExtractLinks 解析 HTML 中 <a href> 的链接，以 base（或页面中的 <base href>）为基准
+// 转为绝对 URL，规范化后去重。只保留 http 和 https 链接，忽略 rel="nofollow" 的链接，
+// 页面带有 <meta name="robots" content="nofollow"> 时返回空。
+func ExtractLinks(base *url.URL, r io.Reader) ([]string, error) {
+	var links []string
+	seen := make(map[string]bool)
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return links, nil
+			}
+			return nil, z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "base":
+				if href, ok := attr(t, "href"); ok {
+					if b, err := base.Parse(href); err == nil {
+						base = b
+					}
+				}
+			case "meta":
+				name, _ := attr(t, "name")
+				content, _ := attr(t, "content")
+				if strings.EqualFold(name, "robots") && hasToken(content, ",", "nofollow", "none") {
+					return nil, nil
+				}
+			case "a":
+				href, ok := attr(t, "href")
+				if !ok {
+					continue
+				}
+				if rel, _ := attr(t, "rel"); hasToken(rel, " ", "nofollow") {
+					continue
+				}
+				u, err := base.Parse(strings.TrimSpace(href))
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+					continue
+				}
+				if link := NormalizeURL(u); !seen[link] {
+					seen[link] = true
+					links = append(links, link)
+				}
+			}
+		}
+	}
+}
+
+func attr(t html.Token, key string) (string, bool) {
+	for _, a := range t.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// hasToken 判断以 sep 分隔的 s 中是否有与 tokens 之一相同（不区分大小写）的项。
+func hasToken(s, sep string, tokens ...string) bool {
+	for _, field := range strings.Split(s, sep) {
+		field = strings.TrimSpace(field)
+		for _, t := range tokens {
+			if strings.EqualFold(field, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
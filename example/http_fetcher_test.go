@@ -0,0 +1,216 @@
+package example
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseRobots(t *testing.T) {
+	const robots = `
+# comment
+User-agent: *
+Disallow: /private
+Allow: /private/public
+
+User-agent: OtherBot
+User-agent: GolangLearningBot
+Disallow: /*.pdf$
+Disallow: /tmp # trailing comment
+Allow: /tmp/ok
+`
+	testcases := []struct {
+		agent, path string
+		allowed     bool
+	}{
+		{"SomeBot", "/", true},
+		{"SomeBot", "/private/x", false},
+		{"SomeBot", "/private/public/x", true},
+		{"SomeBot", "/a.pdf", true},
+		{"GolangLearningBot/1.0", "/private/x", true},
+		{"GolangLearningBot/1.0", "/a/b.pdf", false},
+		{"GolangLearningBot/1.0", "/a/b.pdf?x=1", true},
+		{"GolangLearningBot/1.0", "/tmp/x", false},
+		{"GolangLearningBot/1.0", "/tmp/ok/x", true},
+	}
+	for _, tc := range testcases {
+		rr, err := ParseRobots(strings.NewReader(robots), tc.agent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rr.Allowed(tc.path); got != tc.allowed {
+			t.Errorf("%s %s: want %v, but %v", tc.agent, tc.path, tc.allowed, got)
+		}
+	}
+}
+
+func TestRobotsMatch(t *testing.T) {
+	testcases := []struct {
+		pattern, path string
+		match         bool
+	}{
+		{"/", "/anything", true},
+		{"/a", "/ab", true},
+		{"/a$", "/ab", false},
+		{"/a$", "/a", true},
+		{"/*/b", "/x/y/b/c", true},
+		{"/*.gif$", "/x.gif", true},
+		{"/*.gif$", "/x.gif.png", false},
+		{"/a*b*c", "/abc", true},
+		{"/a*b*c", "/acb", false},
+	}
+	for _, tc := range testcases {
+		if got := robotsMatch(tc.pattern, tc.path); got != tc.match {
+			t.Errorf("robotsMatch(%q, %q): want %v, but %v", tc.pattern, tc.path, tc.match, got)
+		}
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	testcases := []struct{ in, want string }{
+		{"HTTP://Example.COM", "http://example.com/"},
+		{"http://example.com:80/a#frag", "http://example.com/a"},
+		{"https://example.com:443/a?b=1", "https://example.com/a?b=1"},
+		{"https://example.com:8443/", "https://example.com:8443/"},
+		{"http://[::1]:80/x", "http://[::1]/x"},
+	}
+	for _, tc := range testcases {
+		u, err := url.Parse(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := NormalizeURL(u); got != tc.want {
+			t.Errorf("NormalizeURL(%q): want %q, but %q", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	base, _ := url.Parse("http://example.com/dir/page")
+	testcases := []struct {
+		name, html string
+		want       []string
+	}{
+		{
+			"relative and dedupe",
+			`<a href="a">1</a><a href="/b#x">2</a><a href="/b">3</a><A HREF="HTTP://EXAMPLE.com:80/c">4</A>`,
+			[]string{"http://example.com/dir/a", "http://example.com/b", "http://example.com/c"},
+		},
+		{
+			"skip non-http and nofollow",
+			`<a href="mailto:x@example.com">m</a><a href="javascript:void(0)">j</a><a rel="external nofollow" href="/n">n</a><a>none</a><a href="/ok">ok</a>`,
+			[]string{"http://example.com/ok"},
+		},
+		{
+			"base href",
+			`<head><base href="https://other.org/root/"></head><a href="x">x</a>`,
+			[]string{"https://other.org/root/x"},
+		},
+		{
+			"meta robots nofollow",
+			`<meta name="robots" content="noindex, nofollow"><a href="/a">a</a>`,
+			nil,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ExtractLinks(base, strings.NewReader(tc.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalStrings(got, tc.want) {
+				t.Errorf("want %v, but %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	var robotsHits int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		robotsHits++
+		io.WriteString(w, "User-agent: *\nDisallow: /secret\n")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.Header.Get("User-Agent"); ua != "TestBot" {
+			t.Errorf("unexpected User-Agent %q", ua)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<a href="/a">a</a><a href="/a#top">a</a>`)
+	})
+	mux.HandleFunc("/secret", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("/secret should not be fetched")
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, strings.Repeat("x", 200))
+	})
+	mux.HandleFunc("/big-chunked", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		for range 4 {
+			io.WriteString(w, strings.Repeat("x", 50))
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := NewHTTPFetcher(HTTPFetcherConfig{Client: server.Client(), UserAgent: "TestBot", MaxBodySize: 100})
+	body, urls, err := f.Fetch(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, "href") || !equalStrings(urls, []string{server.URL + "/a"}) {
+		t.Errorf("unexpected result: %q %v", body, urls)
+	}
+	testcases := []struct {
+		path string
+		err  error
+	}{
+		{"/secret", ErrDisallowedByRobots},
+		{"/big", ErrBodyTooLarge},
+		{"/big-chunked", ErrBodyTooLarge},
+		{"/image", ErrContentType},
+	}
+	for _, tc := range testcases {
+		if _, _, err := f.FetchContext(context.Background(), server.URL+tc.path); !errors.Is(err, tc.err) {
+			t.Errorf("%s: want %v, but %v", tc.path, tc.err, err)
+		}
+	}
+	if robotsHits != 1 {
+		t.Errorf("want robots.txt fetched once, but %d", robotsHits)
+	}
+}
+
+func TestHTTPFetcherRobotsStatus(t *testing.T) {
+	testcases := []struct {
+		status  int
+		allowed bool
+	}{
+		{http.StatusNotFound, true},
+		{http.StatusServiceUnavailable, false},
+	}
+	for _, tc := range testcases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/robots.txt" {
+				w.WriteHeader(tc.status)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+		}))
+		_, _, err := NewHTTPFetcher(HTTPFetcherConfig{Client: server.Client()}).Fetch(server.URL + "/")
+		server.Close()
+		if allowed := !errors.Is(err, ErrDisallowedByRobots); allowed != tc.allowed || (allowed && err != nil) {
+			t.Errorf("robots.txt %d: unexpected error %v", tc.status, err)
+		}
+	}
+}
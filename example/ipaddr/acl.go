@@ -0,0 +1,134 @@
+package ipaddr
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// List 是由 allow 和 deny 规则组成的访问控制列表。判断一个地址时使用包含它的最长的前缀的规则，
+// 因此可以在允许的大网段中拒绝一个小网段，反之亦然；没有规则匹配时使用默认规则。
+// 同一个前缀后添加的规则覆盖先添加的。并发使用不安全，构造完成后只读使用是安全的。
+type List struct {
+	rules        Trie[bool]
+	defaultAllow bool
+}
+
+// NewList 返回没有规则的 List，defaultAllow 为 true 时是黑名单，否则是白名单。
+func NewList(defaultAllow bool) *List {
+	return &List{defaultAllow: defaultAllow}
+}
+
+func (l *List) Allow(prefixes ...Prefix) {
+	for _, p := range prefixes {
+		l.rules.Insert(p, true)
+	}
+}
+
+func (l *List) Deny(prefixes ...Prefix) {
+	for _, p := range prefixes {
+		l.rules.Insert(p, false)
+	}
+}
+
+// Len 返回规则的数量。
+func (l *List) Len() int {
+	return l.rules.Len()
+}
+
+// Allowed 判断是否允许 a，IPv4 映射的 IPv6 地址按 IPv4 地址判断，无效的地址总是被拒绝。
+func (l *List) Allowed(a Addr) bool {
+	a = a.Unmap()
+	if !a.IsValid() {
+		return false
+	}
+	if _, allow, ok := l.rules.Lookup(a); ok {
+		return allow
+	}
+	return l.defaultAllow
+}
+
+// AllowedString 解析 s 后调用 Allowed，s 无法解析时拒绝。
+func (l *List) AllowedString(s string) bool {
+	a, err := ParseAddr(s)
+	return err == nil && l.Allowed(a)
+}
+
+// ParseList 读取每行一条规则的 List，规则的格式为 "allow <前缀>" 或 "deny <前缀>"，
+// 前缀可以是单个地址。空行和以 # 开头的行被忽略。
+//
+// "default allow" 或 "default deny" 设置默认规则，最多出现一次。没有 default 时，
+// 只要有一条 allow 规则就默认拒绝，否则使用 defaultAllow，这样只有 allow 规则的文件就是白名单。
+//
+//	# 只允许内网，但拒绝访客网段
+//	default deny
+//	allow 10.0.0.0/8
+//	deny  10.99.0.0/16
+//	allow ::1
+func ParseList(r io.Reader, defaultAllow bool) (*List, error) {
+	l := NewList(defaultAllow)
+	hasDefault, hasAllow := false, false
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("ipaddr: line %d: want \"allow|deny <prefix>\" or \"default allow|deny\", but %q", line, text)
+		}
+		if strings.EqualFold(fields[0], "default") {
+			if hasDefault {
+				return nil, fmt.Errorf("ipaddr: line %d: duplicate default", line)
+			}
+			switch strings.ToLower(fields[1]) {
+			case "allow":
+				l.defaultAllow = true
+			case "deny":
+				l.defaultAllow = false
+			default:
+				return nil, fmt.Errorf("ipaddr: line %d: unknown default %q", line, fields[1])
+			}
+			hasDefault = true
+			continue
+		}
+		p, err := ParsePrefix(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("ipaddr: line %d: %w", line, err)
+		}
+		switch strings.ToLower(fields[0]) {
+		case "allow":
+			l.Allow(p)
+			hasAllow = true
+		case "deny":
+			l.Deny(p)
+		default:
+			return nil, fmt.Errorf("ipaddr: line %d: unknown action %q", line, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !hasDefault && hasAllow {
+		l.defaultAllow = false
+	}
+	return l, nil
+}
+
+// ParsePrefixes 解析以逗号分隔的前缀列表，适合用于命令行参数。空字符串返回 nil。
+func ParsePrefixes(s string) ([]Prefix, error) {
+	var ret []Prefix
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		p, err := ParsePrefix(f)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
@@ -0,0 +1,210 @@
+// Package ipaddr 是 examples6_method_interface.go 中 IPAddr 的完整版本：同时支持
+// IPv4 和 IPv6 的地址、CIDR 前缀、最长前缀匹配的基数树，以及基于它的黑白名单。
+//
+//	l := ipaddr.NewList(false)
+//	l.Allow(ipaddr.MustParsePrefix("10.0.0.0/8"))
+//	l.Deny(ipaddr.MustParsePrefix("10.1.0.0/16"))
+//	l.Allowed(ipaddr.MustParseAddr("10.2.3.4")) // true
+//
+// 地址的解析和格式化借助 net/netip，前缀运算都在 128 位整数上进行，IPv4 地址占用低 32 位。
+package ipaddr
+
+import (
+	"math/bits"
+	"net/netip"
+)
+
+// uint128 是大端序的 128 位无符号整数。
+type uint128 struct {
+	hi, lo uint64
+}
+
+// lowOnes 返回低 n 位为 1 的数，n 在 [0, 128] 内。
+func lowOnes(n int) uint128 {
+	switch {
+	case n <= 0:
+		return uint128{}
+	case n < 64:
+		return uint128{0, 1<<n - 1}
+	case n < 128:
+		return uint128{1<<(n-64) - 1, ^uint64(0)}
+	default:
+		return uint128{^uint64(0), ^uint64(0)}
+	}
+}
+
+func (u uint128) and(v uint128) uint128    { return uint128{u.hi & v.hi, u.lo & v.lo} }
+func (u uint128) or(v uint128) uint128     { return uint128{u.hi | v.hi, u.lo | v.lo} }
+func (u uint128) xor(v uint128) uint128    { return uint128{u.hi ^ v.hi, u.lo ^ v.lo} }
+func (u uint128) andNot(v uint128) uint128 { return uint128{u.hi &^ v.hi, u.lo &^ v.lo} }
+
+func (u uint128) addOne() uint128 {
+	lo, carry := bits.Add64(u.lo, 1, 0)
+	return uint128{u.hi + carry, lo}
+}
+
+func (u uint128) subOne() uint128 {
+	lo, borrow := bits.Sub64(u.lo, 1, 0)
+	return uint128{u.hi - borrow, lo}
+}
+
+func (u uint128) cmp(v uint128) int {
+	switch {
+	case u.hi < v.hi || u.hi == v.hi && u.lo < v.lo:
+		return -1
+	case u == v:
+		return 0
+	default:
+		return 1
+	}
+}
+
+func (u uint128) leadingZeros() int {
+	if u.hi != 0 {
+		return bits.LeadingZeros64(u.hi)
+	}
+	return 64 + bits.LeadingZeros64(u.lo)
+}
+
+func (u uint128) trailingZeros() int {
+	if u.lo != 0 {
+		return bits.TrailingZeros64(u.lo)
+	}
+	return 64 + bits.TrailingZeros64(u.hi)
+}
+
+// Addr 是一个 IPv4 或 IPv6 地址，零值表示无效的地址。Addr 可以用 == 比较，也可以作为 map 的 key。
+// 与 net/netip 不同，IPv6 的 zone 会被忽略。
+type Addr struct {
+	u uint128
+	// bitLen 是 32 或 128，零值 Addr 为 0
+	bitLen uint8
+}
+
+// AddrFrom4 返回 IPv4 地址。
+func AddrFrom4(b [4]byte) Addr {
+	return Addr{uint128{0, uint64(b[0])<<24 | uint64(b[1])<<16 | uint64(b[2])<<8 | uint64(b[3])}, 32}
+}
+
+// AddrFrom16 返回 IPv6 地址，IPv4 映射地址（::ffff:a.b.c.d）也作为 IPv6 地址，需要时调用 Unmap。
+func AddrFrom16(b [16]byte) Addr {
+	var u uint128
+	for i := 0; i < 8; i++ {
+		u.hi = u.hi<<8 | uint64(b[i])
+		u.lo = u.lo<<8 | uint64(b[i+8])
+	}
+	return Addr{u, 128}
+}
+
+// AddrFromNetIP 将 netip.Addr 转换为 Addr，无效的 netip.Addr 转换为零值。
+func AddrFromNetIP(a netip.Addr) Addr {
+	switch {
+	case a.Is4():
+		return AddrFrom4(a.As4())
+	case a.Is6():
+		return AddrFrom16(a.As16())
+	default:
+		return Addr{}
+	}
+}
+
+// ParseAddr 解析 "192.0.2.1"、"2001:db8::1" 等形式的地址。
+func ParseAddr(s string) (Addr, error) {
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return Addr{}, err
+	}
+	return AddrFromNetIP(a), nil
+}
+
+// MustParseAddr 与 ParseAddr 相同，但出错时 panic，适合用于常量。
+func MustParseAddr(s string) Addr {
+	a, err := ParseAddr(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func (a Addr) IsValid() bool { return a.bitLen != 0 }
+func (a Addr) Is4() bool     { return a.bitLen == 32 }
+func (a Addr) Is6() bool     { return a.bitLen == 128 }
+
+// BitLen 返回地址的位数，IPv4 为 32，IPv6 为 128，无效地址为 0。
+func (a Addr) BitLen() int { return int(a.bitLen) }
+
+// Is4In6 判断 a 是否为 IPv4 映射的 IPv6 地址。
+func (a Addr) Is4In6() bool {
+	return a.Is6() && a.u.hi == 0 && a.u.lo>>32 == 0xffff
+}
+
+// Unmap 将 IPv4 映射的 IPv6 地址转换为 IPv4 地址，其他地址原样返回。
+// 双栈监听时 IPv4 客户端的地址通常是这种形式。
+func (a Addr) Unmap() Addr {
+	if a.Is4In6() {
+		return Addr{uint128{0, a.u.lo & 0xffffffff}, 32}
+	}
+	return a
+}
+
+// bit 返回从最高位开始数第 i 位的值，i 在 [0, BitLen) 内。
+func (a Addr) bit(i int) int {
+	j := 128 - int(a.bitLen) + i
+	if j < 64 {
+		return int(a.u.hi>>(63-j)) & 1
+	}
+	return int(a.u.lo>>(127-j)) & 1
+}
+
+// Compare 比较两个地址，IPv4 地址排在 IPv6 地址之前，无效地址排在最前。
+func (a Addr) Compare(b Addr) int {
+	if a.bitLen != b.bitLen {
+		if a.bitLen < b.bitLen {
+			return -1
+		}
+		return 1
+	}
+	return a.u.cmp(b.u)
+}
+
+func (a Addr) Less(b Addr) bool { return a.Compare(b) < 0 }
+
+// Next 返回下一个地址，a 已经是最大的地址时返回零值。
+func (a Addr) Next() Addr {
+	if !a.IsValid() || a.u == lowOnes(a.BitLen()) {
+		return Addr{}
+	}
+	return Addr{a.u.addOne(), a.bitLen}
+}
+
+// Prev 返回上一个地址，a 已经是最小的地址时返回零值。
+func (a Addr) Prev() Addr {
+	if !a.IsValid() || a.u == (uint128{}) {
+		return Addr{}
+	}
+	return Addr{a.u.subOne(), a.bitLen}
+}
+
+// NetIP 将 a 转换为 netip.Addr。
+func (a Addr) NetIP() netip.Addr {
+	switch a.bitLen {
+	case 32:
+		v := a.u.lo
+		return netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
+	case 128:
+		var b [16]byte
+		for i := 7; i >= 0; i-- {
+			b[i], b[i+8] = byte(a.u.hi>>(8*(7-i))), byte(a.u.lo>>(8*(7-i)))
+		}
+		return netip.AddrFrom16(b)
+	default:
+		return netip.Addr{}
+	}
+}
+
+func (a Addr) String() string {
+	if !a.IsValid() {
+		return "invalid IP"
+	}
+	return a.NetIP().String()
+}
@@ -0,0 +1,333 @@
+package ipaddr
+
+import (
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseAddr(t *testing.T) {
+	testcases := []struct {
+		in, want string
+		bitLen   int
+	}{
+		{"192.0.2.1", "192.0.2.1", 32},
+		{"2001:0db8::0001", "2001:db8::1", 128},
+		{"::ffff:10.0.0.1", "::ffff:10.0.0.1", 128},
+		{"fe80::1%eth0", "fe80::1", 128},
+		{"::", "::", 128},
+	}
+	for _, tc := range testcases {
+		a, err := ParseAddr(tc.in)
+		if err != nil {
+			t.Errorf("%s: %v", tc.in, err)
+			continue
+		}
+		if a.String() != tc.want || a.BitLen() != tc.bitLen {
+			t.Errorf("%s: want %s (%d bits), but %s (%d bits)", tc.in, tc.want, tc.bitLen, a, a.BitLen())
+		}
+	}
+	for _, bad := range []string{"", "1.2.3", "256.0.0.1", "1::2::3"} {
+		if _, err := ParseAddr(bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+	if a := MustParseAddr("::ffff:10.0.0.1").Unmap(); a != AddrFrom4([4]byte{10, 0, 0, 1}) {
+		t.Errorf("Unmap: got %v", a)
+	}
+	if a := MustParseAddr("255.255.255.255").Next(); a.IsValid() {
+		t.Errorf("want invalid Next of the last address, but %v", a)
+	}
+	if a := MustParseAddr("::ffff:ffff:ffff:ffff").Next(); a.String() != "0:0:0:1::" {
+		t.Errorf("want carry into the high half, but %v", a)
+	}
+	if a := MustParseAddr("0:0:0:1::").Prev(); a.String() != "::ffff:ffff:ffff:ffff" {
+		t.Errorf("want borrow from the high half, but %v", a)
+	}
+}
+
+func TestAddrNetIPRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for range 1000 {
+		var b [16]byte
+		rnd.Read(b[:])
+		if a := AddrFrom16(b); a.NetIP() != netip.AddrFrom16(b) {
+			t.Fatalf("%x: got %v", b, a)
+		}
+		b4 := [4]byte(b[:4])
+		if a := AddrFrom4(b4); a.NetIP() != netip.AddrFrom4(b4) {
+			t.Fatalf("%x: got %v", b4, a)
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	p := MustParsePrefix("10.1.2.3/8")
+	if p.String() != "10.0.0.0/8" {
+		t.Errorf("want masked prefix, but %v", p)
+	}
+	first, last := p.Range()
+	if first.String() != "10.0.0.0" || last.String() != "10.255.255.255" {
+		t.Errorf("unexpected range %v-%v", first, last)
+	}
+	for s, want := range map[string]bool{"10.0.0.0": true, "10.255.255.255": true, "11.0.0.0": false, "::a00:1": false} {
+		if p.Contains(MustParseAddr(s)) != want {
+			t.Errorf("%v contains %s: want %v", p, s, want)
+		}
+	}
+	sub := MustParsePrefix("10.9.0.0/16")
+	if !p.ContainsPrefix(sub) || sub.ContainsPrefix(p) || !sub.Overlaps(p) || sub.Overlaps(MustParsePrefix("11.0.0.0/8")) {
+		t.Error("unexpected prefix relation")
+	}
+	lo, hi, ok := MustParsePrefix("2001:db8::/32").Split()
+	if !ok || lo.String() != "2001:db8::/33" || hi.String() != "2001:db8:8000::/33" {
+		t.Errorf("unexpected Split: %v %v", lo, hi)
+	}
+	if _, _, ok := MustParsePrefix("1.2.3.4").Split(); ok {
+		t.Error("want no split of a single address")
+	}
+	for _, bad := range []string{"10.0.0.0/33", "10.0.0.0/x", "::/129", "10.0.0.0/-1"} {
+		if _, err := ParsePrefix(bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
+
+func prefixStrings(ps []Prefix) string {
+	ss := make([]string, len(ps))
+	for i, p := range ps {
+		ss[i] = p.String()
+	}
+	return strings.Join(ss, " ")
+}
+
+func TestRangeToPrefixes(t *testing.T) {
+	testcases := []struct {
+		first, last, want string
+	}{
+		{"10.0.0.0", "10.0.0.255", "10.0.0.0/24"},
+		{"10.0.0.1", "10.0.0.6", "10.0.0.1/32 10.0.0.2/31 10.0.0.4/31 10.0.0.6/32"},
+		{"0.0.0.0", "255.255.255.255", "0.0.0.0/0"},
+		{"255.255.255.254", "255.255.255.255", "255.255.255.254/31"},
+		{"::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "::/0"},
+		{"::ffff:ffff:ffff:ffff", "0:0:0:1::1", "::ffff:ffff:ffff:ffff/128 0:0:0:1::/127"},
+	}
+	for _, tc := range testcases {
+		got, err := RangeToPrefixes(MustParseAddr(tc.first), MustParseAddr(tc.last))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := prefixStrings(got); s != tc.want {
+			t.Errorf("%s-%s: want %s, but %s", tc.first, tc.last, tc.want, s)
+		}
+	}
+	if _, err := RangeToPrefixes(MustParseAddr("10.0.0.2"), MustParseAddr("10.0.0.1")); err == nil {
+		t.Error("want error for reversed range")
+	}
+	if _, err := RangeToPrefixes(MustParseAddr("10.0.0.1"), MustParseAddr("::1")); err == nil {
+		t.Error("want error for mixed families")
+	}
+}
+
+// addrSet 返回 prefixes 覆盖的所有 IPv4 地址，只用于很小的网段。
+func addrSet(prefixes []Prefix) map[Addr]bool {
+	set := make(map[Addr]bool)
+	for _, p := range prefixes {
+		first, last := p.Range()
+		for a := first; ; a = a.Next() {
+			set[a] = true
+			if a == last {
+				break
+			}
+		}
+	}
+	return set
+}
+
+func randomPrefix(rnd *rand.Rand, minBits int) Prefix {
+	// 集中在 10.0.0.0/22 中，使前缀之间经常重叠
+	a := AddrFrom4([4]byte{10, 0, byte(rnd.Intn(4)), byte(rnd.Intn(256))})
+	p, _ := PrefixFrom(a, minBits+rnd.Intn(33-minBits))
+	return p
+}
+
+func TestRangeAndAggregateRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	for range 200 {
+		first := AddrFrom4([4]byte{10, 0, byte(rnd.Intn(4)), byte(rnd.Intn(256))})
+		last := AddrFrom4([4]byte{10, 0, byte(rnd.Intn(4)), byte(rnd.Intn(256))})
+		if last.Less(first) {
+			first, last = last, first
+		}
+		ps, err := RangeToPrefixes(first, last)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set := addrSet(ps)
+		if want := int(last.u.lo-first.u.lo) + 1; len(set) != want || !set[first] || !set[last] {
+			t.Fatalf("%v-%v: %v covers %d addresses, want %d", first, last, ps, len(set), want)
+		}
+		if agg := Aggregate(ps); prefixStrings(agg) != prefixStrings(ps) {
+			t.Fatalf("%v-%v: aggregating a minimal cover changed it: %v -> %v", first, last, ps, agg)
+		}
+
+		var in []Prefix
+		for range 1 + rnd.Intn(8) {
+			in = append(in, randomPrefix(rnd, 22))
+		}
+		agg := Aggregate(in)
+		want, got := addrSet(in), addrSet(agg)
+		if len(want) != len(got) {
+			t.Fatalf("Aggregate(%v) = %v covers %d addresses, want %d", in, agg, len(got), len(want))
+		}
+		for a := range want {
+			if !got[a] {
+				t.Fatalf("Aggregate(%v) = %v misses %v", in, agg, a)
+			}
+		}
+		for i := 1; i < len(agg); i++ {
+			_, prevLast := agg[i-1].Range()
+			if !prevLast.Less(agg[i].Addr()) {
+				t.Fatalf("Aggregate(%v) = %v is not sorted or overlaps", in, agg)
+			}
+		}
+	}
+	if agg := Aggregate([]Prefix{MustParsePrefix("::/1"), MustParsePrefix("8000::/1"), MustParsePrefix("0.0.0.0/1"), {}}); prefixStrings(agg) != "0.0.0.0/1 ::/0" {
+		t.Errorf("unexpected aggregate %v", agg)
+	}
+}
+
+func TestTrie(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	var trie Trie[int]
+	ref := make(map[Prefix]int)
+	lookup := func(a Addr) (Prefix, int, bool) {
+		var best Prefix
+		found := false
+		for p := range ref {
+			if p.Contains(a) && (!found || p.bits > best.bits) {
+				best, found = p, true
+			}
+		}
+		return best, ref[best], found
+	}
+	for i := range 2000 {
+		p := randomPrefix(rnd, 16)
+		if rnd.Intn(3) == 0 {
+			_, inRef := ref[p]
+			if trie.Delete(p) != inRef {
+				t.Fatalf("Delete(%v): want %v", p, inRef)
+			}
+			delete(ref, p)
+		} else {
+			trie.Insert(p, i)
+			ref[p] = i
+		}
+		if trie.Len() != len(ref) {
+			t.Fatalf("want Len %d, but %d", len(ref), trie.Len())
+		}
+		a := AddrFrom4([4]byte{10, 0, byte(rnd.Intn(4)), byte(rnd.Intn(256))})
+		wp, wv, wok := lookup(a)
+		if gp, gv, gok := trie.Lookup(a); gp != wp || gv != wv || gok != wok {
+			t.Fatalf("Lookup(%v): want %v %v %v, but %v %v %v", a, wp, wv, wok, gp, gv, gok)
+		}
+	}
+	for p, v := range ref {
+		if got, ok := trie.Get(p); !ok || got != v {
+			t.Errorf("Get(%v): want %v, but %v %v", p, v, got, ok)
+		}
+	}
+	var walked []Prefix
+	trie.Walk(func(p Prefix, _ int) bool {
+		walked = append(walked, p)
+		return true
+	})
+	if !slices.IsSortedFunc(walked, Prefix.Compare) || len(walked) != len(ref) {
+		t.Errorf("Walk visited %d prefixes, want %d sorted", len(walked), len(ref))
+	}
+}
+
+func TestTrieIPv6(t *testing.T) {
+	var trie Trie[string]
+	for _, s := range []string{"::/0", "2001:db8::/32", "2001:db8:1::/48", "2001:db8:1::1/128", "0.0.0.0/0"} {
+		trie.Insert(MustParsePrefix(s), s)
+	}
+	testcases := map[string]string{
+		"2001:db8:1::1": "2001:db8:1::1/128",
+		"2001:db8:1::2": "2001:db8:1::/48",
+		"2001:db8:2::1": "2001:db8::/32",
+		"fe80::1":       "::/0",
+		"1.2.3.4":       "0.0.0.0/0",
+	}
+	for s, want := range testcases {
+		if _, got, _ := trie.Lookup(MustParseAddr(s)); got != want {
+			t.Errorf("Lookup(%s): want %s, but %s", s, want, got)
+		}
+	}
+	trie.Delete(MustParsePrefix("::/0"))
+	if _, _, ok := trie.Lookup(MustParseAddr("fe80::1")); ok {
+		t.Error("want no match after deleting the default route")
+	}
+}
+
+func TestList(t *testing.T) {
+	l, err := ParseList(strings.NewReader(`
+# 只允许内网，但拒绝访客网段
+allow 10.0.0.0/8
+deny  10.99.0.0/16
+allow 10.99.1.1
+ALLOW ::1
+`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testcases := map[string]bool{
+		"10.1.2.3":          true,
+		"10.99.0.1":         false,
+		"10.99.1.1":         true,
+		"::ffff:10.1.2.3":   true,
+		"::ffff:10.99.0.1":  false,
+		"::1":               true,
+		"192.168.0.1":       false,
+		"not an ip address": false,
+	}
+	for s, want := range testcases {
+		if got := l.AllowedString(s); got != want {
+			t.Errorf("%s: want %v, but %v", s, want, got)
+		}
+	}
+	for i, bad := range []string{"allow", "permit 10.0.0.0/8", "deny 10.0.0.0/40", "default maybe", "default deny\ndefault allow"} {
+		want := fmt.Sprintf("line %d", 2+strings.Count(bad, "\n"))
+		if _, err := ParseList(strings.NewReader(fmt.Sprintf("# %d\n%s\n", i, bad)), true); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: want error at %s, but %v", bad, want, err)
+		}
+	}
+	// 默认规则：default 指令优先，其次只要有 allow 规则就默认拒绝，最后使用参数
+	for _, tc := range []struct {
+		file         string
+		defaultAllow bool
+		want         bool
+	}{
+		{"allow 10.0.0.0/8", true, false},
+		{"deny 10.0.0.0/8", true, true},
+		{"deny 10.0.0.0/8", false, false},
+		{"# empty", true, true},
+		{"default allow\nallow 10.0.0.0/8", false, true},
+		{"DEFAULT DENY\ndeny 10.0.0.0/8", true, false},
+	} {
+		l, err := ParseList(strings.NewReader(tc.file), tc.defaultAllow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := l.AllowedString("192.168.0.1"); got != tc.want {
+			t.Errorf("%q with defaultAllow %v: want %v for an unmatched address, but %v", tc.file, tc.defaultAllow, tc.want, got)
+		}
+	}
+	ps, err := ParsePrefixes(" 10.0.0.0/8, ,::1 ")
+	if err != nil || prefixStrings(ps) != "10.0.0.0/8 ::1/128" {
+		t.Errorf("ParsePrefixes: %v %v", ps, err)
+	}
+}
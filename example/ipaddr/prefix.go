@@ -0,0 +1,173 @@
+package ipaddr
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Prefix 是一个 CIDR 前缀，例如 10.0.0.0/8。Prefix 总是规范化的：前缀长度之后的主机位都是 0。
+type Prefix struct {
+	addr Addr
+	bits int
+}
+
+// PrefixFrom 返回 addr 的前 bits 位组成的前缀，主机位被清零。
+func PrefixFrom(addr Addr, bits int) (Prefix, error) {
+	if !addr.IsValid() {
+		return Prefix{}, errors.New("ipaddr: invalid address")
+	}
+	if bits < 0 || bits > addr.BitLen() {
+		return Prefix{}, fmt.Errorf("ipaddr: prefix length %d out of range for %v", bits, addr)
+	}
+	return Prefix{Addr{addr.u.andNot(lowOnes(addr.BitLen() - bits)), addr.bitLen}, bits}, nil
+}
+
+// ParsePrefix 解析 "10.0.0.0/8"、"2001:db8::/32" 等形式的前缀，主机位不为 0 的前缀
+// （例如 10.1.2.3/8）会被规范化。不带长度的地址视为只包含它自己的前缀。
+func ParsePrefix(s string) (Prefix, error) {
+	addrStr, bitsStr, hasBits := strings.Cut(s, "/")
+	a, err := netip.ParseAddr(addrStr)
+	if err != nil {
+		return Prefix{}, err
+	}
+	addr := AddrFromNetIP(a)
+	bits := addr.BitLen()
+	if hasBits {
+		if bits, err = strconv.Atoi(bitsStr); err != nil {
+			return Prefix{}, fmt.Errorf("ipaddr: invalid prefix length in %q", s)
+		}
+	}
+	return PrefixFrom(addr, bits)
+}
+
+// MustParsePrefix 与 ParsePrefix 相同，但出错时 panic，适合用于常量。
+func MustParsePrefix(s string) Prefix {
+	p, err := ParsePrefix(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p Prefix) Addr() Addr    { return p.addr }
+func (p Prefix) Bits() int     { return p.bits }
+func (p Prefix) IsValid() bool { return p.addr.IsValid() }
+
+// IsSingleIP 判断 p 是否只包含一个地址。
+func (p Prefix) IsSingleIP() bool {
+	return p.IsValid() && p.bits == p.addr.BitLen()
+}
+
+// Range 返回 p 中的第一个和最后一个地址。
+func (p Prefix) Range() (first, last Addr) {
+	return p.addr, Addr{p.addr.u.or(lowOnes(p.addr.BitLen() - p.bits)), p.addr.bitLen}
+}
+
+// Contains 判断 a 是否在 p 中，IPv4 地址不在任何 IPv6 前缀中，反之亦然。
+func (p Prefix) Contains(a Addr) bool {
+	if !p.IsValid() || a.bitLen != p.addr.bitLen {
+		return false
+	}
+	return a.u.andNot(lowOnes(a.BitLen()-p.bits)) == p.addr.u
+}
+
+// ContainsPrefix 判断 o 是否是 p 的子网（包括 p 自己）。
+func (p Prefix) ContainsPrefix(o Prefix) bool {
+	return o.bits >= p.bits && p.Contains(o.addr)
+}
+
+// Overlaps 判断 p 和 o 是否有公共的地址。两个前缀要么不相交，要么一个包含另一个。
+func (p Prefix) Overlaps(o Prefix) bool {
+	return p.ContainsPrefix(o) || o.ContainsPrefix(p)
+}
+
+// Split 将 p 等分为两个长度加一的子网，p 只包含一个地址时返回 false。
+func (p Prefix) Split() (lo, hi Prefix, ok bool) {
+	if !p.IsValid() || p.IsSingleIP() {
+		return Prefix{}, Prefix{}, false
+	}
+	half := lowOnes(p.addr.BitLen() - p.bits - 1).addOne()
+	return Prefix{p.addr, p.bits + 1}, Prefix{Addr{p.addr.u.or(half), p.addr.bitLen}, p.bits + 1}, true
+}
+
+// Compare 先按地址再按长度比较前缀，排序后父网在子网之前。
+func (p Prefix) Compare(o Prefix) int {
+	if c := p.addr.Compare(o.addr); c != 0 {
+		return c
+	}
+	return p.bits - o.bits
+}
+
+func (p Prefix) String() string {
+	if !p.IsValid() {
+		return "invalid Prefix"
+	}
+	return p.addr.String() + "/" + strconv.Itoa(p.bits)
+}
+
+// commonBits 返回 p 和 o 的公共前缀长度，二者必须是同一种地址。
+func commonBits(p, o Prefix) int {
+	n := p.addr.u.xor(o.addr.u).leadingZeros() - (128 - p.addr.BitLen())
+	return min(n, p.bits, o.bits)
+}
+
+// RangeToPrefixes 返回恰好覆盖 [first, last] 的最少的前缀，按地址排序。
+func RangeToPrefixes(first, last Addr) ([]Prefix, error) {
+	if !first.IsValid() || first.bitLen != last.bitLen {
+		return nil, fmt.Errorf("ipaddr: invalid range %v-%v", first, last)
+	}
+	if last.Less(first) {
+		return nil, fmt.Errorf("ipaddr: range %v-%v is reversed", first, last)
+	}
+	bitLen := first.BitLen()
+	var ret []Prefix
+	for {
+		// 从 first 开始、不超过 last 的最大的对齐块
+		k := min(first.u.trailingZeros(), bitLen)
+		for first.u.or(lowOnes(k)).cmp(last.u) > 0 {
+			k--
+		}
+		ret = append(ret, Prefix{first, bitLen - k})
+		end := Addr{first.u.or(lowOnes(k)), first.bitLen}
+		if end == last {
+			return ret, nil
+		}
+		first = end.Next()
+	}
+}
+
+// Aggregate 合并 prefixes 中重叠和相邻的前缀，返回覆盖相同地址的最少的前缀，按地址排序。
+// 无效的前缀被忽略。
+func Aggregate(prefixes []Prefix) []Prefix {
+	sorted := make([]Prefix, 0, len(prefixes))
+	for _, p := range prefixes {
+		if p.IsValid() {
+			sorted = append(sorted, p)
+		}
+	}
+	slices.SortFunc(sorted, Prefix.Compare)
+	var ret []Prefix
+	flush := func(first, last Addr) {
+		ps, _ := RangeToPrefixes(first, last)
+		ret = append(ret, ps...)
+	}
+	for i := 0; i < len(sorted); {
+		first, last := sorted[i].Range()
+		for i++; i < len(sorted); i++ {
+			f, l := sorted[i].Range()
+			// 排序后 f >= first，只需判断 f 是否在 last 之后紧接着或更早
+			if f.bitLen != last.bitLen || last.Next().IsValid() && last.Next().Less(f) {
+				break
+			}
+			if last.Less(l) {
+				last = l
+			}
+		}
+		flush(first, last)
+	}
+	return ret
+}
@@ -0,0 +1,161 @@
+package ipaddr
+
+// trieNode 是路径压缩的二叉基数树（patricia trie）的节点。set 为 false 的节点只是
+// 两个子树的分叉点，它总是有两个孩子。
+type trieNode[V any] struct {
+	prefix Prefix
+	value  V
+	set    bool
+	child  [2]*trieNode[V]
+}
+
+// Trie 是以前缀为 key 的基数树，支持最长前缀匹配。IPv4 和 IPv6 前缀分别存放在两棵树中。
+// 零值可以直接使用，并发使用不安全。
+type Trie[V any] struct {
+	root4, root6 *trieNode[V]
+	n            int
+}
+
+func (t *Trie[V]) root(bitLen int) **trieNode[V] {
+	if bitLen == 32 {
+		return &t.root4
+	}
+	return &t.root6
+}
+
+// Len 返回前缀的数量。
+func (t *Trie[V]) Len() int {
+	return t.n
+}
+
+// Insert 插入或替换前缀 p 对应的值，p 无效时什么都不做。
+func (t *Trie[V]) Insert(p Prefix, v V) {
+	if !p.IsValid() {
+		return
+	}
+	np := t.root(p.addr.BitLen())
+	for {
+		n := *np
+		if n == nil {
+			*np = &trieNode[V]{prefix: p, value: v, set: true}
+			t.n++
+			return
+		}
+		common := commonBits(n.prefix, p)
+		switch {
+		case common == n.prefix.bits && common == p.bits:
+			if !n.set {
+				t.n++
+			}
+			n.value, n.set = v, true
+			return
+		case common == n.prefix.bits:
+			// n 是 p 的父网，继续向下
+			np = &n.child[p.addr.bit(common)]
+		case common == p.bits:
+			// p 是 n 的父网，插入到 n 之上
+			nn := &trieNode[V]{prefix: p, value: v, set: true}
+			nn.child[n.prefix.addr.bit(common)] = n
+			*np = nn
+			t.n++
+			return
+		default:
+			// 在公共前缀处分叉
+			fork, _ := PrefixFrom(p.addr, common)
+			mid := &trieNode[V]{prefix: fork}
+			mid.child[n.prefix.addr.bit(common)] = n
+			mid.child[p.addr.bit(common)] = &trieNode[V]{prefix: p, value: v, set: true}
+			*np = mid
+			t.n++
+			return
+		}
+	}
+}
+
+// find 返回指向 p 所在节点的指针和它父节点的指针，不存在时返回 nil。
+func (t *Trie[V]) find(p Prefix) (np, parent **trieNode[V]) {
+	if !p.IsValid() {
+		return nil, nil
+	}
+	np = t.root(p.addr.BitLen())
+	for n := *np; n != nil && n.prefix.ContainsPrefix(p); n = *np {
+		if n.prefix.bits == p.bits {
+			return np, parent
+		}
+		parent, np = np, &n.child[p.addr.bit(n.prefix.bits)]
+	}
+	return nil, nil
+}
+
+// Get 返回前缀 p 精确对应的值。
+func (t *Trie[V]) Get(p Prefix) (v V, ok bool) {
+	if np, _ := t.find(p); np != nil && (*np).set {
+		return (*np).value, true
+	}
+	return v, false
+}
+
+// Delete 删除前缀 p，返回它是否存在。
+func (t *Trie[V]) Delete(p Prefix) bool {
+	np, parent := t.find(p)
+	if np == nil || !(*np).set {
+		return false
+	}
+	n := *np
+	var zero V
+	n.value, n.set = zero, false
+	t.n--
+	switch {
+	case n.child[0] != nil && n.child[1] != nil:
+		// 保留为分叉点
+	case n.child[0] != nil:
+		*np = n.child[0]
+	case n.child[1] != nil:
+		*np = n.child[1]
+	default:
+		*np = nil
+		// 父节点如果是分叉点，现在只剩一个孩子，用孩子代替它
+		if parent != nil && !(*parent).set {
+			pn := *parent
+			if pn.child[0] != nil {
+				*parent = pn.child[0]
+			} else {
+				*parent = pn.child[1]
+			}
+		}
+	}
+	return true
+}
+
+// Lookup 返回包含 a 的最长的前缀和它对应的值。IPv4 映射的 IPv6 地址只匹配 IPv6 前缀，
+// 需要时先调用 Addr.Unmap。
+func (t *Trie[V]) Lookup(a Addr) (p Prefix, v V, ok bool) {
+	if !a.IsValid() {
+		return p, v, false
+	}
+	for n := *t.root(a.BitLen()); n != nil && n.prefix.Contains(a); {
+		if n.set {
+			p, v, ok = n.prefix, n.value, true
+		}
+		if n.prefix.bits == a.BitLen() {
+			break
+		}
+		n = n.child[a.bit(n.prefix.bits)]
+	}
+	return p, v, ok
+}
+
+// Walk 按 Prefix.Compare 的顺序对每个前缀调用 f，f 返回 false 时停止。
+func (t *Trie[V]) Walk(f func(Prefix, V) bool) {
+	var walk func(n *trieNode[V]) bool
+	walk = func(n *trieNode[V]) bool {
+		if n == nil {
+			return true
+		}
+		if n.set && !f(n.prefix, n.value) {
+			return false
+		}
+		return walk(n.child[0]) && walk(n.child[1])
+	}
+	_ = walk(t.root4) && walk(t.root6)
+}
@@ -0,0 +1,308 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock 抽象了限流器用到的时间函数，测试时可以替换为手动推进的时钟。
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SystemClock 是使用 time 包的 Clock，各限流器的 Clock 为 nil 时使用它。
+var SystemClock Clock = systemClock{}
+
+func clockOrSystem(c Clock) Clock {
+	if c == nil {
+		return SystemClock
+	}
+	return c
+}
+
+// Limiter 决定某个 key 对应的操作能否执行。
+// 成功时返回的 release 必须在操作结束后调用且只调用一次，
+// 对于只限制速率的 Limiter，release 什么也不做。
+type Limiter interface {
+	// Acquire 阻塞到允许执行或 ctx 结束。
+	Acquire(ctx context.Context, key string) (release func(), err error)
+	// TryAcquire 不阻塞，不允许执行时返回 false。
+	TryAcquire(key string) (release func(), ok bool)
+}
+
+func noRelease() {}
+
+// wait 在 ctx 结束前等待 d。
+func wait(ctx context.Context, clock Clock, d time.Duration) error {
+	select {
+	case <-clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TokenBucket 是令牌桶限流器：桶的容量为 burst，每秒补充 rate 个令牌，每次操作
+// 消耗一个令牌。它不区分 key。Clock 需要在第一次使用前设置好。
+type TokenBucket struct {
+	Clock Clock
+
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket 创建一个装满令牌的 TokenBucket，rate 不是正数或 burst 小于 1 时 panic。
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if !(rate > 0) || burst < 1 {
+		panic(fmt.Sprintf("NewTokenBucket: invalid rate %v or burst %d", rate, burst))
+	}
+	return &TokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// take 尝试消耗一个令牌，失败时返回需要等待的时间。需要持有 tb.mu。
+func (tb *TokenBucket) take() (ok bool, retryAfter time.Duration) {
+	now := clockOrSystem(tb.Clock).Now()
+	if !tb.last.IsZero() {
+		tb.tokens = min(tb.burst, tb.tokens+now.Sub(tb.last).Seconds()*tb.rate)
+	}
+	tb.last = now
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+}
+
+func (tb *TokenBucket) TryAcquire(string) (func(), bool) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	ok, _ := tb.take()
+	return noRelease, ok
+}
+
+func (tb *TokenBucket) Acquire(ctx context.Context, _ string) (func(), error) {
+	for {
+		tb.mu.Lock()
+		ok, retryAfter := tb.take()
+		tb.mu.Unlock()
+		if ok {
+			return noRelease, nil
+		}
+		if err := wait(ctx, clockOrSystem(tb.Clock), retryAfter); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// SlidingWindow 限制任意长度为 window 的时间段内最多执行 limit 次操作。
+// 它记录每次操作的时间，不区分 key。Clock 需要在第一次使用前设置好。
+type SlidingWindow struct {
+	Clock Clock
+
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	log    []time.Time // 窗口内的操作时间，升序
+}
+
+// NewSlidingWindow 创建 SlidingWindow，limit 小于 1 或 window 不是正数时 panic。
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	if limit < 1 || window <= 0 {
+		panic(fmt.Sprintf("NewSlidingWindow: invalid limit %d or window %v", limit, window))
+	}
+	return &SlidingWindow{limit: limit, window: window, log: make([]time.Time, 0, limit)}
+}
+
+// take 需要持有 sw.mu。
+func (sw *SlidingWindow) take() (ok bool, retryAfter time.Duration) {
+	now := clockOrSystem(sw.Clock).Now()
+	expired := 0
+	for expired < len(sw.log) && !sw.log[expired].Add(sw.window).After(now) {
+		expired++
+	}
+	sw.log = Cut(sw.log, 0, expired)
+	if len(sw.log) < sw.limit {
+		sw.log = append(sw.log, now)
+		return true, 0
+	}
+	return false, sw.log[0].Add(sw.window).Sub(now)
+}
+
+func (sw *SlidingWindow) TryAcquire(string) (func(), bool) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	ok, _ := sw.take()
+	return noRelease, ok
+}
+
+func (sw *SlidingWindow) Acquire(ctx context.Context, _ string) (func(), error) {
+	for {
+		sw.mu.Lock()
+		ok, retryAfter := sw.take()
+		sw.mu.Unlock()
+		if ok {
+			return noRelease, nil
+		}
+		if err := wait(ctx, clockOrSystem(sw.Clock), retryAfter); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// keyedSem 是某个 key 的信号量，refs 是持有者和等待者的总数，为 0 时从 map 中删除。
+type keyedSem struct {
+	ch   chan struct{}
+	refs int
+}
+
+// KeyedSemaphore 限制每个 key 同时进行的操作数不超过 n。
+type KeyedSemaphore struct {
+	mu   sync.Mutex
+	n    int
+	sems map[string]*keyedSem
+}
+
+// NewKeyedSemaphore 创建 KeyedSemaphore，n 小于 1 时 panic。
+func NewKeyedSemaphore(n int) *KeyedSemaphore {
+	if n < 1 {
+		panic(fmt.Sprintf("NewKeyedSemaphore: invalid n %d", n))
+	}
+	return &KeyedSemaphore{n: n, sems: make(map[string]*keyedSem)}
+}
+
+func (ks *KeyedSemaphore) ref(key string) *keyedSem {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	sem, ok := ks.sems[key]
+	if !ok {
+		sem = &keyedSem{ch: make(chan struct{}, ks.n)}
+		ks.sems[key] = sem
+	}
+	sem.refs++
+	return sem
+}
+
+func (ks *KeyedSemaphore) unref(key string, sem *keyedSem) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	sem.refs--
+	if sem.refs == 0 {
+		delete(ks.sems, key)
+	}
+}
+
+func (ks *KeyedSemaphore) releaser(key string, sem *keyedSem) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-sem.ch
+			ks.unref(key, sem)
+		})
+	}
+}
+
+func (ks *KeyedSemaphore) TryAcquire(key string) (func(), bool) {
+	sem := ks.ref(key)
+	select {
+	case sem.ch <- struct{}{}:
+		return ks.releaser(key, sem), true
+	default:
+		ks.unref(key, sem)
+		return nil, false
+	}
+}
+
+func (ks *KeyedSemaphore) Acquire(ctx context.Context, key string) (func(), error) {
+	sem := ks.ref(key)
+	select {
+	case sem.ch <- struct{}{}:
+		return ks.releaser(key, sem), nil
+	case <-ctx.Done():
+		ks.unref(key, sem)
+		return nil, ctx.Err()
+	}
+}
+
+// InUse 返回 key 当前正在进行的操作数。
+func (ks *KeyedSemaphore) InUse(key string) int {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if sem, ok := ks.sems[key]; ok {
+		return len(sem.ch)
+	}
+	return 0
+}
+
+// chainLimiter 依次从每个 Limiter 获取许可，全部成功才算成功。
+type chainLimiter []Limiter
+
+// Chain 将多个 Limiter 组合为一个，例如同时限制总速率和每个 key 的并发数。
+// 许可按 limiters 的顺序获取，某一个失败时已获取的许可会被 release，但 TokenBucket 等
+// 只限制速率的 Limiter 已消耗的令牌不会退还。因此应当把可能拒绝的并发限制放在前面、
+// 速率限制放在最后，避免被拒绝的操作白白消耗速率。
+func Chain(limiters ...Limiter) Limiter {
+	return chainLimiter(limiters)
+}
+
+func releaseAll(releases []func()) func() {
+	return func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+}
+
+func (cl chainLimiter) TryAcquire(key string) (func(), bool) {
+	releases := make([]func(), 0, len(cl))
+	for _, l := range cl {
+		release, ok := l.TryAcquire(key)
+		if !ok {
+			releaseAll(releases)()
+			return nil, false
+		}
+		releases = append(releases, release)
+	}
+	return releaseAll(releases), true
+}
+
+func (cl chainLimiter) Acquire(ctx context.Context, key string) (func(), error) {
+	releases := make([]func(), 0, len(cl))
+	for _, l := range cl {
+		release, err := l.Acquire(ctx, key)
+		if err != nil {
+			releaseAll(releases)()
+			return nil, err
+		}
+		releases = append(releases, release)
+	}
+	return releaseAll(releases), nil
+}
+
+// LimitAction 返回先从 l 获取许可再执行 action 的 Action，可以传给 Poller.Poll、
+// ChanPoll、ChanPoller.Poll 或 NewSupervisor。key 为 nil 时所有 Request 共用一个空
+// key。获取许可时 ctx 结束会使本次执行失败，并按照队列的 RetryPolicy 处理。
+func LimitAction(ctx context.Context, l Limiter, key func(*Request) string, action Action) Action {
+	return func(req *Request) error {
+		k := ""
+		if key != nil {
+			k = key(req)
+		}
+		release, err := l.Acquire(ctx, k)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return action(req)
+	}
+}
@@ -0,0 +1,256 @@
+package example
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock 只有在调用 Advance 时才会前进。
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	added   chan struct{} // 每次有新的 After 调用时收到一个值
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0), added: make(chan struct{}, 64)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+	} else {
+		c.waiters = append(c.waiters, fakeWaiter{c.now.Add(d), ch})
+	}
+	c.added <- struct{}{}
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.waiters = Filter(c.waiters, func(w fakeWaiter) bool {
+		if w.deadline.After(c.now) {
+			return true
+		}
+		w.ch <- c.now
+		return false
+	})
+}
+
+func countTry(l Limiter, key string, n int) (ok int) {
+	for range n {
+		if _, success := l.TryAcquire(key); success {
+			ok++
+		}
+	}
+	return
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := newFakeClock()
+	tb := NewTokenBucket(10, 5)
+	tb.Clock = clock
+	if ok := countTry(tb, "", 10); ok != 5 {
+		t.Errorf("burst: want 5, but %d", ok)
+	}
+	clock.Advance(250 * time.Millisecond)
+	if ok := countTry(tb, "", 10); ok != 2 {
+		t.Errorf("after 250ms: want 2, but %d", ok)
+	}
+	clock.Advance(time.Hour)
+	if ok := countTry(tb, "", 10); ok != 5 {
+		t.Errorf("after 1h: want 5, but %d", ok)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := tb.Acquire(context.Background(), "")
+		done <- err
+	}()
+	<-clock.added
+	clock.Advance(50 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("Acquire returned before a token is available")
+	default:
+	}
+	clock.Advance(50 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := newFakeClock()
+	sw := NewSlidingWindow(3, time.Second)
+	sw.Clock = clock
+	if ok := countTry(sw, "", 5); ok != 3 {
+		t.Errorf("want 3, but %d", ok)
+	}
+	clock.Advance(999 * time.Millisecond)
+	if ok := countTry(sw, "", 5); ok != 0 {
+		t.Errorf("inside the window: want 0, but %d", ok)
+	}
+	clock.Advance(time.Millisecond)
+	if ok := countTry(sw, "", 1); ok != 1 {
+		t.Errorf("window slides: want 1, but %d", ok)
+	}
+	countTry(sw, "", 2)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := sw.Acquire(ctx, "")
+		done <- err
+	}()
+	<-clock.added
+	cancelFunc()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("want Canceled, but %v", err)
+	}
+}
+
+func TestKeyedSemaphore(t *testing.T) {
+	ks := NewKeyedSemaphore(2)
+	r1, ok1 := ks.TryAcquire("a")
+	r2, ok2 := ks.TryAcquire("a")
+	_, ok3 := ks.TryAcquire("a")
+	rb, okb := ks.TryAcquire("b")
+	if !ok1 || !ok2 || ok3 || !okb {
+		t.Fatalf("unexpected results: %v %v %v %v", ok1, ok2, ok3, okb)
+	}
+	if ks.InUse("a") != 2 || ks.InUse("b") != 1 {
+		t.Errorf("unexpected in use: %d %d", ks.InUse("a"), ks.InUse("b"))
+	}
+	r1()
+	r1() // release is idempotent
+	if _, ok := ks.TryAcquire("a"); !ok {
+		t.Error("want success after release")
+	}
+	r2()
+	rb()
+	if len(ks.sems) != 1 {
+		t.Errorf("idle keys should be removed, but %d left", len(ks.sems))
+	}
+}
+
+func TestLimiterInvalidArgs(t *testing.T) {
+	testcases := []struct {
+		name string
+		f    func()
+	}{
+		{"NewTokenBucket", func() { NewTokenBucket(0, 1) }},
+		{"NewTokenBucket", func() { NewTokenBucket(math.NaN(), 1) }},
+		{"NewTokenBucket", func() { NewTokenBucket(1, 0) }},
+		{"NewSlidingWindow", func() { NewSlidingWindow(0, time.Second) }},
+		{"NewSlidingWindow", func() { NewSlidingWindow(1, 0) }},
+		{"NewKeyedSemaphore", func() { NewKeyedSemaphore(-1) }},
+	}
+	for _, tc := range testcases {
+		func() {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.HasPrefix(msg, tc.name+":") {
+					t.Errorf("%s: want panic, but %q", tc.name, msg)
+				}
+			}()
+			tc.f()
+		}()
+	}
+}
+
+func TestChain(t *testing.T) {
+	clock := newFakeClock()
+	tb := NewTokenBucket(1, 1)
+	tb.Clock = clock
+	ks := NewKeyedSemaphore(1)
+	l := Chain(ks, tb)
+	release, ok := l.TryAcquire("a")
+	if !ok {
+		t.Fatal("want success")
+	}
+	release()
+	if _, ok := l.TryAcquire("a"); ok {
+		t.Error("want failure when the bucket is empty")
+	}
+	if ks.InUse("a") != 0 {
+		t.Error("semaphore should be released when a later limiter fails")
+	}
+
+	// 被前面的并发限制拒绝的操作不会消耗后面的令牌
+	tb = NewTokenBucket(1, 2)
+	tb.Clock = clock
+	l = Chain(ks, tb)
+	release, ok = l.TryAcquire("a")
+	if !ok {
+		t.Fatal("want success")
+	}
+	defer release()
+	for range 3 {
+		if _, ok := l.TryAcquire("a"); ok {
+			t.Fatal("want failure when a is at its concurrency limit")
+		}
+	}
+	if _, ok := l.TryAcquire("b"); !ok {
+		t.Error("rejected requests of a should not consume tokens")
+	}
+}
+
+func TestLimitAction(t *testing.T) {
+	const REQUEST_NUM = 100
+	ks := NewKeyedSemaphore(2)
+	var running, peak atomic.Int64
+	action := LimitAction(context.Background(), ks, nil, func(*Request) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Microsecond)
+		running.Add(-1)
+		return nil
+	})
+	in := make(chan *Request, REQUEST_NUM)
+	req := Request("1")
+	for range REQUEST_NUM {
+		in <- &req
+	}
+	close(in)
+	var wg sync.WaitGroup
+	wg.Add(8)
+	for range 8 {
+		go func() {
+			defer wg.Done()
+			ChanPoll(context.Background(), in, action, RetryPolicy{}, nil)
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 2 {
+		t.Errorf("want at most 2 concurrent actions, but %d", peak.Load())
+	}
+}
@@ -0,0 +1,452 @@
+package example
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MetricType 是指标的类型。
+type MetricType int
+
+const (
+	CounterType MetricType = iota
+	GaugeType
+	HistogramType
+)
+
+func (t MetricType) String() string {
+	switch t {
+	case CounterType:
+		return "counter"
+	case GaugeType:
+		return "gauge"
+	case HistogramType:
+		return "histogram"
+	}
+	return "untyped"
+}
+
+// metricShards 是每个指标的分片数。SafeCounter 用一把锁保护整个 map，
+// Registry 将同一指标的不同标签组合按哈希分到不同分片，各分片有自己的锁。
+const metricShards = 16
+
+var metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// Registry 是指标的注册表，并发使用是安全的。每个指标由名字确定，一个指标下的每组标签值
+// 对应一个时间序列。ttl 大于 0 时，超过 ttl 没有更新的时间序列会在 Snapshot、WriteText
+// 或 Expire 时被删除，因此设置了 ttl 时不应长期持有 With 返回的值，而应每次重新调用 With。
+type Registry struct {
+	// Clock 需要在第一次使用前设置好，为 nil 时使用 SystemClock。
+	Clock Clock
+
+	ttl      time.Duration
+	mu       sync.RWMutex
+	families map[string]*metricFamily
+}
+
+func NewRegistry(ttl time.Duration) *Registry {
+	return &Registry{ttl: ttl, families: make(map[string]*metricFamily)}
+}
+
+type metricFamily struct {
+	name, help string
+	typ        MetricType
+	labelNames []string
+	buckets    []float64 // 只用于直方图，升序
+	shards     [metricShards]metricShard
+}
+
+type metricShard struct {
+	mu     sync.RWMutex
+	series map[string]*metricSeries // key 是用 0xff 连接的标签值
+}
+
+// metricSeries 是一个时间序列，计数器和仪表盘的值以 float64 的位模式保存在 bits 中。
+type metricSeries struct {
+	labelValues []string
+	bits        atomic.Uint64
+	counts      []atomic.Uint64 // 只用于直方图，最后一个是 +Inf 桶
+	count       atomic.Uint64
+	touched     atomic.Int64 // 最后一次更新的 UnixNano
+}
+
+func (s *metricSeries) add(v float64) {
+	for {
+		old := s.bits.Load()
+		if s.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (s *metricSeries) value() float64 {
+	return math.Float64frombits(s.bits.Load())
+}
+
+func (r *Registry) now() int64 {
+	return clockOrSystem(r.Clock).Now().UnixNano()
+}
+
+// register 返回名为 name 的指标，不存在时创建。同名指标的类型或标签不同时 panic。
+func (r *Registry) register(name, help string, typ MetricType, labelNames []string, buckets []float64) *metricFamily {
+	if !metricNameRegexp.MatchString(name) {
+		panic(fmt.Sprintf("invalid metric name %q", name))
+	}
+	for _, l := range labelNames {
+		if !metricNameRegexp.MatchString(l) || strings.Contains(l, ":") || l == "le" {
+			panic(fmt.Sprintf("invalid label name %q", l))
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.families[name]; ok {
+		if f.typ != typ || !slices.Equal(f.labelNames, labelNames) || !slices.Equal(f.buckets, buckets) {
+			panic(fmt.Sprintf("metric %q registered again with a different type, labels or buckets", name))
+		}
+		return f
+	}
+	f := &metricFamily{name: name, help: help, typ: typ, labelNames: Clone(labelNames), buckets: buckets}
+	for i := range f.shards {
+		f.shards[i].series = make(map[string]*metricSeries)
+	}
+	r.families[name] = f
+	return f
+}
+
+// with 返回 labelValues 对应的时间序列，不存在时创建。
+func (r *Registry) with(f *metricFamily, labelValues []string) *metricSeries {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metric %q: want %d label values, but %d", f.name, len(f.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	shard := &f.shards[h.Sum32()%metricShards]
+	// touched 在持有锁时更新，Expire 不会删除刚刚返回的时间序列
+	shard.mu.RLock()
+	s, ok := shard.series[key]
+	if ok {
+		s.touched.Store(r.now())
+	}
+	shard.mu.RUnlock()
+	if !ok {
+		shard.mu.Lock()
+		if s, ok = shard.series[key]; !ok {
+			s = &metricSeries{labelValues: Clone(labelValues)}
+			if f.typ == HistogramType {
+				s.counts = make([]atomic.Uint64, len(f.buckets)+1)
+			}
+			shard.series[key] = s
+		}
+		s.touched.Store(r.now())
+		shard.mu.Unlock()
+	}
+	return s
+}
+
+// CounterVec 是带标签的计数器。
+type CounterVec struct {
+	r *Registry
+	f *metricFamily
+}
+
+// Counter 返回名为 name 的计数器，已存在时返回已有的。
+func (r *Registry) Counter(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{r, r.register(name, help, CounterType, labelNames, nil)}
+}
+
+// With 返回 labelValues 对应的计数器，labelValues 的个数需要与标签名的个数相同。
+func (cv *CounterVec) With(labelValues ...string) Counter {
+	return Counter{cv.r, cv.r.with(cv.f, labelValues)}
+}
+
+// Counter 是只增不减的计数器。
+type Counter struct {
+	r *Registry
+	s *metricSeries
+}
+
+func (c Counter) Inc() { c.Add(1) }
+
+// Add 增加 v，v 为负数时 panic。
+func (c Counter) Add(v float64) {
+	if v < 0 {
+		panic("counter cannot decrease")
+	}
+	c.s.add(v)
+	c.s.touched.Store(c.r.now())
+}
+
+func (c Counter) Value() float64 { return c.s.value() }
+
+// GaugeVec 是带标签的仪表盘。
+type GaugeVec struct {
+	r *Registry
+	f *metricFamily
+}
+
+// Gauge 返回名为 name 的仪表盘，已存在时返回已有的。
+func (r *Registry) Gauge(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{r, r.register(name, help, GaugeType, labelNames, nil)}
+}
+
+func (gv *GaugeVec) With(labelValues ...string) Gauge {
+	return Gauge{gv.r, gv.r.with(gv.f, labelValues)}
+}
+
+// Gauge 是可以任意增减的值。
+type Gauge struct {
+	r *Registry
+	s *metricSeries
+}
+
+func (g Gauge) Set(v float64) {
+	g.s.bits.Store(math.Float64bits(v))
+	g.s.touched.Store(g.r.now())
+}
+
+func (g Gauge) Add(v float64) {
+	g.s.add(v)
+	g.s.touched.Store(g.r.now())
+}
+
+func (g Gauge) Inc()           { g.Add(1) }
+func (g Gauge) Dec()           { g.Add(-1) }
+func (g Gauge) Value() float64 { return g.s.value() }
+
+// DefaultBuckets 是 Histogram 的默认桶上界，适合以秒为单位的请求延迟。
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// HistogramVec 是带标签的直方图。
+type HistogramVec struct {
+	r *Registry
+	f *metricFamily
+}
+
+// Histogram 返回名为 name 的直方图，buckets 为空时使用 DefaultBuckets。
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = Clone(buckets)
+	sort.Float64s(buckets)
+	return &HistogramVec{r, r.register(name, help, HistogramType, labelNames, buckets)}
+}
+
+func (hv *HistogramVec) With(labelValues ...string) Histogram {
+	return Histogram{hv.r, hv.f, hv.r.with(hv.f, labelValues)}
+}
+
+// Histogram 按桶统计观测值的分布。
+type Histogram struct {
+	r *Registry
+	f *metricFamily
+	s *metricSeries
+}
+
+// Observe 记录一个观测值，v 落在第一个上界不小于 v 的桶中。
+func (h Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.f.buckets, v)
+	h.s.counts[i].Add(1)
+	h.s.count.Add(1)
+	h.s.add(v)
+	h.s.touched.Store(h.r.now())
+}
+
+// FloatHistogramSnapshot 是 Histogram 的快照。Counts 比 Buckets 多一个元素，
+// Counts[i] 是落在 (Buckets[i-1], Buckets[i]] 中的个数，最后一个是大于所有上界的个数。
+type FloatHistogramSnapshot struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     float64
+}
+
+// SeriesSnapshot 是一个时间序列的快照，Histogram 只在直方图中不为 nil。
+type SeriesSnapshot struct {
+	LabelValues []string
+	Value       float64
+	Histogram   *FloatHistogramSnapshot
+}
+
+// MetricSnapshot 是一个指标的快照，Series 按标签值排序。
+type MetricSnapshot struct {
+	Name       string
+	Help       string
+	Type       MetricType
+	LabelNames []string
+	Series     []SeriesSnapshot
+}
+
+// Expire 删除超过 ttl 没有更新的时间序列，返回删除的个数。
+func (r *Registry) Expire() int {
+	if r.ttl <= 0 {
+		return 0
+	}
+	deadline := r.now() - r.ttl.Nanoseconds()
+	n := 0
+	r.eachShard(func(_ *metricFamily, shard *metricShard) {
+		shard.mu.Lock()
+		for k, s := range shard.series {
+			if s.touched.Load() < deadline {
+				delete(shard.series, k)
+				n++
+			}
+		}
+		shard.mu.Unlock()
+	})
+	return n
+}
+
+// Reset 删除所有时间序列，已注册的指标保持不变。
+func (r *Registry) Reset() {
+	r.eachShard(func(_ *metricFamily, shard *metricShard) {
+		shard.mu.Lock()
+		clear(shard.series)
+		shard.mu.Unlock()
+	})
+}
+
+func (r *Registry) eachShard(fn func(f *metricFamily, shard *metricShard)) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, f := range r.families {
+		for i := range f.shards {
+			fn(f, &f.shards[i])
+		}
+	}
+}
+
+// Snapshot 返回所有指标按名字排序的快照。各时间序列分别读取，并不是同一时刻的值。
+func (r *Registry) Snapshot() []MetricSnapshot {
+	r.Expire()
+	r.mu.RLock()
+	families := make([]*metricFamily, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.RUnlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	ret := make([]MetricSnapshot, len(families))
+	for i, f := range families {
+		ms := MetricSnapshot{Name: f.name, Help: f.help, Type: f.typ, LabelNames: Clone(f.labelNames)}
+		for j := range f.shards {
+			shard := &f.shards[j]
+			shard.mu.RLock()
+			for _, s := range shard.series {
+				ss := SeriesSnapshot{LabelValues: Clone(s.labelValues), Value: s.value()}
+				if f.typ == HistogramType {
+					hs := &FloatHistogramSnapshot{Buckets: Clone(f.buckets), Counts: make([]uint64, len(s.counts))}
+					for k := range s.counts {
+						hs.Counts[k] = s.counts[k].Load()
+					}
+					hs.Count, hs.Sum = s.count.Load(), ss.Value
+					ss.Histogram, ss.Value = hs, 0
+				}
+				ms.Series = append(ms.Series, ss)
+			}
+			shard.mu.RUnlock()
+		}
+		sort.Slice(ms.Series, func(a, b int) bool {
+			return slices.Compare(ms.Series[a].LabelValues, ms.Series[b].LabelValues) < 0
+		})
+		ret[i] = ms
+	}
+	return ret
+}
+
+// WriteText 以 Prometheus 文本格式输出所有指标。
+func (r *Registry) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, ms := range r.Snapshot() {
+		if ms.Help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", ms.Name, escapeHelp(ms.Help))
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", ms.Name, ms.Type)
+		for _, ss := range ms.Series {
+			if ss.Histogram == nil {
+				writeSample(bw, ms.Name, ms.LabelNames, ss.LabelValues, "", ss.Value)
+				continue
+			}
+			hs := ss.Histogram
+			var cum uint64
+			for i, c := range hs.Counts {
+				cum += c
+				le := math.Inf(1)
+				if i < len(hs.Buckets) {
+					le = hs.Buckets[i]
+				}
+				writeSample(bw, ms.Name+"_bucket", ms.LabelNames, ss.LabelValues, formatFloat(le), float64(cum))
+			}
+			writeSample(bw, ms.Name+"_sum", ms.LabelNames, ss.LabelValues, "", hs.Sum)
+			writeSample(bw, ms.Name+"_count", ms.LabelNames, ss.LabelValues, "", float64(hs.Count))
+		}
+	}
+	return bw.Flush()
+}
+
+// writeSample 输出一行样本，le 不为空时追加 le 标签。
+func writeSample(w io.Writer, name string, labelNames, labelValues []string, le string, v float64) {
+	var sb strings.Builder
+	sb.WriteString(name)
+	if len(labelNames) > 0 || le != "" {
+		sb.WriteByte('{')
+		for i, l := range labelNames {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(&sb, "%s=\"%s\"", l, escapeLabelValue(labelValues[i]))
+		}
+		if le != "" {
+			if len(labelNames) > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(&sb, "le=\"%s\"", le)
+		}
+		sb.WriteByte('}')
+	}
+	fmt.Fprintf(w, "%s %s\n", sb.String(), formatFloat(v))
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
+func escapeLabelValue(s string) string { return labelValueEscaper.Replace(s) }
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Handler 返回以 Prometheus 文本格式输出所有指标的 http.Handler。
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WriteText(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package example
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistryConcurrent(t *testing.T) {
+	reg := NewRegistry(0)
+	cv := reg.Counter("requests_total", "", "key")
+	gv := reg.Gauge("in_flight", "")
+	const goroutines, n = 8, 1000
+	var wg sync.WaitGroup
+	for i := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range n {
+				cv.With(fmt.Sprint(j % 10)).Inc()
+				if i%2 == 0 {
+					gv.With().Inc()
+				} else {
+					gv.With().Dec()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for k := range 10 {
+		if v := cv.With(fmt.Sprint(k)).Value(); v != goroutines*n/10 {
+			t.Errorf("key %d: want %d, but %v", k, goroutines*n/10, v)
+		}
+	}
+	if v := gv.With().Value(); v != 0 {
+		t.Errorf("gauge: want 0, but %v", v)
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	reg := NewRegistry(0)
+	a := reg.Counter("c", "", "x")
+	a.With("1").Add(2)
+	if v := reg.Counter("c", "", "x").With("1").Value(); v != 2 {
+		t.Errorf("want the same counter, but value %v", v)
+	}
+	mustPanic := func(name string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: want panic", name)
+			}
+		}()
+		f()
+	}
+	mustPanic("type", func() { reg.Gauge("c", "", "x") })
+	mustPanic("labels", func() { reg.Counter("c", "", "y") })
+	mustPanic("name", func() { reg.Counter("bad-name", "") })
+	mustPanic("label count", func() { a.With("1", "2") })
+	mustPanic("negative", func() { a.With("1").Add(-1) })
+}
+
+func TestRegistryExpire(t *testing.T) {
+	clock := newFakeClock()
+	reg := NewRegistry(time.Minute)
+	reg.Clock = clock
+	cv := reg.Counter("c", "", "k")
+	cv.With("old").Inc()
+	clock.Advance(40 * time.Second)
+	cv.With("new").Inc()
+	clock.Advance(40 * time.Second)
+	if n := reg.Expire(); n != 1 {
+		t.Errorf("want 1 expired series, but %d", n)
+	}
+	series := reg.Snapshot()[0].Series
+	if len(series) != 1 || series[0].LabelValues[0] != "new" {
+		t.Errorf("unexpected series %+v", series)
+	}
+	reg.Reset()
+	if series := reg.Snapshot()[0].Series; len(series) != 0 {
+		t.Errorf("want no series after Reset, but %+v", series)
+	}
+	// the family is kept after Reset
+	if v := cv.With("new").Value(); v != 0 {
+		t.Errorf("want 0 after Reset, but %v", v)
+	}
+}
+
+func TestRegistryHistogram(t *testing.T) {
+	reg := NewRegistry(0)
+	h := reg.Histogram("latency", "", []float64{1, 0.1}).With()
+	for _, v := range []float64{0.05, 0.1, 0.5, 2} {
+		h.Observe(v)
+	}
+	hs := reg.Snapshot()[0].Series[0].Histogram
+	wantCounts := []uint64{2, 1, 1}
+	for i, c := range wantCounts {
+		if hs.Counts[i] != c {
+			t.Errorf("bucket %d: want %d, but %d", i, c, hs.Counts[i])
+		}
+	}
+	if hs.Count != 4 || hs.Sum != 2.65 {
+		t.Errorf("unexpected snapshot %+v", hs)
+	}
+}
+
+func TestRegistryWriteText(t *testing.T) {
+	reg := NewRegistry(0)
+	reg.Counter("http_requests_total", "Number of requests.\nSecond line.", "method", "path").With("GET", `/a"b\`).Add(3)
+	reg.Gauge("temperature", "").With().Set(-1.5)
+	h := reg.Histogram("rpc_seconds", "RPC latency.", []float64{0.5, 1}, "rpc").With("get")
+	h.Observe(0.2)
+	h.Observe(3)
+	want := `# HELP http_requests_total Number of requests.\nSecond line.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",path="/a\"b\\"} 3
+# HELP rpc_seconds RPC latency.
+# TYPE rpc_seconds histogram
+rpc_seconds_bucket{rpc="get",le="0.5"} 1
+rpc_seconds_bucket{rpc="get",le="1"} 1
+rpc_seconds_bucket{rpc="get",le="+Inf"} 2
+rpc_seconds_sum{rpc="get"} 3.2
+rpc_seconds_count{rpc="get"} 2
+# TYPE temperature gauge
+temperature -1.5
+`
+	var sb strings.Builder
+	if err := reg.WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != want {
+		t.Errorf("want:\n%s\nbut:\n%s", want, sb.String())
+	}
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") || string(body) != want {
+		t.Errorf("unexpected response %q %q", ct, body)
+	}
+}
@@ -0,0 +1,220 @@
+package example
+
+import "slices"
+
+// Deque 是不可变的持久化双端队列：两端各有一个最多 vectorWidth 个元素的缓冲区，中间是一个 Vector。
+// 在两端 push 和 pop 只复制缓冲区，缓冲区满或空时才与中间的 Vector 交换一整个叶子，
+// 所以均摊每次操作只需要 O(log n / vectorWidth) 的树操作，对实际的 n 来说是 O(1) 的。
+// 下标访问、切片和拼接通过中间的 Vector 完成，是 O(log n) 的。
+//
+// 缓冲区在创建后不再修改，不同版本的 Deque 可以安全地共享它们。方法的名字与 Vector 相同，零值是空的 Deque。
+type Deque[E any] struct {
+	front []E
+	mid   Vector[E]
+	back  []E
+}
+
+// DequeOf 返回依次包含 elems 的 Deque，elems 被复制。
+func DequeOf[E any](elems ...E) Deque[E] {
+	return Deque[E]{mid: VectorOf(elems...)}
+}
+
+// Vector 返回包含相同元素的 Vector。
+func (d Deque[E]) Vector() Vector[E] {
+	return leafVector(d.front).Concat(d.mid).Concat(leafVector(d.back))
+}
+
+// leafVector 返回只有一个叶子的 Vector，elems 不被复制，长度不能超过 vectorWidth。
+func leafVector[E any](elems []E) Vector[E] {
+	if len(elems) == 0 {
+		return Vector[E]{}
+	}
+	return Vector[E]{root: &vectorNode[E]{elems: elems}}
+}
+
+func (d Deque[E]) Len() int {
+	return len(d.front) + d.mid.Len() + len(d.back)
+}
+
+// Get 返回第 i 个元素。
+func (d Deque[E]) Get(i int) E {
+	if err := checkIndex("Get", i, d.Len()); err != nil {
+		panic(err)
+	}
+	if i < len(d.front) {
+		return d.front[i]
+	}
+	if i -= len(d.front); i < d.mid.Len() {
+		return d.mid.Get(i)
+	}
+	return d.back[i-d.mid.Len()]
+}
+
+// At 与 Get 相同，但 i 超出范围时返回 false。
+func (d Deque[E]) At(i int) (E, bool) {
+	if i < 0 || i >= d.Len() {
+		var zeroValue E
+		return zeroValue, false
+	}
+	return d.Get(i), true
+}
+
+// Set 返回第 i 个元素被替换为 e 的 Deque。
+func (d Deque[E]) Set(i int, e E) Deque[E] {
+	if err := checkIndex("Set", i, d.Len()); err != nil {
+		panic(err)
+	}
+	switch {
+	case i < len(d.front):
+		d.front = slices.Clone(d.front)
+		d.front[i] = e
+	case i-len(d.front) < d.mid.Len():
+		d.mid = d.mid.Set(i-len(d.front), e)
+	default:
+		d.back = slices.Clone(d.back)
+		d.back[i-len(d.front)-d.mid.Len()] = e
+	}
+	return d
+}
+
+// PushBack 返回在末尾追加 e 后的 Deque。
+func (d Deque[E]) PushBack(e ...E) Deque[E] {
+	for _, x := range e {
+		if len(d.back) == vectorWidth {
+			d.mid = d.mid.Concat(leafVector(d.back))
+			d.back = nil
+		}
+		back := make([]E, len(d.back)+1)
+		copy(back, d.back)
+		back[len(d.back)] = x
+		d.back = back
+	}
+	return d
+}
+
+// PushFront 返回在开头插入 e 后的 Deque，e 的顺序保持不变。
+func (d Deque[E]) PushFront(e ...E) Deque[E] {
+	for i := len(e) - 1; i >= 0; i-- {
+		if len(d.front) == vectorWidth {
+			d.mid = leafVector(d.front).Concat(d.mid)
+			d.front = nil
+		}
+		front := make([]E, len(d.front)+1)
+		front[0] = e[i]
+		copy(front[1:], d.front)
+		d.front = front
+	}
+	return d
+}
+
+// PopBack 返回去掉最后一个元素的 Deque 和这个元素，d 为空时 panic。
+func (d Deque[E]) PopBack() (Deque[E], E) {
+	if len(d.back) == 0 {
+		switch {
+		case d.mid.Len() > 0:
+			d.back = d.mid.lastLeaf()
+			d.mid = d.mid.slice(0, d.mid.Len()-len(d.back))
+		case len(d.front) > 0:
+			d.front, d.back = nil, d.front
+		default:
+			panic(emptyError("PopBack"))
+		}
+	}
+	e := d.back[len(d.back)-1]
+	d.back = d.back[: len(d.back)-1 : len(d.back)-1]
+	return d, e
+}
+
+// PopFront 返回去掉第一个元素的 Deque 和这个元素，d 为空时 panic。
+func (d Deque[E]) PopFront() (Deque[E], E) {
+	if len(d.front) == 0 {
+		switch {
+		case d.mid.Len() > 0:
+			d.front = d.mid.firstLeaf()
+			d.mid = d.mid.slice(len(d.front), d.mid.Len())
+		case len(d.back) > 0:
+			d.front, d.back = d.back, nil
+		default:
+			panic(emptyError("PopFront"))
+		}
+	}
+	e := d.front[0]
+	d.front = d.front[1:]
+	return d, e
+}
+
+// TryPopBack 与 PopBack 相同，但 d 为空时返回 false 而不是 panic。
+func (d Deque[E]) TryPopBack() (Deque[E], E, bool) {
+	if d.Len() == 0 {
+		var zeroValue E
+		return d, zeroValue, false
+	}
+	d, e := d.PopBack()
+	return d, e, true
+}
+
+// TryPopFront 与 PopFront 相同，但 d 为空时返回 false 而不是 panic。
+func (d Deque[E]) TryPopFront() (Deque[E], E, bool) {
+	if d.Len() == 0 {
+		var zeroValue E
+		return d, zeroValue, false
+	}
+	d, e := d.PopFront()
+	return d, e, true
+}
+
+// Back 返回最后一个元素，d 为空时返回 false。
+func (d Deque[E]) Back() (E, bool) {
+	return d.At(d.Len() - 1)
+}
+
+// Front 返回第一个元素，d 为空时返回 false。
+func (d Deque[E]) Front() (E, bool) {
+	return d.At(0)
+}
+
+// Slice 返回 [i, j) 中的元素组成的 Deque。
+func (d Deque[E]) Slice(i, j int) Deque[E] {
+	if err := checkRange("Slice", i, j, d.Len()); err != nil {
+		panic(err)
+	}
+	return Deque[E]{mid: d.Vector().slice(i, j)}
+}
+
+// Concat 返回 d 后接 o 的 Deque。
+func (d Deque[E]) Concat(o Deque[E]) Deque[E] {
+	return Deque[E]{front: d.front, mid: d.mid.Concat(leafVector(d.back)).Concat(o.Vector())}
+}
+
+// Range 按顺序对每个元素调用 f，f 返回 false 时停止。
+func (d Deque[E]) Range(f func(i int, e E) bool) {
+	for i, e := range d.front {
+		if !f(i, e) {
+			return
+		}
+	}
+	offset, ok := len(d.front), true
+	d.mid.Range(func(i int, e E) bool {
+		ok = f(offset+i, e)
+		return ok
+	})
+	if !ok {
+		return
+	}
+	offset += d.mid.Len()
+	for i, e := range d.back {
+		if !f(offset+i, e) {
+			return
+		}
+	}
+}
+
+// ToSlice 返回包含所有元素的新切片。
+func (d Deque[E]) ToSlice() []E {
+	ret := make([]E, 0, d.Len())
+	d.Range(func(_ int, e E) bool {
+		ret = append(ret, e)
+		return true
+	})
+	return ret
+}
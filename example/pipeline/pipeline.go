@@ -0,0 +1,361 @@
+// Package pipeline 是 examples7_concurrency.go 中生产者-消费者模式的泛型版本。
+// 每个 stage 是一个或多个 goroutine，stage 之间用有界的 channel 连接；任意 stage
+// 出错时整个 Pipeline 的 ctx 被取消，所有 stage 随之退出，Wait 返回第一个错误。
+//
+//	p := pipeline.New(ctx, 16)
+//	nums := pipeline.FromSlice(p, []int{1, 2, 3})
+//	squares := pipeline.Map(p, nums, func(_ context.Context, n int) (int, error) { return n * n, nil })
+//	pipeline.Sink(p, squares, func(_ context.Context, n int) error { fmt.Println(n); return nil })
+//	err := p.Wait()
+package pipeline
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example"
+)
+
+// Pipeline 管理一组 stage 的生命周期。最后一个 stage 通常是 Sink；如果调用者自己读取
+// 最后一个 channel，需要读完或取消 ctx，否则 Wait 不会返回。
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	buf    int
+	wg     sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+}
+
+// New 返回一个 Pipeline，其中各 stage 输出的 channel 的缓冲区大小为 buf。
+func New(ctx context.Context, buf int) *Pipeline {
+	if buf < 0 {
+		buf = 0
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Pipeline{ctx: ctx, cancel: cancel, buf: buf}
+}
+
+// Context 返回 Pipeline 的 ctx，它在某个 stage 出错、父 ctx 结束或 Wait 返回后被取消。
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Go 在新的 goroutine 中运行 f，f 返回的错误会取消整个 Pipeline。
+// 可以用它编写自定义的 stage。
+func (p *Pipeline) Go(f func(ctx context.Context) error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := f(p.ctx); err != nil {
+			p.fail(err)
+		}
+	}()
+}
+
+func (p *Pipeline) fail(err error) {
+	p.errOnce.Do(func() {
+		p.err = err
+		p.cancel(err)
+	})
+}
+
+// Wait 等待所有 stage 结束，返回第一个出错的 stage 的错误；没有 stage 出错但父 ctx
+// 被取消时返回父 ctx 的错误。
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.errOnce.Do(func() {
+		p.err = context.Cause(p.ctx)
+	})
+	p.cancel(nil)
+	return p.err
+}
+
+// Send 将 v 发送到 out，ctx 结束时返回 false。
+func Send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Source 运行 gen，gen 通过 emit 输出数据，emit 返回 false 表示 Pipeline 已被取消，
+// gen 应尽快返回。
+func Source[T any](p *Pipeline, gen func(ctx context.Context, emit func(T) bool) error) <-chan T {
+	out := make(chan T, p.buf)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		return gen(ctx, func(v T) bool { return Send(ctx, out, v) })
+	})
+	return out
+}
+
+// FromSlice 依次输出 s 中的元素。
+func FromSlice[T any](p *Pipeline, s []T) <-chan T {
+	s = example.Clone(s)
+	return Source(p, func(_ context.Context, emit func(T) bool) error {
+		for _, v := range s {
+			if !emit(v) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// FromSeq 依次输出 seq 中的元素，Pipeline 被取消时停止遍历 seq。
+func FromSeq[T any](p *Pipeline, seq iter.Seq[T]) <-chan T {
+	return Source(p, func(_ context.Context, emit func(T) bool) error {
+		for v := range seq {
+			if !emit(v) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// Map 依次对 in 中的每个元素调用 f 并输出结果。
+func Map[T, U any](p *Pipeline, in <-chan T, f func(context.Context, T) (U, error)) <-chan U {
+	return ParallelMap(p, in, 1, true, f)
+}
+
+// ParallelMap 用 workers 个 goroutine 并发地调用 f。ordered 为 true 时结果的顺序与
+// 输入相同，此时最多有 workers+buf 个结果在等待前面的结果。
+func ParallelMap[T, U any](p *Pipeline, in <-chan T, workers int, ordered bool, f func(context.Context, T) (U, error)) <-chan U {
+	workers = max(workers, 1)
+	out := make(chan U, p.buf)
+	if !ordered {
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for range workers {
+			p.Go(func(ctx context.Context) error {
+				defer wg.Done()
+				return forEach(ctx, in, func(v T) error {
+					u, err := f(ctx, v)
+					if err != nil {
+						return err
+					}
+					Send(ctx, out, u)
+					return nil
+				})
+			})
+		}
+		p.Go(func(context.Context) error {
+			wg.Wait()
+			close(out)
+			return nil
+		})
+		return out
+	}
+
+	// 每个元素对应一个容量为 1 的结果 channel，pending 按输入顺序保存这些 channel，
+	// 它的容量限制了乱序完成的结果个数。
+	type job struct {
+		v      T
+		result chan U
+	}
+	jobs := make(chan job)
+	pending := make(chan chan U, workers+p.buf)
+	p.Go(func(ctx context.Context) error {
+		defer close(jobs)
+		defer close(pending)
+		return forEach(ctx, in, func(v T) error {
+			j := job{v, make(chan U, 1)}
+			if Send(ctx, pending, j.result) {
+				Send(ctx, jobs, j)
+			}
+			return nil
+		})
+	})
+	for range workers {
+		p.Go(func(ctx context.Context) error {
+			for j := range jobs {
+				u, err := f(ctx, j.v)
+				if err != nil {
+					return err
+				}
+				j.result <- u
+			}
+			return nil
+		})
+	}
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for result := range pending {
+			select {
+			case u := <-result:
+				if !Send(ctx, out, u) {
+					return nil
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// forEach 对 in 中的每个元素调用f，直到 in 被关闭、ctx 结束或 f 返回错误。
+func forEach[T any](ctx context.Context, in <-chan T, f func(T) error) error {
+	for {
+		select {
+		case v, ok := <-in:
+			if !ok {
+				return nil
+			}
+			if err := f(v); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Filter 只输出使 keep 返回 true 的元素。
+func Filter[T any](p *Pipeline, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T, p.buf)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		return forEach(ctx, in, func(v T) error {
+			if keep(v) {
+				Send(ctx, out, v)
+			}
+			return nil
+		})
+	})
+	return out
+}
+
+// FanOut 将 in 中的元素分发到 n 个 channel，每个元素只会出现在其中一个 channel 中，
+// 空闲的下游先拿到元素。
+func FanOut[T any](p *Pipeline, in <-chan T, n int) []<-chan T {
+	outs := make([]<-chan T, max(n, 1))
+	for i := range outs {
+		out := make(chan T, p.buf)
+		p.Go(func(ctx context.Context) error {
+			defer close(out)
+			return forEach(ctx, in, func(v T) error {
+				Send(ctx, out, v)
+				return nil
+			})
+		})
+		outs[i] = out
+	}
+	return outs
+}
+
+// FanIn 将多个 channel 合并为一个，所有输入都被关闭后输出被关闭。
+func FanIn[T any](p *Pipeline, ins ...<-chan T) <-chan T {
+	out := make(chan T, p.buf)
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		p.Go(func(ctx context.Context) error {
+			defer wg.Done()
+			return forEach(ctx, in, func(v T) error {
+				Send(ctx, out, v)
+				return nil
+			})
+		})
+	}
+	p.Go(func(context.Context) error {
+		wg.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// Batch 将元素按 size 个一组输出。maxWait 大于 0 时，一组中的第一个元素等待超过
+// maxWait 后即使不满 size 个也会被输出。in 被关闭时输出剩余的元素。
+func Batch[T any](p *Pipeline, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	size = max(size, 1)
+	out := make(chan []T, p.buf)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		batch := make([]T, 0, size)
+		var timeout <-chan time.Time
+		flush := func() bool {
+			timeout = nil
+			if len(batch) == 0 {
+				return true
+			}
+			b := example.Clone(batch)
+			batch = batch[:0]
+			return Send(ctx, out, b)
+		}
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return nil
+				}
+				if len(batch) == 0 && maxWait > 0 {
+					timeout = time.After(maxWait)
+				}
+				if batch = append(batch, v); len(batch) == size && !flush() {
+					return nil
+				}
+			case <-timeout:
+				if !flush() {
+					return nil
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// Tee 将 in 中的每个元素复制到 n 个 channel，最慢的下游决定整体的速度。
+func Tee[T any](p *Pipeline, in <-chan T, n int) []<-chan T {
+	chs := make([]chan T, max(n, 1))
+	outs := make([]<-chan T, len(chs))
+	for i := range chs {
+		chs[i] = make(chan T, p.buf)
+		outs[i] = chs[i]
+	}
+	p.Go(func(ctx context.Context) error {
+		defer func() {
+			for _, ch := range chs {
+				close(ch)
+			}
+		}()
+		return forEach(ctx, in, func(v T) error {
+			for _, ch := range chs {
+				if !Send(ctx, ch, v) {
+					break
+				}
+			}
+			return nil
+		})
+	})
+	return outs
+}
+
+// Sink 对 in 中的每个元素调用 f，f 返回错误时取消 Pipeline。
+func Sink[T any](p *Pipeline, in <-chan T, f func(context.Context, T) error) {
+	p.Go(func(ctx context.Context) error {
+		return forEach(ctx, in, func(v T) error { return f(ctx, v) })
+	})
+}
+
+// Collect 读取 in 中的所有元素，在 Wait 返回后可以安全地读取结果。
+func Collect[T any](p *Pipeline, in <-chan T) *[]T {
+	var ret []T
+	Sink(p, in, func(_ context.Context, v T) error {
+		ret = append(ret, v)
+		return nil
+	})
+	return &ret
+}
@@ -0,0 +1,243 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// checkLeak 在测试结束时检查 goroutine 的数量是否恢复到测试开始时的水平。
+func checkLeak(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				buf := make([]byte, 1<<16)
+				t.Errorf("goroutine leak: %d before, %d after\n%s", before, runtime.NumGoroutine(), buf[:runtime.Stack(buf, true)])
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
+
+func ints(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
+
+func square(_ context.Context, n int) (int, error) { return n * n, nil }
+
+func TestMapFilter(t *testing.T) {
+	checkLeak(t)
+	p := New(context.Background(), 2)
+	evens := Filter(p, FromSlice(p, ints(10)), func(n int) bool { return n%2 == 0 })
+	got := Collect(p, Map(p, evens, square))
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 4, 16, 36, 64}; !slices.Equal(*got, want) {
+		t.Errorf("want %v, but %v", want, *got)
+	}
+}
+
+func TestFromSeq(t *testing.T) {
+	checkLeak(t)
+	p := New(context.Background(), 0)
+	// 无限的 seq，下游出错取消 Pipeline 后 FromSeq 应停止遍历
+	naturals := func(yield func(int) bool) {
+		for i := 0; yield(i); i++ {
+		}
+	}
+	errStop := errors.New("stop")
+	var got []int
+	Sink(p, FromSeq(p, naturals), func(_ context.Context, n int) error {
+		if n == 5 {
+			return errStop
+		}
+		got = append(got, n)
+		return nil
+	})
+	if err := p.Wait(); !errors.Is(err, errStop) {
+		t.Fatalf("want %v, but %v", errStop, err)
+	}
+	if want := ints(5); !slices.Equal(got, want) {
+		t.Errorf("want %v, but %v", want, got)
+	}
+}
+
+func TestParallelMap(t *testing.T) {
+	for _, ordered := range []bool{true, false} {
+		t.Run(fmt.Sprint("ordered=", ordered), func(t *testing.T) {
+			checkLeak(t)
+			var inFlight, maxInFlight atomic.Int32
+			p := New(context.Background(), 0)
+			out := ParallelMap(p, FromSlice(p, ints(50)), 4, ordered, func(ctx context.Context, n int) (int, error) {
+				cur := inFlight.Add(1)
+				for {
+					m := maxInFlight.Load()
+					if cur <= m || maxInFlight.CompareAndSwap(m, cur) {
+						break
+					}
+				}
+				// later elements finish earlier
+				time.Sleep(time.Duration(50-n) * 20 * time.Microsecond)
+				inFlight.Add(-1)
+				return square(ctx, n)
+			})
+			got := Collect(p, out)
+			if err := p.Wait(); err != nil {
+				t.Fatal(err)
+			}
+			want := make([]int, 50)
+			for i := range want {
+				want[i] = i * i
+			}
+			if !ordered {
+				slices.Sort(*got)
+			}
+			if !slices.Equal(*got, want) {
+				t.Errorf("want %v, but %v", want, *got)
+			}
+			if m := maxInFlight.Load(); m > 4 {
+				t.Errorf("want at most 4 workers, but %d", m)
+			}
+		})
+	}
+}
+
+func TestFanOutFanIn(t *testing.T) {
+	checkLeak(t)
+	p := New(context.Background(), 1)
+	outs := FanOut(p, FromSlice(p, ints(100)), 3)
+	for i := range outs {
+		outs[i] = Map(p, outs[i], square)
+	}
+	got := Collect(p, FanIn(p, outs...))
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(*got)
+	for i, v := range *got {
+		if v != i*i {
+			t.Fatalf("want %d at %d, but %d", i*i, i, v)
+		}
+	}
+	if len(*got) != 100 {
+		t.Errorf("want 100 elements, but %d", len(*got))
+	}
+}
+
+func TestBatch(t *testing.T) {
+	checkLeak(t)
+	p := New(context.Background(), 0)
+	got := Collect(p, Batch(p, FromSlice(p, ints(7)), 3, 0))
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int{{0, 1, 2}, {3, 4, 5}, {6}}
+	if !slices.EqualFunc(*got, want, slices.Equal) {
+		t.Errorf("want %v, but %v", want, *got)
+	}
+
+	// a partial batch is flushed after maxWait
+	p = New(context.Background(), 0)
+	src := Source(p, func(ctx context.Context, emit func(int) bool) error {
+		emit(1)
+		<-ctx.Done()
+		return nil
+	})
+	batches := Batch(p, src, 10, 10*time.Millisecond)
+	select {
+	case b := <-batches:
+		if !slices.Equal(b, []int{1}) {
+			t.Errorf("want [1], but %v", b)
+		}
+	case <-time.After(time.Second):
+		t.Error("partial batch not flushed")
+	}
+	p.Go(func(context.Context) error { return errors.New("stop") })
+	p.Wait()
+}
+
+func TestTee(t *testing.T) {
+	checkLeak(t)
+	p := New(context.Background(), 0)
+	outs := Tee(p, FromSlice(p, ints(5)), 2)
+	a, b := Collect(p, outs[0]), Collect(p, outs[1])
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(*a, ints(5)) || !slices.Equal(*b, ints(5)) {
+		t.Errorf("unexpected result %v %v", *a, *b)
+	}
+}
+
+func TestErrorCancels(t *testing.T) {
+	checkLeak(t)
+	errBoom := errors.New("boom")
+	for _, ordered := range []bool{true, false} {
+		p := New(context.Background(), 4)
+		// an endless source must be stopped by the error
+		src := Source(p, func(ctx context.Context, emit func(int) bool) error {
+			for i := 0; emit(i); i++ {
+			}
+			return nil
+		})
+		mapped := ParallelMap(p, src, 3, ordered, func(_ context.Context, n int) (int, error) {
+			if n == 2 {
+				return 0, errBoom
+			}
+			return n, nil
+		})
+		outs := Tee(p, mapped, 2)
+		Sink(p, outs[0], func(context.Context, int) error { return nil })
+		// outs[1] is never read, the error must still unblock Tee
+		_ = outs[1]
+		if err := p.Wait(); !errors.Is(err, errBoom) {
+			t.Errorf("ordered=%v: want %v, but %v", ordered, errBoom, err)
+		}
+	}
+}
+
+func TestSinkError(t *testing.T) {
+	checkLeak(t)
+	errStop := errors.New("stop")
+	p := New(context.Background(), 0)
+	n := 0
+	Sink(p, FromSlice(p, ints(100)), func(context.Context, int) error {
+		if n++; n == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if err := p.Wait(); !errors.Is(err, errStop) || n != 3 {
+		t.Errorf("want %v after 3 elements, but %v after %d", errStop, err, n)
+	}
+}
+
+func TestParentCancel(t *testing.T) {
+	checkLeak(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx, 0)
+	src := Source(p, func(ctx context.Context, emit func(int) bool) error {
+		for i := 0; emit(i); i++ {
+		}
+		return nil
+	})
+	Sink(p, Batch(p, src, 5, time.Millisecond), func(context.Context, []int) error { return nil })
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := p.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v, but %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,208 @@
+package example
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PollObserver 在 Request 的生命周期的各个阶段被 Poller、ChanPoller 和 ChanPoll 调用。
+// 实现需要是并发安全的，且不应阻塞。只关心部分事件时可以内嵌 NopObserver。
+type PollObserver interface {
+	// OnEnqueue 在 Request 成功进入队列后调用。
+	OnEnqueue(req *Request)
+	// OnDequeue 在 Request 被某个 worker 从队列中取出后调用。Poller 中的 Request
+	// 每次重试前都会被重新取出，因此会再次调用。
+	OnDequeue(req *Request)
+	// OnStart 在每次调用 action 之前调用。
+	OnStart(req *Request)
+	// OnFinish 在每次 action 返回后调用，d 是本次 action 的耗时。
+	OnFinish(req *Request, err error, d time.Duration)
+	// OnDrop 在 Request 重试耗尽、被放入死信队列时调用，err 是最后一次的错误。
+	OnDrop(req *Request, err error)
+}
+
+// NopObserver 是什么也不做的 PollObserver。
+type NopObserver struct{}
+
+func (NopObserver) OnEnqueue(*Request)                      {}
+func (NopObserver) OnDequeue(*Request)                      {}
+func (NopObserver) OnStart(*Request)                        {}
+func (NopObserver) OnFinish(*Request, error, time.Duration) {}
+func (NopObserver) OnDrop(*Request, error)                  {}
+
+// multiObserver 依次通知其中的每个 PollObserver。
+type multiObserver []PollObserver
+
+// combineObservers 将 observers 合并为一个 PollObserver，没有 observer 时返回 NopObserver。
+func combineObservers(observers []PollObserver) PollObserver {
+	switch len(observers) {
+	case 0:
+		return NopObserver{}
+	case 1:
+		return observers[0]
+	}
+	return multiObserver(Clone(observers))
+}
+
+func (mo multiObserver) OnEnqueue(req *Request) {
+	for _, o := range mo {
+		o.OnEnqueue(req)
+	}
+}
+
+func (mo multiObserver) OnDequeue(req *Request) {
+	for _, o := range mo {
+		o.OnDequeue(req)
+	}
+}
+
+func (mo multiObserver) OnStart(req *Request) {
+	for _, o := range mo {
+		o.OnStart(req)
+	}
+}
+
+func (mo multiObserver) OnFinish(req *Request, err error, d time.Duration) {
+	for _, o := range mo {
+		o.OnFinish(req, err, d)
+	}
+}
+
+func (mo multiObserver) OnDrop(req *Request, err error) {
+	for _, o := range mo {
+		o.OnDrop(req, err)
+	}
+}
+
+// DefaultLatencyBuckets 是 NewStatsObserver 默认使用的延迟直方图的桶上界。
+var DefaultLatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// LatencyHistogram 是并发安全的延迟直方图。
+// 第 i 个桶统计 (buckets[i-1], buckets[i]] 范围内的值，最后一个桶统计大于所有上界的值。
+type LatencyHistogram struct {
+	buckets []time.Duration
+	counts  []atomic.Uint64
+	sum     atomic.Int64
+}
+
+// NewLatencyHistogram 的 buckets 会被拷贝并排序。
+func NewLatencyHistogram(buckets ...time.Duration) *LatencyHistogram {
+	bs := Clone(buckets)
+	sort.Slice(bs, func(i, j int) bool { return bs[i] < bs[j] })
+	return &LatencyHistogram{buckets: bs, counts: make([]atomic.Uint64, len(bs)+1)}
+}
+
+func (h *LatencyHistogram) Observe(d time.Duration) {
+	i := sort.Search(len(h.buckets), func(i int) bool { return d <= h.buckets[i] })
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+// HistogramSnapshot 是 LatencyHistogram 某一时刻的拷贝。Counts 比 Buckets 多一个元素。
+type HistogramSnapshot struct {
+	Buckets []time.Duration
+	Counts  []uint64
+	Count   uint64
+	Sum     time.Duration
+}
+
+func (h *LatencyHistogram) Snapshot() HistogramSnapshot {
+	hs := HistogramSnapshot{
+		Buckets: Clone(h.buckets),
+		Counts:  make([]uint64, len(h.counts)),
+		Sum:     time.Duration(h.sum.Load()),
+	}
+	for i := range h.counts {
+		hs.Counts[i] = h.counts[i].Load()
+		hs.Count += hs.Counts[i]
+	}
+	return hs
+}
+
+// Mean 返回平均延迟，没有数据时返回 0。
+func (hs HistogramSnapshot) Mean() time.Duration {
+	if hs.Count == 0 {
+		return 0
+	}
+	return hs.Sum / time.Duration(hs.Count)
+}
+
+// StatsObserver 是统计各事件次数和 action 耗时的 PollObserver。
+// 零值可以直接使用，延迟直方图使用 DefaultLatencyBuckets。
+type StatsObserver struct {
+	enqueued atomic.Uint64
+	dequeued atomic.Uint64
+	started  atomic.Uint64
+	finished atomic.Uint64
+	failed   atomic.Uint64
+	dropped  atomic.Uint64
+
+	latencyOnce sync.Once
+	latency     *LatencyHistogram
+}
+
+var _ PollObserver = (*StatsObserver)(nil)
+
+// NewStatsObserver 使用 buckets 作为延迟直方图的桶上界，为空时使用 DefaultLatencyBuckets。
+func NewStatsObserver(buckets ...time.Duration) *StatsObserver {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	return &StatsObserver{latency: NewLatencyHistogram(buckets...)}
+}
+
+// histogram 返回延迟直方图，零值 StatsObserver 第一次调用时创建。
+func (so *StatsObserver) histogram() *LatencyHistogram {
+	so.latencyOnce.Do(func() {
+		if so.latency == nil {
+			so.latency = NewLatencyHistogram(DefaultLatencyBuckets...)
+		}
+	})
+	return so.latency
+}
+
+func (so *StatsObserver) OnEnqueue(*Request) { so.enqueued.Add(1) }
+func (so *StatsObserver) OnDequeue(*Request) { so.dequeued.Add(1) }
+func (so *StatsObserver) OnStart(*Request)   { so.started.Add(1) }
+
+func (so *StatsObserver) OnFinish(_ *Request, err error, d time.Duration) {
+	if err != nil {
+		so.failed.Add(1)
+	}
+	so.histogram().Observe(d)
+	so.finished.Add(1)
+}
+
+func (so *StatsObserver) OnDrop(*Request, error) { so.dropped.Add(1) }
+
+// PollStats 是 StatsObserver 某一时刻的统计信息。
+type PollStats struct {
+	Enqueued, Dequeued, Started, Finished, Failed, Dropped uint64
+	Latency                                                HistogramSnapshot
+}
+
+func (so *StatsObserver) Stats() PollStats {
+	return PollStats{
+		Enqueued: so.enqueued.Load(),
+		Dequeued: so.dequeued.Load(),
+		Started:  so.started.Load(),
+		Finished: so.finished.Load(),
+		Failed:   so.failed.Load(),
+		Dropped:  so.dropped.Load(),
+		Latency:  so.histogram().Snapshot(),
+	}
+}
+
+// Finished 返回 action 返回的总次数，包括失败的。
+func (so *StatsObserver) Finished() uint64 {
+	return so.finished.Load()
+}
@@ -0,0 +1,123 @@
+package example
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordObserver 按顺序记录收到的事件。
+type recordObserver struct {
+	NopObserver
+	mu     sync.Mutex
+	events []string
+}
+
+func (ro *recordObserver) record(e string) {
+	ro.mu.Lock()
+	ro.events = append(ro.events, e)
+	ro.mu.Unlock()
+}
+
+func (ro *recordObserver) OnEnqueue(*Request) { ro.record("enqueue") }
+func (ro *recordObserver) OnDequeue(*Request) { ro.record("dequeue") }
+func (ro *recordObserver) OnStart(*Request)   { ro.record("start") }
+func (ro *recordObserver) OnDrop(*Request, error) {
+	ro.record("drop")
+}
+
+func (ro *recordObserver) OnFinish(_ *Request, err error, _ time.Duration) {
+	if err != nil {
+		ro.record("fail")
+	} else {
+		ro.record("finish")
+	}
+}
+
+func (ro *recordObserver) Events() []string {
+	ro.mu.Lock()
+	defer ro.mu.Unlock()
+	return Clone(ro.events)
+}
+
+func TestPollerObserver(t *testing.T) {
+	ro, stats := new(recordObserver), NewStatsObserver()
+	plr := NewPoller(ro, stats)
+	plr.Retry = RetryPolicy{MaxAttempts: 2}
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	go plr.Poll(ctx, func(*Request) error { return errors.New("always") })
+	req := Request("1")
+	f, ok := plr.TrySubmit(&req)
+	if !ok {
+		t.Fatal("TrySubmit failed")
+	}
+	if err := f.Wait(context.Background()); err == nil {
+		t.Fatal("want error, but nil")
+	}
+	want := []string{"enqueue", "dequeue", "start", "fail", "dequeue", "start", "fail", "drop"}
+	if got := ro.Events(); !equalStrings(got, want) {
+		t.Errorf("want %v, but %v", want, got)
+	}
+	s := stats.Stats()
+	if s.Enqueued != 1 || s.Dequeued != 2 || s.Started != 2 || s.Finished != 2 || s.Failed != 2 || s.Dropped != 1 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+func TestChanPollerObserver(t *testing.T) {
+	ro := new(recordObserver)
+	cp := NewChanPoller(1, ro)
+	cp.Retry = RetryPolicy{MaxAttempts: 2}
+	fail := true
+	req := Request("1")
+	if _, err := cp.Submit(context.Background(), &req); err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+	cp.Poll(context.Background(), func(*Request) error {
+		if fail {
+			fail = false
+			return errors.New("once")
+		}
+		return nil
+	})
+	want := []string{"enqueue", "dequeue", "start", "fail", "start", "finish"}
+	if got := ro.Events(); !equalStrings(got, want) {
+		t.Errorf("want %v, but %v", want, got)
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	h := NewLatencyHistogram(10*time.Millisecond, time.Millisecond)
+	for _, d := range []time.Duration{0, time.Millisecond, 2 * time.Millisecond, 10 * time.Millisecond, time.Second} {
+		h.Observe(d)
+	}
+	hs := h.Snapshot()
+	wantCounts := []uint64{2, 2, 1}
+	for i, c := range wantCounts {
+		if hs.Counts[i] != c {
+			t.Errorf("bucket %d: want %d, but %d", i, c, hs.Counts[i])
+		}
+	}
+	if hs.Count != 5 || hs.Sum != 1013*time.Millisecond || hs.Mean() != hs.Sum/5 {
+		t.Errorf("unexpected snapshot: %+v", hs)
+	}
+}
+
+func TestStatsObserverZeroValue(t *testing.T) {
+	var so StatsObserver
+	cp := NewChanPoller(1, &so)
+	req := Request("a")
+	if _, err := cp.Submit(context.Background(), &req); err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+	cp.Poll(context.Background(), func(*Request) error { return errors.New("fail") })
+	s := so.Stats()
+	if s.Finished != 1 || s.Failed != 1 || s.Latency.Count != 1 || len(s.Latency.Buckets) != len(DefaultLatencyBuckets) {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
@@ -0,0 +1,545 @@
+package example
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const BUFFER_SIZE = 256
+
+type Request string
+
+// Action 处理一个 Request，返回非 nil 的 error 表示本次处理失败，需要重试。
+type Action func(*Request) error
+
+// RetryPolicy 描述处理失败的 Request 的重试方式。
+// MaxAttempts 是包括第一次在内的最大尝试次数，小于 1 时视为 1，即不重试。
+// Backoff 根据已失败的次数（从 1 开始）返回下一次尝试前需要等待的时间，为 nil 时不等待。
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     func(attempt int) time.Duration
+}
+
+func (rp RetryPolicy) maxAttempts() int {
+	return max(rp.MaxAttempts, 1)
+}
+
+func (rp RetryPolicy) backoff(attempt int) time.Duration {
+	if rp.Backoff == nil {
+		return 0
+	}
+	return rp.Backoff(attempt)
+}
+
+// ExponentialBackoff 返回从 base 开始每次翻倍、最多为 limit 的退避函数。
+func ExponentialBackoff(base, limit time.Duration) func(int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < limit; i++ {
+			d <<= 1
+		}
+		return min(d, limit)
+	}
+}
+
+// DeadLetter 记录一个重试耗尽后仍然失败的 Request。ctx 结束时还在队列中的 Request 也会
+// 成为 DeadLetter，它的 Attempts 为 0。
+type DeadLetter struct {
+	Req      *Request
+	Attempts int
+	Err      error
+}
+
+func (dl DeadLetter) String() string {
+	return fmt.Sprintf("request %q failed after %d attempts: %v", string(*dl.Req), dl.Attempts, dl.Err)
+}
+
+// DeadLetterQueue 是死信队列，并发使用是安全的，零值即可使用。
+type DeadLetterQueue struct {
+	mu sync.Mutex
+	s  []DeadLetter
+}
+
+func (q *DeadLetterQueue) push(dl DeadLetter) {
+	q.mu.Lock()
+	q.s = append(q.s, dl)
+	q.mu.Unlock()
+}
+
+// Len 返回队列中死信的个数。
+func (q *DeadLetterQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.s)
+}
+
+// Items 返回队列中所有死信的拷贝，不会清空队列。
+func (q *DeadLetterQueue) Items() []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return Clone(q.s)
+}
+
+// Drain 取出并清空队列中的所有死信。
+func (q *DeadLetterQueue) Drain() []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s := q.s
+	q.s = nil
+	return s
+}
+
+// Future 表示一个已提交的 Request 的最终处理结果。
+type Future struct {
+	done chan struct{}
+	err  error
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+func (f *Future) resolve(err error) {
+	if f == nil {
+		return
+	}
+	f.err = err
+	close(f.done)
+}
+
+// Done 返回的 channel 在 Request 处理成功或进入死信队列后被关闭。
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Err 返回最终结果，nil 表示处理成功。Done 关闭前调用会返回 nil。
+func (f *Future) Err() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
+
+// Wait 阻塞到 Request 处理完毕或 ctx 结束。
+func (f *Future) Wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Poller 是固定容量的请求队列，多个 goroutine 可以同时对其调用 Poll。
+// Retry、DeadLetters 和 Store 需要在第一次调用 Poll 之前设置好。
+// Store 为 nil 时 Request 只保存在内存中，进程崩溃会丢失尚未处理的 Request。
+// 零值 Poller 可以直接使用，需要 PollObserver 时请使用 NewPoller。
+type Poller struct {
+	Retry       RetryPolicy
+	DeadLetters DeadLetterQueue
+	Store       QueueStore
+
+	observer PollObserver
+
+	// state 是每个位置的 slot* 状态。data、attempts、future 和 storeID 只由占有这个位置的
+	// goroutine 读写：空的位置属于持有 initLock 的 tryAdd，slotPolling 的位置属于选中它的 Poll，
+	// 修改 state 之前的写入对之后读到新 state 的 goroutine 可见。
+	state      [BUFFER_SIZE]atomic.Int32
+	createTime [BUFFER_SIZE]atomic.Int64
+	notBefore  [BUFFER_SIZE]atomic.Int64
+	data       [BUFFER_SIZE]*Request
+	attempts   [BUFFER_SIZE]int
+	future     [BUFFER_SIZE]*Future
+	storeID    [BUFFER_SIZE]uint64
+	size       atomic.Int64
+	closed     atomic.Bool
+	lock       sync.Mutex
+	initLock   [BUFFER_SIZE]sync.Mutex
+
+	// 空闲的 Poll 阻塞在 wake 上，有 Request 可以处理时其中一个被唤醒
+	wakeOnce sync.Once
+	wake     chan struct{}
+}
+
+// Poller 中每个位置的状态。
+const (
+	slotEmpty int32 = iota
+	slotReady
+	slotPolling
+)
+
+// NewPoller 创建一个会通知 observers 的 Poller。
+func NewPoller(observers ...PollObserver) *Poller {
+	return &Poller{observer: combineObservers(observers)}
+}
+
+// wakeCh 返回 wake，第一次调用时创建它，这样零值 Poller 也可以使用。
+func (plr *Poller) wakeCh() chan struct{} {
+	plr.wakeOnce.Do(func() {
+		plr.wake = make(chan struct{}, 1)
+	})
+	return plr.wake
+}
+
+// signal 唤醒一个空闲的 Poll，已经有未被取走的唤醒时什么也不做。
+func (plr *Poller) signal() {
+	select {
+	case plr.wakeCh() <- struct{}{}:
+	default:
+	}
+}
+
+func (plr *Poller) obs() PollObserver {
+	if plr.observer == nil {
+		return NopObserver{}
+	}
+	return plr.observer
+}
+
+// Len 返回 Poller 中尚未处理完毕的 Request 个数，包括正在处理和等待重试的。
+func (plr *Poller) Len() int {
+	return int(plr.size.Load())
+}
+
+// Close 使之后的 TryAddRequest、TrySubmit 和 Recover 都失败，已有的 Request 仍会被 Poll 处理。
+func (plr *Poller) Close() {
+	plr.closed.Store(true)
+}
+
+// TryAddRequest 在 Poller 已满、已 Close 或写入 Store 失败时返回 false。
+func (plr *Poller) TryAddRequest(req *Request) (success bool) {
+	return plr.tryAdd(req, nil, 0)
+}
+
+// TrySubmit 与 TryAddRequest 相同，但成功时会额外返回 req 的 Future。
+func (plr *Poller) TrySubmit(req *Request) (f *Future, success bool) {
+	f = newFuture()
+	if !plr.tryAdd(req, f, 0) {
+		return nil, false
+	}
+	return f, true
+}
+
+// ErrPollerFull 表示 Recover 时 Poller 放不下 Store 中所有未 Ack 的 Request。
+var ErrPollerFull = errors.New("poller is full")
+
+// Recover 将 Store 中未 Ack 且不在 Poller 中的 Request 重新放入 Poller，用于重启后
+// 重新投递崩溃前尚未处理完的 Request。Poller 放不下所有 Request 时返回已放入的个数
+// 和 ErrPollerFull，此时可以在部分 Request 处理完毕后再次调用 Recover。
+func (plr *Poller) Recover() (n int, err error) {
+	if plr.Store == nil {
+		return 0, nil
+	}
+	pending, err := plr.Store.Pending()
+	if err != nil {
+		return 0, err
+	}
+	loaded := make(map[uint64]bool)
+	for i := range BUFFER_SIZE {
+		plr.initLock[i].Lock()
+		if plr.state[i].Load() != slotEmpty {
+			loaded[plr.storeID[i]] = true
+		}
+		plr.initLock[i].Unlock()
+	}
+	for _, sr := range pending {
+		if loaded[sr.ID] {
+			continue
+		}
+		if !plr.tryAdd(sr.Req, nil, sr.ID) {
+			return n, ErrPollerFull
+		}
+		n++
+	}
+	return n, nil
+}
+
+// tryAdd 将 req 放入空闲的位置。storeID 为 0 时表示 req 是新的 Request，需要先写入 Store。
+func (plr *Poller) tryAdd(req *Request, f *Future, storeID uint64) (success bool) {
+	if plr.closed.Load() {
+		return false
+	}
+	for i := range BUFFER_SIZE {
+		if plr.initLock[i].TryLock() {
+			if plr.state[i].Load() != slotEmpty {
+				plr.initLock[i].Unlock()
+				continue
+			}
+			if plr.Store != nil && storeID == 0 {
+				id, err := plr.Store.Append(req)
+				if err != nil {
+					plr.initLock[i].Unlock()
+					return false
+				}
+				storeID = id
+			}
+			plr.storeID[i] = storeID
+			plr.data[i] = req
+			plr.createTime[i].Store(time.Now().UnixNano())
+			plr.notBefore[i].Store(0)
+			plr.attempts[i] = 0
+			plr.future[i] = f
+			plr.size.Add(1)
+			plr.state[i].Store(slotReady)
+			plr.initLock[i].Unlock()
+			plr.obs().OnEnqueue(req)
+			plr.signal()
+			return true
+		}
+	}
+	return false
+}
+
+// Poll 不断处理 Poller 中的 Request，直到 ctx 结束。没有可以处理的 Request 时阻塞，
+// 直到有新的 Request 加入、等待重试的 Request 到期或 ctx 结束。
+func (plr *Poller) Poll(ctx context.Context, action Action) {
+	obs := plr.obs()
+	// 退出前可能刚取走了一次唤醒，把它交给其他空闲的 Poll
+	defer plr.signal()
+	for ctx.Err() == nil {
+		handleIdx, wakeAt := plr.next()
+		if handleIdx == -1 {
+			plr.wait(ctx, wakeAt)
+			continue
+		}
+		req := plr.data[handleIdx]
+		obs.OnDequeue(req)
+		err := runAction(obs, req, action)
+		plr.attempts[handleIdx]++
+		if err != nil && plr.attempts[handleIdx] < plr.Retry.maxAttempts() {
+			// keep the slot and make it visible again after the backoff
+			plr.notBefore[handleIdx].Store(time.Now().Add(plr.Retry.backoff(plr.attempts[handleIdx])).UnixNano())
+			plr.state[handleIdx].Store(slotReady)
+			plr.signal()
+			continue
+		}
+		if err != nil {
+			plr.DeadLetters.push(DeadLetter{req, plr.attempts[handleIdx], err})
+			obs.OnDrop(req, err)
+		}
+		if plr.Store != nil {
+			// a failed Ack only leads to a redelivery after restart
+			plr.Store.Ack(plr.storeID[handleIdx])
+		}
+		plr.future[handleIdx].resolve(err)
+		plr.future[handleIdx] = nil
+		plr.size.Add(-1)
+		plr.state[handleIdx].Store(slotEmpty)
+	}
+}
+
+// next 找出可以处理的、最早入队的 Request，将它的位置标记为 slotPolling 并返回，没有时返回 -1。
+// wakeAt 是等待重试的 Request 中最早的到期时间，没有时为 0。
+func (plr *Poller) next() (handleIdx int, wakeAt int64) {
+	// get the least recently-polled Resource
+	// and mark it as being polled
+	plr.lock.Lock()
+	defer plr.lock.Unlock()
+	now := time.Now().UnixNano()
+	handleIdx = -1
+	ready := 0
+	var createTime int64
+	for i := range BUFFER_SIZE {
+		if plr.state[i].Load() != slotReady {
+			continue
+		}
+		if t := plr.notBefore[i].Load(); t > now {
+			if wakeAt == 0 || t < wakeAt {
+				wakeAt = t
+			}
+			continue
+		}
+		ready++
+		if t := plr.createTime[i].Load(); handleIdx == -1 || t < createTime {
+			handleIdx, createTime = i, t
+		}
+	}
+	if handleIdx != -1 {
+		// 持有 lock 时其他 Poll 不会修改 slotReady 的位置，tryAdd 只修改空的位置
+		plr.state[handleIdx].Store(slotPolling)
+	}
+	if ready > 1 {
+		// 还有其他可以处理的 Request，唤醒下一个空闲的 Poll
+		plr.signal()
+	}
+	return handleIdx, wakeAt
+}
+
+// wait 阻塞到被 signal 唤醒、到达 wakeAt（为 0 时不限时）或 ctx 结束。
+func (plr *Poller) wait(ctx context.Context, wakeAt int64) {
+	var timeout <-chan time.Time
+	if wakeAt != 0 {
+		timer := time.NewTimer(time.Until(time.Unix(0, wakeAt)))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-plr.wakeCh():
+	case <-timeout:
+	case <-ctx.Done():
+	}
+}
+
+// runAction 调用 action 并通知 obs。
+func runAction(obs PollObserver, req *Request, action Action) error {
+	obs.OnStart(req)
+	start := time.Now()
+	err := action(req)
+	obs.OnFinish(req, err, time.Since(start))
+	return err
+}
+
+// ChanPoll 处理 in 中的 Request，直到 in 被关闭或 ctx 结束。失败的 Request 按 retry 在当前
+// goroutine 中重试，重试耗尽或等待重试时 ctx 结束的 Request 被放入 dead，dead 为 nil 时丢弃。
+// 需要 Future 时请使用 ChanPoller。
+func ChanPoll(ctx context.Context, in chan *Request, action Action, retry RetryPolicy, dead *DeadLetterQueue, observers ...PollObserver) {
+	obs := combineObservers(observers)
+	for {
+		select {
+		case req, ok := <-in:
+			if !ok {
+				return
+			}
+			obs.OnDequeue(req)
+			runWithRetry(ctx, obs, retry, dead, req, action)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runWithRetry 按 retry 处理 req，返回最后一次的错误。失败的 Request 被放入 dead（可以为 nil）并通知 obs。
+func runWithRetry(ctx context.Context, obs PollObserver, retry RetryPolicy, dead *DeadLetterQueue, req *Request, action Action) error {
+	var err error
+	attempts := 0
+	for attempts < retry.maxAttempts() {
+		if attempts > 0 {
+			timer := time.NewTimer(retry.backoff(attempts))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return deadLetter(obs, dead, req, attempts, err)
+			}
+		}
+		attempts++
+		if err = runAction(obs, req, action); err == nil {
+			return nil
+		}
+	}
+	return deadLetter(obs, dead, req, attempts, err)
+}
+
+// deadLetter 把 req 放入 dead（可以为 nil）并通知 obs，返回 err。
+func deadLetter(obs PollObserver, dead *DeadLetterQueue, req *Request, attempts int, err error) error {
+	if dead != nil {
+		dead.push(DeadLetter{req, attempts, err})
+	}
+	obs.OnDrop(req, err)
+	return err
+}
+
+// chanTask 是 ChanPoller 内部传递的 Request 及其 Future。
+type chanTask struct {
+	req *Request
+	f   *Future
+}
+
+// ChanPoller 是自带队列的 ChanPoll，在同样的重试和死信队列之外还提供 Submit、Close 和 Future。
+// Retry 和 DeadLetters 需要在第一次调用 Poll 之前设置好。
+type ChanPoller struct {
+	Retry       RetryPolicy
+	DeadLetters DeadLetterQueue
+
+	observer PollObserver
+	in       chan chanTask
+	mu       sync.RWMutex // 保证不会向已关闭的 in 发送
+	closed   bool
+}
+
+// ErrQueueClosed 表示向已 Close 的 ChanPoller 提交 Request。
+var ErrQueueClosed = errors.New("queue is closed")
+
+// NewChanPoller 创建缓冲区大小为 size、会通知 observers 的 ChanPoller。
+func NewChanPoller(size int, observers ...PollObserver) *ChanPoller {
+	return &ChanPoller{observer: combineObservers(observers), in: make(chan chanTask, size)}
+}
+
+// Submit 将 req 放入队列，队列已满时阻塞到有空位或 ctx 结束。
+func (cp *ChanPoller) Submit(ctx context.Context, req *Request) (*Future, error) {
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+	if cp.closed {
+		return nil, ErrQueueClosed
+	}
+	f := newFuture()
+	select {
+	case cp.in <- chanTask{req, f}:
+		cp.observer.OnEnqueue(req)
+		return f, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Len 返回队列中等待处理的 Request 个数，不包括正在处理的。
+func (cp *ChanPoller) Len() int {
+	return len(cp.in)
+}
+
+// Close 表示不会再有新的 Request，所有 Poll 处理完队列中剩余的 Request 后返回。
+// 重复调用 Close 没有任何效果。
+func (cp *ChanPoller) Close() {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if !cp.closed {
+		cp.closed = true
+		close(cp.in)
+	}
+}
+
+// Poll 不断从队列中取出 Request 并处理，直到队列被 Close 且为空，或 ctx 结束。
+// 处理失败的 Request 会在当前 goroutine 中等待退避时间后重试。ctx 结束时，队列中尚未处理的
+// Request 以 ctx.Err() 放入死信队列，它们的 Future 也以 ctx.Err() 结束。
+func (cp *ChanPoller) Poll(ctx context.Context, action Action) {
+	for {
+		select {
+		case t, ok := <-cp.in:
+			if !ok {
+				return
+			}
+			if err := ctx.Err(); err != nil {
+				// ctx 结束时两个 case 可能同时就绪
+				t.f.resolve(deadLetter(cp.observer, &cp.DeadLetters, t.req, 0, err))
+				cp.drain(err)
+				return
+			}
+			cp.observer.OnDequeue(t.req)
+			t.f.resolve(runWithRetry(ctx, cp.observer, cp.Retry, &cp.DeadLetters, t.req, action))
+		case <-ctx.Done():
+			cp.drain(ctx.Err())
+			return
+		}
+	}
+}
+
+// drain 以 err 结束队列中所有等待处理的 Request。
+func (cp *ChanPoller) drain(err error) {
+	for {
+		select {
+		case t, ok := <-cp.in:
+			if !ok {
+				return
+			}
+			t.f.resolve(deadLetter(cp.observer, &cp.DeadLetters, t.req, 0, err))
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,433 @@
+package example
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueueStore 是 Poller 的持久化后端。Poller 在 Request 入队时调用 Append，在
+// action 处理完毕（成功或进入死信队列）后调用 Ack，重启后通过 Pending 找回未
+// Ack 的 Request，从而实现至少一次（at-least-once）的处理语义。
+type QueueStore interface {
+	// Append 持久化 req 并返回其 ID，ID 单调递增。
+	Append(req *Request) (id uint64, err error)
+	// Ack 表示 id 对应的 Request 已处理完毕，之后不会再被 Pending 返回。
+	Ack(id uint64) error
+	// Pending 按 Append 的顺序返回所有未 Ack 的 Request。
+	Pending() ([]StoredRequest, error)
+	Close() error
+}
+
+// StoredRequest 是 QueueStore 中的一条 Request。
+type StoredRequest struct {
+	ID  uint64
+	Req *Request
+}
+
+// SyncPolicy 决定 SegmentStore 何时调用 fsync。
+type SyncPolicy int
+
+const (
+	// SyncAlways 在每条记录写入后 fsync，最安全也最慢。
+	SyncAlways SyncPolicy = iota
+	// SyncBatch 每写入 SyncEvery 条记录或距上次 fsync 超过 SyncInterval 时 fsync。
+	// 之后没有新的写入时，后台会在 SyncInterval 内 fsync 剩下的记录。
+	SyncBatch
+	// SyncNone 从不主动 fsync，交给操作系统决定何时落盘。
+	SyncNone
+)
+
+// SegmentStoreOptions 是 OpenSegmentStore 的参数，零值字段使用默认值。
+type SegmentStoreOptions struct {
+	SegmentSize  int64 // 单个 segment 文件的大小上限，默认 4 MiB
+	Sync         SyncPolicy
+	SyncEvery    int           // 默认 64
+	SyncInterval time.Duration // 默认 100ms
+}
+
+const (
+	defaultSegmentSize  = 4 << 20
+	defaultSyncEvery    = 64
+	defaultSyncInterval = 100 * time.Millisecond
+
+	segmentExt = ".seg"
+
+	recordAppend byte = 1
+	recordAck    byte = 2
+
+	// 类型（1 字节）、ID（8 字节）、payload 长度（4 字节）
+	recordHeaderSize = 1 + 8 + 4
+	recordCRCSize    = 4
+)
+
+var (
+	ErrStoreClosed   = errors.New("segment store: closed")
+	ErrUnknownID     = errors.New("segment store: unknown id")
+	ErrCorruptRecord = errors.New("segment store: corrupt record")
+)
+
+// segment 是一个 append-only 的 segment 文件，文件名是其第一个 ID。
+type segment struct {
+	path    string
+	firstID uint64
+	size    int64
+	pending int // 本 segment 中尚未 Ack 的 Append 记录数
+}
+
+// SegmentStore 是基于目录中 append-only segment 文件的 QueueStore。
+// Append 和 Ack 都作为记录追加到当前 segment 末尾，写满后创建新的 segment。
+// 某个 segment 及其之前的 segment 中的 Request 都被 Ack 后，该 segment 会被删除。
+// 并发使用是安全的。
+type SegmentStore struct {
+	dir  string
+	opts SegmentStoreOptions
+
+	mu        sync.Mutex
+	segments  []*segment // 按 firstID 升序，最后一个是当前写入的 segment
+	active    *os.File
+	w         *bufio.Writer
+	pending   map[uint64]StoredRequest
+	owner     map[uint64]*segment
+	nextID    uint64
+	unsynced  int
+	lastSync  time.Time
+	syncTimer *time.Timer // 有未 fsync 的记录时等待后台 fsync
+	syncErr   error       // 后台 fsync 的错误，由下一次写入或 Close 返回
+	closed    bool
+	headerBuf [recordHeaderSize]byte
+}
+
+var _ QueueStore = (*SegmentStore)(nil)
+
+// OpenSegmentStore 打开（不存在时创建）dir 中的 SegmentStore，并回放已有的
+// segment 以恢复未 Ack 的 Request。最后一个 segment 末尾不完整的记录（例如写入时
+// 进程崩溃）会被截断。
+func OpenSegmentStore(dir string, opts SegmentStoreOptions) (*SegmentStore, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = defaultSegmentSize
+	}
+	if opts.SyncEvery <= 0 {
+		opts.SyncEvery = defaultSyncEvery
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = defaultSyncInterval
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &SegmentStore{
+		dir:      dir,
+		opts:     opts,
+		pending:  make(map[uint64]StoredRequest),
+		owner:    make(map[uint64]*segment),
+		nextID:   1,
+		lastSync: time.Now(),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if len(s.segments) == 0 {
+		if err := s.rotate(); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	last := s.segments[len(s.segments)-1]
+	f, err := os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s.active, s.w = f, bufio.NewWriter(f)
+	return s, nil
+}
+
+func (s *SegmentStore) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		firstID, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		s.segments = append(s.segments, &segment{path: filepath.Join(s.dir, name), firstID: firstID})
+	}
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].firstID < s.segments[j].firstID
+	})
+	for i, seg := range s.segments {
+		isLast := i == len(s.segments)-1
+		if err := s.replay(seg, isLast); err != nil {
+			return fmt.Errorf("replay %s: %w", seg.path, err)
+		}
+	}
+	return nil
+}
+
+// replay 读取 seg 中的所有记录。若 truncate 为真，遇到不完整或校验失败的记录时截断
+// 文件，否则返回 ErrCorruptRecord。
+func (s *SegmentStore) replay(seg *segment, truncate bool) error {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var offset int64
+	for {
+		typ, id, payload, n, err := readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if !truncate {
+				return err
+			}
+			f.Close()
+			if err := os.Truncate(seg.path, offset); err != nil {
+				return err
+			}
+			break
+		}
+		offset += n
+		switch typ {
+		case recordAppend:
+			req := Request(payload)
+			s.pending[id] = StoredRequest{id, &req}
+			s.owner[id] = seg
+			seg.pending++
+			s.nextID = max(s.nextID, id+1)
+		case recordAck:
+			if owner, ok := s.owner[id]; ok {
+				owner.pending--
+				delete(s.owner, id)
+				delete(s.pending, id)
+			}
+		}
+	}
+	seg.size = offset
+	return nil
+}
+
+func readRecord(r io.Reader) (typ byte, id uint64, payload []byte, n int64, err error) {
+	var header [recordHeaderSize]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = ErrCorruptRecord
+		}
+		return
+	}
+	typ = header[0]
+	id = binary.BigEndian.Uint64(header[1:9])
+	payload = make([]byte, binary.BigEndian.Uint32(header[9:13]))
+	var sum [recordCRCSize]byte
+	if _, err = io.ReadFull(r, payload); err != nil {
+		err = ErrCorruptRecord
+		return
+	}
+	if _, err = io.ReadFull(r, sum[:]); err != nil {
+		err = ErrCorruptRecord
+		return
+	}
+	crc := crc32.NewIEEE()
+	crc.Write(header[:])
+	crc.Write(payload)
+	if crc.Sum32() != binary.BigEndian.Uint32(sum[:]) || (typ != recordAppend && typ != recordAck) {
+		err = ErrCorruptRecord
+		return
+	}
+	n = int64(recordHeaderSize + len(payload) + recordCRCSize)
+	return
+}
+
+func (s *SegmentStore) writeRecord(typ byte, id uint64, payload []byte) error {
+	s.headerBuf[0] = typ
+	binary.BigEndian.PutUint64(s.headerBuf[1:9], id)
+	binary.BigEndian.PutUint32(s.headerBuf[9:13], uint32(len(payload)))
+	crc := crc32.NewIEEE()
+	crc.Write(s.headerBuf[:])
+	crc.Write(payload)
+	var sum [recordCRCSize]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	s.w.Write(s.headerBuf[:])
+	s.w.Write(payload)
+	if _, err := s.w.Write(sum[:]); err != nil {
+		return err
+	}
+	s.segments[len(s.segments)-1].size += int64(recordHeaderSize + len(payload) + recordCRCSize)
+	return s.flush()
+}
+
+// flush 将缓冲区写入文件，并按照 SyncPolicy 决定是否 fsync。
+func (s *SegmentStore) flush() error {
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	s.unsynced++
+	if err := s.syncErr; err != nil {
+		s.syncErr = nil
+		return err
+	}
+	switch s.opts.Sync {
+	case SyncAlways:
+	case SyncBatch:
+		if wait := s.opts.SyncInterval - time.Since(s.lastSync); s.unsynced < s.opts.SyncEvery && wait > 0 {
+			if s.syncTimer == nil {
+				s.syncTimer = time.AfterFunc(wait, s.backgroundSync)
+			}
+			return nil
+		}
+	default:
+		return nil
+	}
+	return s.sync()
+}
+
+// backgroundSync 在 SyncInterval 内没有触发 fsync 时由 syncTimer 调用。
+func (s *SegmentStore) backgroundSync() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncTimer = nil
+	if s.closed || s.unsynced == 0 {
+		return
+	}
+	if err := s.sync(); err != nil {
+		s.syncErr = err
+	}
+}
+
+func (s *SegmentStore) sync() error {
+	if s.syncTimer != nil {
+		s.syncTimer.Stop()
+		s.syncTimer = nil
+	}
+	s.unsynced = 0
+	s.lastSync = time.Now()
+	return s.active.Sync()
+}
+
+// rotate 关闭当前 segment 并以 nextID 为文件名创建新的 segment。
+func (s *SegmentStore) rotate() error {
+	if s.active != nil {
+		if err := s.w.Flush(); err != nil {
+			return err
+		}
+		if err := s.sync(); err != nil {
+			return err
+		}
+		if err := s.active.Close(); err != nil {
+			return err
+		}
+	}
+	seg := &segment{
+		path:    filepath.Join(s.dir, fmt.Sprintf("%020d%s", s.nextID, segmentExt)),
+		firstID: s.nextID,
+	}
+	f, err := os.OpenFile(seg.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	s.segments = append(s.segments, seg)
+	s.active, s.w = f, bufio.NewWriter(f)
+	return nil
+}
+
+// compact 从最旧的 segment 开始删除已全部 Ack 的 segment，当前写入的 segment 不会被删除。
+func (s *SegmentStore) compact() error {
+	for len(s.segments) > 1 && s.segments[0].pending == 0 {
+		if err := os.Remove(s.segments[0].path); err != nil {
+			return err
+		}
+		s.segments = s.segments[1:]
+	}
+	return nil
+}
+
+func (s *SegmentStore) Append(req *Request) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, ErrStoreClosed
+	}
+	if s.segments[len(s.segments)-1].size >= s.opts.SegmentSize {
+		if err := s.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	id := s.nextID
+	if err := s.writeRecord(recordAppend, id, []byte(*req)); err != nil {
+		return 0, err
+	}
+	s.nextID++
+	seg := s.segments[len(s.segments)-1]
+	seg.pending++
+	s.pending[id] = StoredRequest{id, req}
+	s.owner[id] = seg
+	return id, nil
+}
+
+func (s *SegmentStore) Ack(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStoreClosed
+	}
+	seg, ok := s.owner[id]
+	if !ok {
+		return ErrUnknownID
+	}
+	if err := s.writeRecord(recordAck, id, nil); err != nil {
+		return err
+	}
+	seg.pending--
+	delete(s.owner, id)
+	delete(s.pending, id)
+	return s.compact()
+}
+
+func (s *SegmentStore) Pending() ([]StoredRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrStoreClosed
+	}
+	ret := make([]StoredRequest, 0, len(s.pending))
+	for _, sr := range s.pending {
+		ret = append(ret, sr)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].ID < ret[j].ID
+	})
+	return ret, nil
+}
+
+// Close 将缓冲区中的记录落盘后关闭当前 segment，无论 SyncPolicy 如何都会 fsync。
+func (s *SegmentStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	err := s.w.Flush()
+	if err == nil {
+		err = s.sync()
+	}
+	return errors.Join(s.syncErr, err, s.active.Close())
+}
@@ -0,0 +1,251 @@
+package example
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func pendingReqs(t *testing.T, s QueueStore) []string {
+	t.Helper()
+	pending, err := s.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := make([]string, len(pending))
+	for i, sr := range pending {
+		ret[i] = string(*sr.Req)
+	}
+	return ret
+}
+
+func equalStrings(s1, s2 []string) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+	for i := range s1 {
+		if s1[i] != s2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSegmentStoreReopen(t *testing.T) {
+	for _, policy := range []SyncPolicy{SyncAlways, SyncBatch, SyncNone} {
+		t.Run(strconv.Itoa(int(policy)), func(t *testing.T) {
+			dir := t.TempDir()
+			opts := SegmentStoreOptions{SegmentSize: 64, Sync: policy, SyncEvery: 3}
+			s, err := OpenSegmentStore(dir, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]uint64, 10)
+			for i := range ids {
+				req := Request("req" + strconv.Itoa(i))
+				if ids[i], err = s.Append(&req); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, i := range []int{0, 1, 2, 5, 9} {
+				if err := s.Ack(ids[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.Ack(ids[0]); err != ErrUnknownID {
+				t.Errorf("ack twice: want ErrUnknownID, but %v", err)
+			}
+			want := []string{"req3", "req4", "req6", "req7", "req8"}
+			if got := pendingReqs(t, s); !equalStrings(got, want) {
+				t.Errorf("before reopen: want %v, but %v", want, got)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Append(new(Request)); err != ErrStoreClosed {
+				t.Errorf("append after close: want ErrStoreClosed, but %v", err)
+			}
+
+			s, err = OpenSegmentStore(dir, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			if got := pendingReqs(t, s); !equalStrings(got, want) {
+				t.Errorf("after reopen: want %v, but %v", want, got)
+			}
+			req := Request("req10")
+			id, err := s.Append(&req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id <= ids[len(ids)-1] {
+				t.Errorf("id after reopen should be greater than %d, but %d", ids[len(ids)-1], id)
+			}
+		})
+	}
+}
+
+func TestSegmentStoreCompaction(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSegmentStore(dir, SegmentStoreOptions{SegmentSize: 32, Sync: SyncNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for i := range 20 {
+		req := Request(strconv.Itoa(i))
+		id, err := s.Append(&req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Ack(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	segs, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segs) != 1 {
+		t.Errorf("want only the active segment left, but %v", segs)
+	}
+}
+
+func TestSegmentStoreTornTail(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSegmentStore(dir, SegmentStoreOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []Request{"a", "b"} {
+		if _, err := s.Append(&r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// simulate a crash in the middle of writing the third record
+	segs, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	f, err := os.OpenFile(segs[0], os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{recordAppend, 0, 0, 0})
+	f.Close()
+
+	s, err = OpenSegmentStore(dir, SegmentStoreOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := pendingReqs(t, s); !equalStrings(got, []string{"a", "b"}) {
+		t.Errorf("want [a b], but %v", got)
+	}
+	c := Request("c")
+	if _, err := s.Append(&c); err != nil {
+		t.Fatal(err)
+	}
+	if got := pendingReqs(t, s); !equalStrings(got, []string{"a", "b", "c"}) {
+		t.Errorf("want [a b c], but %v", got)
+	}
+}
+
+func TestSegmentStoreBackgroundSync(t *testing.T) {
+	s, err := OpenSegmentStore(t.TempDir(), SegmentStoreOptions{Sync: SyncBatch, SyncEvery: 100, SyncInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// 一次写入的突发之后不再有写入，剩下的记录也要在 SyncInterval 后 fsync
+	for i := range 3 {
+		req := Request(strconv.Itoa(i))
+		if _, err := s.Append(&req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unsynced := func() int {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.unsynced
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for unsynced() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := unsynced(); n != 0 {
+		t.Errorf("want all records synced, but %d unsynced", n)
+	}
+}
+
+func TestPollerRedelivery(t *testing.T) {
+	const REQUEST_NUM = 100
+	dir := t.TempDir()
+	s, err := OpenSegmentStore(dir, SegmentStoreOptions{SegmentSize: 256, Sync: SyncBatch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first process accepts the requests but crashes before handling them
+	plr := &Poller{Store: s}
+	reqs := make([]Request, REQUEST_NUM)
+	for i := range reqs {
+		reqs[i] = Request(strconv.Itoa(i))
+	}
+	for i := range reqs {
+		if !plr.TryAddRequest(&reqs[i]) {
+			t.Fatal("TryAddRequest failed")
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenSegmentStore(dir, SegmentStoreOptions{SegmentSize: 256, Sync: SyncBatch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plr = &Poller{Store: s}
+	n, err := plr.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != REQUEST_NUM {
+		t.Fatalf("want %d recovered requests, but %d", REQUEST_NUM, n)
+	}
+	if n, _ := plr.Recover(); n != 0 {
+		t.Errorf("recover twice: want 0, but %d", n)
+	}
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	var wg sync.WaitGroup
+	wg.Add(REQUEST_NUM)
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	for range 4 {
+		go plr.Poll(ctx, func(r *Request) error {
+			mu.Lock()
+			seen[string(*r)]++
+			mu.Unlock()
+			wg.Done()
+			return nil
+		})
+	}
+	wg.Wait()
+	// the Ack of the last requests may still be in flight
+	deadline := time.Now().Add(5 * time.Second)
+	for len(pendingReqs(t, s)) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancelFunc()
+	if len(seen) != REQUEST_NUM {
+		t.Errorf("want %d distinct requests, but %d", REQUEST_NUM, len(seen))
+	}
+	if got := pendingReqs(t, s); len(got) != 0 {
+		t.Errorf("want no pending requests, but %v", got)
+	}
+	s.Close()
+}
@@ -2,6 +2,8 @@ package example
 
 import (
 	"context"
+	"errors"
+	"runtime"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -9,115 +11,37 @@ import (
 	"time"
 )
 
-const BUFFER_SIZE = 256
-
-type Request string
-
-type HookName string
-
-type Poller struct {
-	notNull    [BUFFER_SIZE]bool
-	data       [BUFFER_SIZE]*Request
-	polling    [BUFFER_SIZE]bool
-	createTime [BUFFER_SIZE]int64
-	lock       sync.Mutex
-	initLock   [BUFFER_SIZE]sync.Mutex
-}
-
-func (plr *Poller) TryAddRequest(req *Request) (success bool) {
-	for i := range BUFFER_SIZE {
-		if plr.initLock[i].TryLock() {
-			if plr.notNull[i] {
-				plr.initLock[i].Unlock()
-				continue
-			}
-			plr.data[i] = req
-			plr.polling[i] = false
-			plr.createTime[i] = time.Now().UnixNano()
-			plr.notNull[i] = true
-			plr.initLock[i].Unlock()
-			return true
-		}
-	}
-	return false
-}
-
-func (plr *Poller) Poll(ctx context.Context, action func(*Request)) {
-	var beforeVisit func()
-	hook := ctx.Value(HookName("beforeVisit"))
-	if hook != nil {
-		if f, isFunc := hook.(func()); isFunc {
-			beforeVisit = f
-		}
-	}
-	for {
-		// get the least recently-polled Resource
-		// and mark it as being polled
-		plr.lock.Lock()
-		select {
-		case <-ctx.Done():
-			plr.lock.Unlock()
-			return
-		default:
-		}
-		handleIdx := -1
-		for i := range BUFFER_SIZE {
-			if !plr.notNull[i] || plr.polling[i] {
-				continue
-			}
-			if handleIdx == -1 || plr.createTime[i] < plr.createTime[handleIdx] {
-				handleIdx = i
-			}
-		}
-		if handleIdx != -1 {
-			plr.polling[handleIdx] = true
-			beforeVisit()
-		}
-		plr.lock.Unlock()
-		if handleIdx == -1 {
-			continue
-		}
-		action(plr.data[handleIdx])
-		plr.initLock[handleIdx].Lock()
-		plr.notNull[handleIdx] = false
-		plr.initLock[handleIdx].Unlock()
-	}
-}
-
 func TestPoller1(t *testing.T) {
 	const GO_ROUTINE_NUM = 64
 	const REQUEST_NUM = 10000000
-	plr := new(Poller)
+	stats := NewStatsObserver()
+	plr := NewPoller(stats)
 	req := Request("1")
 	var sum atomic.Int64
-	action := func(r *Request) {
+	action := func(r *Request) error {
 		i, err := strconv.Atoi(string(*r))
 		if err != nil {
-			return
+			return err
 		}
 		sum.Add(int64(i))
+		return nil
 	}
 	addDone := make(chan struct{})
 	go func() {
 		for range REQUEST_NUM {
 			for !plr.TryAddRequest(&req) {
+				runtime.Gosched()
 			}
 		}
 		close(addDone)
 	}()
-	handleCtr := 0
 	ctx, cancelFunc := context.WithCancel(context.Background())
-	ctx = context.WithValue(ctx, HookName("beforeVisit"), func() {
-		handleCtr++
-	})
 	for range GO_ROUTINE_NUM {
 		go plr.Poll(ctx, action)
 	}
 	<-addDone
-	for {
-		if handleCtr == REQUEST_NUM {
-			break
-		}
+	for stats.Finished() != REQUEST_NUM {
+		time.Sleep(time.Millisecond)
 	}
 	cancelFunc()
 	if sum.Load() != REQUEST_NUM {
@@ -125,31 +49,18 @@ func TestPoller1(t *testing.T) {
 	}
 }
 
-func ChanPoll(ctx context.Context, in chan *Request, action func(*Request)) {
-	wgDone := func() {}
-	wgVal := ctx.Value(HookName("wg"))
-	if wgVal != nil {
-		if f, ok := wgVal.(func()); ok {
-			wgDone = f
-		}
-	}
-	for req := range in {
-		action(req)
-	}
-	wgDone()
-}
-
 func TestPoller2(t *testing.T) {
 	const GO_ROUTINE_NUM = 64
 	const REQUEST_NUM = 10000000
 	req := Request("1")
 	var sum atomic.Int64
-	action := func(r *Request) {
+	action := func(r *Request) error {
 		i, err := strconv.Atoi(string(*r))
 		if err != nil {
-			return
+			return err
 		}
 		sum.Add(int64(i))
+		return nil
 	}
 	in := make(chan *Request, BUFFER_SIZE)
 	go func() {
@@ -158,15 +69,262 @@ func TestPoller2(t *testing.T) {
 		}
 		close(in)
 	}()
+	stats := NewStatsObserver()
 	var wg sync.WaitGroup
 	wg.Add(GO_ROUTINE_NUM)
 	for range GO_ROUTINE_NUM {
-		go ChanPoll(
-			context.WithValue(context.Background(), HookName("wg"), wg.Done),
-			in, action)
+		go func() {
+			defer wg.Done()
+			ChanPoll(context.Background(), in, action, RetryPolicy{}, nil, stats)
+		}()
 	}
 	wg.Wait()
 	if sum.Load() != REQUEST_NUM {
 		t.Errorf("want: %d, but: %d", REQUEST_NUM, sum.Load())
 	}
+	if s := stats.Stats(); s.Dequeued != REQUEST_NUM || s.Started != REQUEST_NUM || s.Latency.Count != REQUEST_NUM {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+// flakyAction 对每个 Request 的前 failures 次调用返回错误，值为 "bad" 的 Request 总是失败。
+func flakyAction(failures int) (Action, *sync.Map) {
+	var calls sync.Map
+	return func(r *Request) error {
+		v, _ := calls.LoadOrStore(r, new(atomic.Int64))
+		n := v.(*atomic.Int64).Add(1)
+		if *r == "bad" || n <= int64(failures) {
+			return errors.New("flaky " + string(*r))
+		}
+		return nil
+	}, &calls
+}
+
+func TestPollerRetry(t *testing.T) {
+	plr := &Poller{Retry: RetryPolicy{MaxAttempts: 3, Backoff: ExponentialBackoff(time.Millisecond, 4*time.Millisecond)}}
+	action, calls := flakyAction(2)
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	for range 4 {
+		go plr.Poll(ctx, action)
+	}
+	good, bad := Request("good"), Request("bad")
+	goodFuture, ok := plr.TrySubmit(&good)
+	if !ok {
+		t.Fatal("TrySubmit failed")
+	}
+	badFuture, ok := plr.TrySubmit(&bad)
+	if !ok {
+		t.Fatal("TrySubmit failed")
+	}
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelWait()
+	if err := goodFuture.Wait(waitCtx); err != nil {
+		t.Errorf("good request: %v", err)
+	}
+	if err := badFuture.Wait(waitCtx); err == nil {
+		t.Error("bad request: want error, but nil")
+	}
+	for _, r := range []*Request{&good, &bad} {
+		if v, _ := calls.Load(r); v.(*atomic.Int64).Load() != 3 {
+			t.Errorf("%s: want 3 attempts, but %d", *r, v.(*atomic.Int64).Load())
+		}
+	}
+	dls := plr.DeadLetters.Drain()
+	if len(dls) != 1 || dls[0].Req != &bad || dls[0].Attempts != 3 {
+		t.Errorf("unexpected dead letters: %v", dls)
+	}
+	if plr.DeadLetters.Len() != 0 {
+		t.Error("DeadLetters is not empty after Drain")
+	}
+}
+
+// TestPollerConcurrentSubmit 让多个 goroutine 同时 TrySubmit 和 Poll，用 go test -race 运行时
+// 可以检查 Poller 各个位置的状态是否都被正确地加锁。
+func TestPollerConcurrentSubmit(t *testing.T) {
+	const PRODUCER_NUM = 8
+	const REQUEST_NUM = 500
+	plr := &Poller{Retry: RetryPolicy{MaxAttempts: 2}}
+	action, _ := flakyAction(1)
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	for range 4 {
+		go plr.Poll(ctx, action)
+	}
+	reqs := make([]Request, PRODUCER_NUM*REQUEST_NUM)
+	futures := make([]*Future, len(reqs))
+	var wg sync.WaitGroup
+	wg.Add(PRODUCER_NUM)
+	for p := range PRODUCER_NUM {
+		go func() {
+			defer wg.Done()
+			for i := p * REQUEST_NUM; i < (p+1)*REQUEST_NUM; i++ {
+				reqs[i] = Request(strconv.Itoa(i))
+				f, ok := plr.TrySubmit(&reqs[i])
+				for !ok {
+					runtime.Gosched()
+					f, ok = plr.TrySubmit(&reqs[i])
+				}
+				futures[i] = f
+			}
+		}()
+	}
+	wg.Wait()
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelWait()
+	for i, f := range futures {
+		if err := f.Wait(waitCtx); err != nil {
+			t.Fatalf("%s: %v", reqs[i], err)
+		}
+	}
+	// Future 在位置被释放之前就已经结束
+	for plr.Len() != 0 && waitCtx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	if plr.Len() != 0 || plr.DeadLetters.Len() != 0 {
+		t.Errorf("want an empty poller, but Len %d, dead letters %d", plr.Len(), plr.DeadLetters.Len())
+	}
+}
+
+func TestPollerIdleWake(t *testing.T) {
+	plr := &Poller{Retry: RetryPolicy{MaxAttempts: 2, Backoff: ExponentialBackoff(20*time.Millisecond, 20*time.Millisecond)}}
+	action, calls := flakyAction(1)
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(4)
+	for range 4 {
+		go func() {
+			defer wg.Done()
+			plr.Poll(ctx, action)
+		}()
+	}
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelWait()
+	// 所有 Poll 都已经阻塞，新的 Request 和到期的重试都要能唤醒它们
+	for i := range 3 {
+		time.Sleep(10 * time.Millisecond)
+		req := Request(strconv.Itoa(i))
+		f, ok := plr.TrySubmit(&req)
+		if !ok {
+			t.Fatal("TrySubmit failed")
+		}
+		if err := f.Wait(waitCtx); err != nil {
+			t.Fatalf("%s: %v", req, err)
+		}
+		if v, _ := calls.Load(&req); v.(*atomic.Int64).Load() != 2 {
+			t.Errorf("%s: want 2 attempts, but %d", req, v.(*atomic.Int64).Load())
+		}
+	}
+	cancelFunc()
+	wg.Wait()
+}
+
+func TestChanPollerRetry(t *testing.T) {
+	cp := NewChanPoller(BUFFER_SIZE)
+	cp.Retry = RetryPolicy{MaxAttempts: 2}
+	action, _ := flakyAction(1)
+	var wg sync.WaitGroup
+	wg.Add(4)
+	for range 4 {
+		go func() {
+			defer wg.Done()
+			cp.Poll(context.Background(), action)
+		}()
+	}
+	reqs := []Request{"a", "b", "bad", "c"}
+	futures := make([]*Future, len(reqs))
+	for i := range reqs {
+		f, err := cp.Submit(context.Background(), &reqs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		futures[i] = f
+	}
+	cp.Close()
+	wg.Wait()
+	for i, f := range futures {
+		select {
+		case <-f.Done():
+		default:
+			t.Fatalf("%s: future is not done after Poll returns", reqs[i])
+		}
+		if (reqs[i] == "bad") != (f.Err() != nil) {
+			t.Errorf("%s: unexpected result %v", reqs[i], f.Err())
+		}
+	}
+	dls := cp.DeadLetters.Items()
+	if len(dls) != 1 || *dls[0].Req != "bad" || dls[0].Attempts != 2 {
+		t.Errorf("unexpected dead letters: %v", dls)
+	}
+}
+
+func TestChanPollRetry(t *testing.T) {
+	action, calls := flakyAction(1)
+	in := make(chan *Request, 3)
+	reqs := []Request{"a", "bad", "b"}
+	for i := range reqs {
+		in <- &reqs[i]
+	}
+	close(in)
+	var dead DeadLetterQueue
+	ChanPoll(context.Background(), in, action, RetryPolicy{MaxAttempts: 3}, &dead)
+	for i := range reqs {
+		want := int64(2)
+		if reqs[i] == "bad" {
+			want = 3
+		}
+		if v, _ := calls.Load(&reqs[i]); v.(*atomic.Int64).Load() != want {
+			t.Errorf("%s: want %d attempts, but %d", reqs[i], want, v.(*atomic.Int64).Load())
+		}
+	}
+	dls := dead.Items()
+	if len(dls) != 1 || *dls[0].Req != "bad" || dls[0].Attempts != 3 {
+		t.Errorf("unexpected dead letters: %v", dls)
+	}
+}
+
+func TestChanPollerCancel(t *testing.T) {
+	cp := NewChanPoller(BUFFER_SIZE)
+	reqs := []Request{"a", "b", "c"}
+	futures := make([]*Future, len(reqs))
+	for i := range reqs {
+		f, err := cp.Submit(context.Background(), &reqs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		futures[i] = f
+	}
+	// ctx 已经结束，队列中的 Request 都不会被处理
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cp.Poll(ctx, func(*Request) error {
+		t.Error("action called after ctx is cancelled")
+		return nil
+	})
+	for i, f := range futures {
+		select {
+		case <-f.Done():
+		default:
+			t.Fatalf("%s: future is still pending after Poll returns", reqs[i])
+		}
+		if !errors.Is(f.Err(), context.Canceled) {
+			t.Errorf("%s: want context.Canceled, but %v", reqs[i], f.Err())
+		}
+	}
+	if dls := cp.DeadLetters.Items(); len(dls) != len(reqs) || dls[0].Attempts != 0 {
+		t.Errorf("unexpected dead letters: %v", dls)
+	}
+	if cp.Len() != 0 {
+		t.Errorf("want an empty queue, but %d", cp.Len())
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Millisecond, 5*time.Millisecond)
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 5 * time.Millisecond, 5 * time.Millisecond}
+	for i, w := range want {
+		if got := backoff(i + 1); got != w {
+			t.Errorf("attempt %d: want %v, but %v", i+1, w, got)
+		}
+	}
 }
@@ -0,0 +1,138 @@
+package pretty
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Diff 返回 a 和 b 的结构化差异，每行一处，形如
+//
+//	.Users[1].Name: "张三" != "李四"
+//	.Tags: len 2 != 3
+//	.Tags[2]: <missing> != "c"
+//	.Scores["math"]: 90 != <missing>
+//
+// 左边是 a 中的值，右边是 b 中的值。结构体的所有字段（包括未导出的）都参与比较，
+// 没有导出字段的结构体（例如 time.Time）按格式化后的字符串比较。a 和 b 没有差异时返回 ""。
+func Diff(a, b any) string {
+	d := differ{visited: map[[2]uintptr]bool{}}
+	d.diff("", reflect.ValueOf(a), reflect.ValueOf(b))
+	return strings.Join(d.lines, "\n")
+}
+
+type differ struct {
+	lines []string
+	// visited 记录已经比较过的指针对，避免循环引用导致无限递归
+	visited map[[2]uintptr]bool
+}
+
+func (d *differ) report(path, a, b string) {
+	if path == "" {
+		d.lines = append(d.lines, a+" != "+b)
+		return
+	}
+	d.lines = append(d.lines, path+": "+a+" != "+b)
+}
+
+func (d *differ) diff(path string, a, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			d.report(path, formatLeaf(a), formatLeaf(b))
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		d.report(path, fmt.Sprintf("%s (%s)", formatLeaf(a), a.Type()), fmt.Sprintf("%s (%s)", formatLeaf(b), b.Type()))
+		return
+	}
+	switch a.Kind() {
+	case reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.report(path, formatLeaf(a), formatLeaf(b))
+			}
+			return
+		}
+		key := [2]uintptr{a.Pointer(), b.Pointer()}
+		if key[0] == key[1] || d.visited[key] {
+			return
+		}
+		d.visited[key] = true
+		d.diff(path, a.Elem(), b.Elem())
+	case reflect.Interface:
+		d.diff(path, a.Elem(), b.Elem())
+	case reflect.Struct:
+		if isLeafType(a.Type()) {
+			d.diffLeaf(path, a, b)
+			return
+		}
+		for i := range a.NumField() {
+			d.diff(path+"."+a.Type().Field(i).Name, a.Field(i), b.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		if a.Kind() == reflect.Slice && a.IsNil() != b.IsNil() {
+			d.report(path, formatSummary(a), formatSummary(b))
+			return
+		}
+		if a.Len() != b.Len() {
+			d.report(path, "len "+strconv.Itoa(a.Len()), strconv.Itoa(b.Len()))
+		}
+		for i := range max(a.Len(), b.Len()) {
+			p := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= a.Len():
+				d.report(p, "<missing>", formatLeaf(b.Index(i)))
+			case i >= b.Len():
+				d.report(p, formatLeaf(a.Index(i)), "<missing>")
+			default:
+				d.diff(p, a.Index(i), b.Index(i))
+			}
+		}
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() {
+			d.report(path, formatSummary(a), formatSummary(b))
+			return
+		}
+		keys := a.MapKeys()
+		for _, k := range b.MapKeys() {
+			if !a.MapIndex(k).IsValid() {
+				keys = append(keys, k)
+			}
+		}
+		sortValues(keys)
+		for _, k := range keys {
+			p := path + "[" + formatLeaf(k) + "]"
+			av, bv := a.MapIndex(k), b.MapIndex(k)
+			switch {
+			case !av.IsValid():
+				d.report(p, "<missing>", formatLeaf(bv))
+			case !bv.IsValid():
+				d.report(p, formatLeaf(av), "<missing>")
+			default:
+				d.diff(p, av, bv)
+			}
+		}
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if a.Pointer() != b.Pointer() {
+			d.report(path, formatLeaf(a), formatLeaf(b))
+		}
+	default:
+		d.diffLeaf(path, a, b)
+	}
+}
+
+func (d *differ) diffLeaf(path string, a, b reflect.Value) {
+	if sa, sb := formatLeaf(a), formatLeaf(b); sa != sb {
+		d.report(path, sa, sb)
+	}
+}
+
+// formatSummary 格式化 nil 或非 nil 的切片和 map，非 nil 的只显示类型和长度。
+func formatSummary(v reflect.Value) string {
+	if v.IsNil() {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s (len %d)", v.Type(), v.Len())
+}
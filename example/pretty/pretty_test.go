@@ -0,0 +1,182 @@
+package pretty
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStringWidth(t *testing.T) {
+	testcases := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"张三", 4},
+		{"ｈｉ", 4},       // 全角字母
+		{"ｶﾅ", 2},       // 半角片假名
+		{"é", 1},        // e + 组合重音符
+		{"±½", 2},       // Ambiguous 按窄字符计算
+		{"한국어", 6},      // 谚文
+		{"a\tb\x00", 2}, // 控制字符不占列
+		{"Ü​ber", 4},    // 零宽空格
+		{"→←", 2},       // UTF-8 编码为 3 字节，但不是宽字符
+		{"😀", 2},
+	}
+	for _, tc := range testcases {
+		if got := StringWidth(tc.s); got != tc.want {
+			t.Errorf("StringWidth(%q): want %d, but %d", tc.s, tc.want, got)
+		}
+	}
+	if got := Truncate("中文字符串", 7); got != "中文字…" {
+		t.Errorf("Truncate: got %q", got)
+	}
+	if got := PadLeft("张", 4); got != "  张" {
+		t.Errorf("PadLeft: got %q", got)
+	}
+}
+
+type address struct {
+	City string
+}
+
+type Base struct {
+	ID int
+}
+
+type user struct {
+	Base
+	Name    string
+	Age     int
+	Tags    []string
+	Home    *address
+	Created time.Time
+	secret  string
+}
+
+func TestTable(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	users := []*user{
+		{Base{1}, "张三", 30, []string{"a"}, &address{"北京"}, created, "x"},
+		{Base{12}, "Bob\nSmith", 7, nil, nil, created, "y"},
+		nil,
+	}
+	table, err := TableOf(users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("want 7 lines, but\n%s", table)
+	}
+	for _, line := range lines {
+		// 每一行的显示宽度都相同，说明中文和转义后的换行都被正确对齐
+		if StringWidth(line) != StringWidth(lines[0]) {
+			t.Errorf("misaligned table:\n%s", table)
+			break
+		}
+	}
+	if !strings.HasPrefix(lines[3], "|     1 | 张三       |") || !strings.Contains(lines[4], `| Bob\nSmith |`) {
+		t.Errorf("unexpected rows:\n%s", table)
+	}
+	if strings.Contains(table.String(), "secret") || strings.Contains(lines[1], "Base") {
+		t.Errorf("unexported or embedded field shown:\n%s", table)
+	}
+
+	m, err := TableOf(map[string]int{"b": 2, "a": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "+-----+-------+\n| Key | Value |\n+-----+-------+\n| a   |    10 |\n| b   |     2 |\n+-----+-------+\n"; m.String() != want {
+		t.Errorf("map table: want\n%s\nbut\n%s", want, m)
+	}
+
+	rows, err := TableOf([]map[string]any{{"x": 1}, {"y": "二"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "+---+----+\n| x | y  |\n+---+----+\n| 1 |    |\n|   | 二 |\n+---+----+\n"; rows.String() != want {
+		t.Errorf("slice of maps: want\n%s\nbut\n%s", want, rows)
+	}
+	if _, err := TableOf(42); err == nil {
+		t.Error("want error for an int")
+	}
+	if got, want := Box("User.AfterCreate\n中文"), "+------------------+\n| User.AfterCreate |\n| 中文             |\n+------------------+\n"; got != want {
+		t.Errorf("Box: want\n%s\nbut\n%s", want, got)
+	}
+}
+
+func TestTree(t *testing.T) {
+	type node struct {
+		Name     string
+		Children []*node
+		Parent   *node
+		Attrs    map[string]int
+	}
+	root := &node{Name: "根", Attrs: map[string]int{"b": 2, "a": 1}}
+	root.Children = []*node{{Name: "子", Parent: root}}
+	want := `pretty.node
+├─ Name: "根"
+├─ Children: []*pretty.node (len 1)
+│  └─ [0]: pretty.node
+│     ├─ Name: "子"
+│     ├─ Children: <nil>
+│     ├─ Parent: <cycle>
+│     └─ Attrs: <nil>
+├─ Parent: <nil>
+└─ Attrs: map[string]int (len 2)
+   ├─ "a": 1
+   └─ "b": 2
+`
+	if got := Tree(root); got != want {
+		t.Errorf("want\n%s\nbut\n%s", want, got)
+	}
+	if got := Tree(3); got != "3\n" {
+		t.Errorf("Tree(3): got %q", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := user{Base{1}, "张三", 30, []string{"a", "b"}, &address{"北京"}, created, "x"}
+	b := user{Base{1}, "李四", 30, []string{"a", "c", "d"}, &address{"上海"}, created.Add(time.Hour), "y"}
+	want := `.Name: "张三" != "李四"
+.Tags: len 2 != 3
+.Tags[1]: "b" != "c"
+.Tags[2]: <missing> != "d"
+.Home.City: "北京" != "上海"
+.Created: 2024-01-02 03:04:05 +0000 UTC != 2024-01-02 04:04:05 +0000 UTC
+.secret: "x" != "y"`
+	if got := Diff(a, b); got != want {
+		t.Errorf("want\n%s\nbut\n%s", want, got)
+	}
+	if got := Diff(a, a); got != "" {
+		t.Errorf("want no difference, but\n%s", got)
+	}
+	testcases := []struct {
+		a, b any
+		want string
+	}{
+		{1, int64(1), "1 (int) != 1 (int64)"},
+		{map[string]int{"a": 1, "b": 2}, map[string]int{"b": 3, "c": 4}, `["a"]: 1 != <missing>` + "\n" + `["b"]: 2 != 3` + "\n" + `["c"]: <missing> != 4`},
+		{[]int(nil), []int{}, "<nil> != []int (len 0)"},
+		{nil, &address{}, "<nil> != &{}"},
+		{[]any{1, "x"}, []any{1, "x"}, ""},
+	}
+	for _, tc := range testcases {
+		if got := Diff(tc.a, tc.b); got != tc.want {
+			t.Errorf("Diff(%v, %v): want\n%s\nbut\n%s", tc.a, tc.b, tc.want, got)
+		}
+	}
+	// 循环引用不应导致无限递归
+	type ring struct {
+		Next *ring
+		V    int
+	}
+	r1, r2 := &ring{V: 1}, &ring{V: 2}
+	r1.Next, r2.Next = r1, r2
+	if got := Diff(r1, r2); got != ".V: 1 != 2" {
+		t.Errorf("cyclic Diff: got %q", got)
+	}
+}
@@ -0,0 +1,337 @@
+package pretty
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Align 是表格中一列的对齐方式。
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+)
+
+// Table 是按显示宽度对齐的文本表格：
+//
+//	+----+------+
+//	| ID | Name |
+//	+----+------+
+//	|  1 | 张三 |
+//	+----+------+
+//
+// 没有表头时不输出表头和它下面的分隔线。单元格中的换行和制表符被转义，保证每行只占一行。
+type Table struct {
+	header []string
+	rows   [][]string
+	align  []Align
+}
+
+// NewTable 返回以 header 为表头的空表格。
+func NewTable(header ...string) *Table {
+	t := &Table{}
+	for _, h := range header {
+		t.header = append(t.header, escapeCell(h))
+	}
+	return t
+}
+
+// SetAlign 设置第 col 列（从 0 开始）的对齐方式，默认左对齐。
+func (t *Table) SetAlign(col int, a Align) *Table {
+	for len(t.align) <= col {
+		t.align = append(t.align, AlignLeft)
+	}
+	t.align[col] = a
+	return t
+}
+
+// AddRow 追加一行，每个单元格用 fmt.Sprint 格式化，nil 显示为 <nil>。
+func (t *Table) AddRow(cells ...any) *Table {
+	row := make([]string, len(cells))
+	for i, c := range cells {
+		row[i] = escapeCell(fmt.Sprint(c))
+	}
+	t.rows = append(t.rows, row)
+	return t
+}
+
+// Len 返回表格的行数，不包括表头。
+func (t *Table) Len() int {
+	return len(t.rows)
+}
+
+var cellEscaper = strings.NewReplacer("\r\n", `\n`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+func escapeCell(s string) string {
+	return cellEscaper.Replace(s)
+}
+
+// widths 返回每一列的宽度，列数是表头和所有行中最多的那个。
+func (t *Table) widths() []int {
+	var w []int
+	for _, row := range append([][]string{t.header}, t.rows...) {
+		for i, c := range row {
+			if i == len(w) {
+				w = append(w, 0)
+			}
+			w[i] = max(w[i], StringWidth(c))
+		}
+	}
+	return w
+}
+
+// WriteTo 把表格写入 w，实现 io.WriterTo。
+func (t *Table) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, t.String())
+	return int64(n), err
+}
+
+func (t *Table) String() string {
+	widths := t.widths()
+	if len(widths) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	bar := func() {
+		for _, w := range widths {
+			sb.WriteString("+" + strings.Repeat("-", w+2))
+		}
+		sb.WriteString("+\n")
+	}
+	line := func(row []string) {
+		for i, w := range widths {
+			var c string
+			if i < len(row) {
+				c = row[i]
+			}
+			if i < len(t.align) && t.align[i] == AlignRight {
+				c = PadLeft(c, w)
+			} else {
+				c = PadRight(c, w)
+			}
+			sb.WriteString("| " + c + " ")
+		}
+		sb.WriteString("|\n")
+	}
+	bar()
+	if len(t.header) > 0 {
+		line(t.header)
+		bar()
+	}
+	for _, row := range t.rows {
+		line(row)
+	}
+	if len(t.rows) > 0 {
+		bar()
+	}
+	return sb.String()
+}
+
+// Box 返回用边框围起来的 s，s 中的每一行各占一行，按显示宽度对齐：
+//
+//	+------------------+
+//	| User.AfterCreate |
+//	+------------------+
+func Box(s string) string {
+	t := NewTable()
+	for _, line := range strings.Split(s, "\n") {
+		t.AddRow(line)
+	}
+	return t.String()
+}
+
+// PrintBox 把 Box(s) 输出到标准输出。
+func PrintBox(s string) {
+	fmt.Print(Box(s))
+}
+
+// TableOf 根据 v 的类型生成表格：
+//   - 结构体（或结构体指针）的切片：每个导出字段一列，嵌入结构体的字段被展开；
+//   - map 的切片：所有 map 的键的并集各占一列；
+//   - 其他切片和数组：下标和值两列；
+//   - map：键和值两列，按键排序；
+//   - 结构体：字段名和值两列。
+//
+// 数字列右对齐。v 是其他类型时返回错误。
+func TableOf(v any) (*Table, error) {
+	rv := indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		elem := rv.Type().Elem()
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		switch {
+		case elem.Kind() == reflect.Struct && !isLeafType(elem):
+			return structsTable(rv, elem), nil
+		case elem.Kind() == reflect.Map:
+			return mapsTable(rv), nil
+		}
+		t := NewTable("#", "Value").SetAlign(0, AlignRight)
+		for i := range rv.Len() {
+			t.AddRow(i, formatCell(rv.Index(i)))
+		}
+		return t.alignNumbers(1, elem), nil
+	case reflect.Map:
+		t := NewTable("Key", "Value")
+		for _, k := range sortedKeys(rv) {
+			t.AddRow(formatCell(k), formatCell(rv.MapIndex(k)))
+		}
+		return t.alignNumbers(0, rv.Type().Key()).alignNumbers(1, rv.Type().Elem()), nil
+	case reflect.Struct:
+		t := NewTable("Field", "Value")
+		for _, f := range exportedFields(rv.Type()) {
+			t.AddRow(f.Name, formatCell(rv.FieldByIndex(f.Index)))
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("pretty: cannot make a table of %T", v)
+}
+
+// PrintTable 把 TableOf(v) 输出到标准输出，v 不支持时输出 fmt.Sprint(v)。
+func PrintTable(v any) {
+	t, err := TableOf(v)
+	if err != nil {
+		fmt.Fprintln(os.Stdout, v)
+		return
+	}
+	t.WriteTo(os.Stdout)
+}
+
+func structsTable(rv reflect.Value, elem reflect.Type) *Table {
+	fields := exportedFields(elem)
+	header := make([]string, len(fields))
+	for i, f := range fields {
+		header[i] = f.Name
+	}
+	t := NewTable(header...)
+	for i := range rv.Len() {
+		e := indirect(rv.Index(i))
+		row := make([]any, len(fields))
+		for j, f := range fields {
+			if !e.IsValid() {
+				row[j] = "<nil>"
+			} else if fv, err := e.FieldByIndexErr(f.Index); err != nil {
+				// 嵌入的结构体指针为 nil
+				row[j] = "<nil>"
+			} else {
+				row[j] = formatCell(fv)
+			}
+		}
+		t.AddRow(row...)
+	}
+	for j, f := range fields {
+		t.alignNumbers(j, f.Type)
+	}
+	return t
+}
+
+func mapsTable(rv reflect.Value) *Table {
+	// 各行的键可能不同，先收集键的并集
+	var keys []reflect.Value
+	seen := map[string]bool{}
+	for i := range rv.Len() {
+		m := indirect(rv.Index(i))
+		for _, k := range sortedKeys(m) {
+			if s := formatCell(k); !seen[s] {
+				seen[s] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sortValues(keys)
+	header := make([]string, len(keys))
+	for i, k := range keys {
+		header[i] = formatCell(k)
+	}
+	t := NewTable(header...)
+	for i := range rv.Len() {
+		m := indirect(rv.Index(i))
+		row := make([]any, len(keys))
+		for j, k := range keys {
+			if m.IsValid() {
+				if v := m.MapIndex(k); v.IsValid() {
+					row[j] = formatCell(v)
+				}
+			}
+			if row[j] == nil {
+				row[j] = ""
+			}
+		}
+		t.AddRow(row...)
+	}
+	return t
+}
+
+// alignNumbers 在 typ 是数字类型时把第 col 列设为右对齐。
+func (t *Table) alignNumbers(col int, typ reflect.Type) *Table {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		t.SetAlign(col, AlignRight)
+	}
+	return t
+}
+
+// exportedFields 返回 t 的所有导出字段，嵌入结构体的字段被展开，嵌入字段本身不包括在内。
+func exportedFields(t reflect.Type) []reflect.StructField {
+	var ret []reflect.StructField
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() {
+			continue
+		}
+		if ft := derefType(f.Type); f.Anonymous && ft.Kind() == reflect.Struct && !isLeafType(ft) {
+			continue
+		}
+		ret = append(ret, f)
+	}
+	return ret
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// sortedKeys 返回按 compareValues 排序的 map 的键，m 无效时返回 nil。
+func sortedKeys(m reflect.Value) []reflect.Value {
+	if !m.IsValid() || m.Kind() != reflect.Map {
+		return nil
+	}
+	keys := m.MapKeys()
+	sortValues(keys)
+	return keys
+}
+
+func sortValues(vs []reflect.Value) {
+	slices.SortFunc(vs, compareValues)
+}
+
+// compareValues 比较同类的基本类型的值，其他情况比较它们格式化后的字符串。
+func compareValues(a, b reflect.Value) int {
+	if a.Kind() == b.Kind() {
+		switch a.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return cmp.Compare(a.Int(), b.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return cmp.Compare(a.Uint(), b.Uint())
+		case reflect.Float32, reflect.Float64:
+			return cmp.Compare(a.Float(), b.Float())
+		case reflect.String:
+			return cmp.Compare(a.String(), b.String())
+		}
+	}
+	return cmp.Compare(formatLeaf(a), formatLeaf(b))
+}
@@ -0,0 +1,172 @@
+package pretty
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// indirect 解开 v 外层的指针和接口，遇到 nil 时返回无效的 Value。
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// isLeafType 判断结构体类型 t 是否应该作为一个整体输出：没有导出字段的结构体
+// （例如 time.Time）展开后只有无意义的内部状态，直接用 fmt 格式化。
+func isLeafType(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	for i := range t.NumField() {
+		if t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
+
+// isContainer 判断 v 是否需要展开为子节点。
+func isContainer(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	case reflect.Struct:
+		return !isLeafType(v.Type())
+	}
+	return false
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+// formatLeaf 格式化一个不再展开的值，字符串带引号。
+func formatLeaf(v reflect.Value) string {
+	switch {
+	case isNil(v):
+		return "<nil>"
+	case v.Kind() == reflect.String:
+		return strconv.Quote(v.String())
+	case v.Kind() == reflect.Func || v.Kind() == reflect.Chan:
+		return fmt.Sprintf("%s(%#x)", v.Type(), v.Pointer())
+	}
+	return fmt.Sprint(v)
+}
+
+// formatCell 格式化表格中的一个单元格，字符串不带引号。
+func formatCell(v reflect.Value) string {
+	if v = indirect(v); !v.IsValid() {
+		return "<nil>"
+	}
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	return formatLeaf(v)
+}
+
+// Tree 返回 v 的树形表示，结构体、map、切片和数组展开为子节点，指针和接口被解开，
+// map 按键排序。循环引用显示为 <cycle>。
+//
+//	model.User
+//	├─ Name: "张三"
+//	├─ Tags: []string (len 2)
+//	│  ├─ [0]: "a"
+//	│  └─ [1]: "b"
+//	└─ Manager: <nil>
+func Tree(v any) string {
+	var sb strings.Builder
+	tw := treeWriter{sb: &sb, path: map[uintptr]bool{}}
+	tw.node(reflect.ValueOf(v), "", "", "")
+	return sb.String()
+}
+
+// PrintTree 把 Tree(v) 输出到标准输出。
+func PrintTree(v any) {
+	fmt.Print(Tree(v))
+}
+
+type treeWriter struct {
+	sb *strings.Builder
+	// path 记录从根到当前节点经过的指针，用于发现循环引用
+	path map[uintptr]bool
+}
+
+// node 输出 v 和它的子节点。label 是 v 在父节点中的名字，head 是 v 所在行的前缀，
+// indent 是子节点的行的前缀。
+func (tw treeWriter) node(v reflect.Value, label, head, indent string) {
+	tw.sb.WriteString(head)
+	if label != "" {
+		tw.sb.WriteString(label + ": ")
+	}
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		if v.Kind() == reflect.Pointer {
+			if tw.path[v.Pointer()] {
+				tw.sb.WriteString("<cycle>\n")
+				return
+			}
+			tw.path[v.Pointer()] = true
+			defer delete(tw.path, v.Pointer())
+		}
+		v = v.Elem()
+	}
+	if !isContainer(v) {
+		tw.sb.WriteString(formatLeaf(v) + "\n")
+		return
+	}
+
+	type child struct {
+		label string
+		v     reflect.Value
+	}
+	var children []child
+	switch v.Kind() {
+	case reflect.Struct:
+		tw.sb.WriteString(v.Type().String())
+		for _, f := range exportedFields(v.Type()) {
+			fv, err := v.FieldByIndexErr(f.Index)
+			if err != nil {
+				continue
+			}
+			children = append(children, child{f.Name, fv})
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			tw.sb.WriteString("<nil>\n")
+			return
+		}
+		fmt.Fprintf(tw.sb, "%s (len %d)", v.Type(), v.Len())
+		for _, k := range sortedKeys(v) {
+			children = append(children, child{formatLeaf(k), v.MapIndex(k)})
+		}
+	default:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			tw.sb.WriteString("<nil>\n")
+			return
+		}
+		fmt.Fprintf(tw.sb, "%s (len %d)", v.Type(), v.Len())
+		for i := range v.Len() {
+			children = append(children, child{"[" + strconv.Itoa(i) + "]", v.Index(i)})
+		}
+	}
+	tw.sb.WriteByte('\n')
+	for i, c := range children {
+		if i == len(children)-1 {
+			tw.node(c.v, c.label, indent+"└─ ", indent+"   ")
+		} else {
+			tw.node(c.v, c.label, indent+"├─ ", indent+"│  ")
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Package pretty 把切片、map 和结构体渲染为对齐的表格或树，并生成两个值的结构化差异，
+// 用于示例程序的输出和测试失败时的信息。
+//
+// 对齐按终端中的显示宽度计算：中日韩文字和全角字符占两列，组合字符和控制字符不占列，
+// 宽度来自 golang.org/x/text/width 中的 East Asian Width 表，而不是 UTF-8 编码的长度。
+package pretty
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// RuneWidth 返回 r 在等宽终端中占的列数：East Asian Wide 和 Fullwidth 的字符为 2，
+// 组合字符、格式字符和控制字符为 0，其余（包括 Ambiguous）为 1。
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || r >= 0x7f && r < 0xa0:
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// StringWidth 返回 s 在等宽终端中占的列数。
+func StringWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += RuneWidth(r)
+	}
+	return n
+}
+
+// PadRight 在 s 的右边填充空格，使它的宽度至少为 w。
+func PadRight(s string, w int) string {
+	if n := StringWidth(s); n < w {
+		return s + strings.Repeat(" ", w-n)
+	}
+	return s
+}
+
+// PadLeft 在 s 的左边填充空格，使它的宽度至少为 w。
+func PadLeft(s string, w int) string {
+	if n := StringWidth(s); n < w {
+		return strings.Repeat(" ", w-n) + s
+	}
+	return s
+}
+
+// Truncate 截断 s 使它的宽度不超过 w，被截断时以 "…" 结尾。
+func Truncate(s string, w int) string {
+	if StringWidth(s) <= w {
+		return s
+	}
+	if w <= 0 {
+		return ""
+	}
+	n := 0
+	for i, r := range s {
+		if n+RuneWidth(r) > w-1 {
+			return s[:i] + "…"
+		}
+		n += RuneWidth(r)
+	}
+	return s
+}
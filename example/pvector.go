@@ -0,0 +1,494 @@
+package example
+
+import "slices"
+
+// Vector 是不可变的持久化向量，实现为 RRB 树（relaxed radix balanced tree）：每个叶子最多存
+// vectorWidth 个元素，每个内部节点最多有 vectorWidth 个孩子，所有叶子的深度相同。
+// 修改操作只复制从根到被修改的叶子的路径，返回新的 Vector，旧的 Vector 不受影响并与新的共享其余节点。
+//
+// 与普通的 radix 树不同，拼接和切片后内部节点的孩子不一定是满的，因此每个内部节点保存孩子大小的前缀和，
+// 查找时先按 radix 猜测孩子的下标，再向后扫描；拼接时的重新平衡保证向后扫描的次数很少。
+// Get、Set、PushBack、PushFront、Slice、Concat 和 Insert 都是 O(log n) 的。
+//
+// 方法的名字与 slice_utils.go 中的函数相同，非法的下标同样会 panic，零值是空的 Vector。
+type Vector[E any] struct {
+	root *vectorNode[E]
+	// shift 是根节点的 shift，根节点的每个孩子最多包含 1<<shift 个元素，根节点是叶子时为 0
+	shift int
+}
+
+const (
+	vectorBits  = 5
+	vectorWidth = 1 << vectorBits
+	// vectorExtra 是拼接后每一层允许比最少的节点数多出的节点数
+	vectorExtra = 2
+)
+
+// vectorNode 是叶子（children 为 nil）或内部节点。节点创建后不再修改。
+type vectorNode[E any] struct {
+	children []*vectorNode[E]
+	// sizes[i] 是前 i+1 个孩子包含的元素总数
+	sizes []int
+	elems []E
+}
+
+func (n *vectorNode[E]) isLeaf() bool {
+	return n.children == nil
+}
+
+func (n *vectorNode[E]) size() int {
+	if n.isLeaf() {
+		return len(n.elems)
+	}
+	return n.sizes[len(n.sizes)-1]
+}
+
+// slots 返回叶子中元素的个数或内部节点中孩子的个数。
+func (n *vectorNode[E]) slots() int {
+	if n.isLeaf() {
+		return len(n.elems)
+	}
+	return len(n.children)
+}
+
+func newVectorBranch[E any](children []*vectorNode[E]) *vectorNode[E] {
+	sizes := make([]int, len(children))
+	total := 0
+	for i, c := range children {
+		total += c.size()
+		sizes[i] = total
+	}
+	return &vectorNode[E]{children: children, sizes: sizes}
+}
+
+// locate 返回内部节点 n 中第 i 个元素所在的孩子和元素在孩子中的下标，sh 是 n 的 shift。
+// 每个孩子最多包含 1<<sh 个元素，所以 i>>sh 不会超过真正的下标。
+func (n *vectorNode[E]) locate(sh, i int) (int, int) {
+	idx := i >> sh
+	for n.sizes[idx] <= i {
+		idx++
+	}
+	if idx > 0 {
+		i -= n.sizes[idx-1]
+	}
+	return idx, i
+}
+
+// VectorOf 返回依次包含 elems 的 Vector，elems 被复制。
+func VectorOf[E any](elems ...E) Vector[E] {
+	if len(elems) == 0 {
+		return Vector[E]{}
+	}
+	level := make([]*vectorNode[E], 0, (len(elems)+vectorWidth-1)/vectorWidth)
+	for i := 0; i < len(elems); i += vectorWidth {
+		level = append(level, &vectorNode[E]{elems: slices.Clone(elems[i:min(i+vectorWidth, len(elems))])})
+	}
+	shift := 0
+	for len(level) > 1 {
+		next := make([]*vectorNode[E], 0, (len(level)+vectorWidth-1)/vectorWidth)
+		for i := 0; i < len(level); i += vectorWidth {
+			next = append(next, newVectorBranch(slices.Clip(level[i:min(i+vectorWidth, len(level))])))
+		}
+		level, shift = next, shift+vectorBits
+	}
+	return Vector[E]{level[0], shift}
+}
+
+func (v Vector[E]) Len() int {
+	if v.root == nil {
+		return 0
+	}
+	return v.root.size()
+}
+
+// Get 返回第 i 个元素。
+func (v Vector[E]) Get(i int) E {
+	if err := checkIndex("Get", i, v.Len()); err != nil {
+		panic(err)
+	}
+	n := v.root
+	for sh := v.shift; sh > 0; sh -= vectorBits {
+		var idx int
+		idx, i = n.locate(sh, i)
+		n = n.children[idx]
+	}
+	return n.elems[i]
+}
+
+// At 与 Get 相同，但 i 超出范围时返回 false。
+func (v Vector[E]) At(i int) (E, bool) {
+	if i < 0 || i >= v.Len() {
+		var zeroValue E
+		return zeroValue, false
+	}
+	return v.Get(i), true
+}
+
+// Set 返回第 i 个元素被替换为 e 的 Vector。
+func (v Vector[E]) Set(i int, e E) Vector[E] {
+	if err := checkIndex("Set", i, v.Len()); err != nil {
+		panic(err)
+	}
+	return Vector[E]{setVectorNode(v.root, v.shift, i, e), v.shift}
+}
+
+func setVectorNode[E any](n *vectorNode[E], sh, i int, e E) *vectorNode[E] {
+	if n.isLeaf() {
+		elems := slices.Clone(n.elems)
+		elems[i] = e
+		return &vectorNode[E]{elems: elems}
+	}
+	idx, j := n.locate(sh, i)
+	children := slices.Clone(n.children)
+	children[idx] = setVectorNode(children[idx], sh-vectorBits, j, e)
+	// 大小没有变化，可以共享 sizes
+	return &vectorNode[E]{children: children, sizes: n.sizes}
+}
+
+// Range 按顺序对每个元素调用 f，f 返回 false 时停止。
+func (v Vector[E]) Range(f func(i int, e E) bool) {
+	if v.root == nil {
+		return
+	}
+	i := 0
+	var walk func(n *vectorNode[E]) bool
+	walk = func(n *vectorNode[E]) bool {
+		if n.isLeaf() {
+			for _, e := range n.elems {
+				if !f(i, e) {
+					return false
+				}
+				i++
+			}
+			return true
+		}
+		for _, c := range n.children {
+			if !walk(c) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(v.root)
+}
+
+// ToSlice 返回包含所有元素的新切片。
+func (v Vector[E]) ToSlice() []E {
+	ret := make([]E, 0, v.Len())
+	v.Range(func(_ int, e E) bool {
+		ret = append(ret, e)
+		return true
+	})
+	return ret
+}
+
+// collapse 去掉只有一个孩子的根节点。
+func (v Vector[E]) collapse() Vector[E] {
+	for v.root != nil && !v.root.isLeaf() && len(v.root.children) == 1 {
+		v.root, v.shift = v.root.children[0], v.shift-vectorBits
+	}
+	return v
+}
+
+// Slice 返回 [i, j) 中的元素组成的 Vector，与 v[i:j] 相同。
+func (v Vector[E]) Slice(i, j int) Vector[E] {
+	if err := checkRange("Slice", i, j, v.Len()); err != nil {
+		panic(err)
+	}
+	return v.slice(i, j)
+}
+
+func (v Vector[E]) slice(i, j int) Vector[E] {
+	if i == j {
+		return Vector[E]{}
+	}
+	root := takeLeft(v.root, v.shift, j)
+	root = dropLeft(root, v.shift, i)
+	return Vector[E]{root, v.shift}.collapse()
+}
+
+// takeLeft 返回 n 的前 k 个元素组成的节点，0 < k <= n.size()。
+func takeLeft[E any](n *vectorNode[E], sh, k int) *vectorNode[E] {
+	if k == n.size() {
+		return n
+	}
+	if n.isLeaf() {
+		return &vectorNode[E]{elems: n.elems[:k:k]}
+	}
+	idx, j := n.locate(sh, k-1)
+	children := slices.Clone(n.children[:idx+1])
+	children[idx] = takeLeft(children[idx], sh-vectorBits, j+1)
+	return newVectorBranch(children)
+}
+
+// dropLeft 返回 n 去掉前 k 个元素后的节点，0 <= k < n.size()。
+func dropLeft[E any](n *vectorNode[E], sh, k int) *vectorNode[E] {
+	if k == 0 {
+		return n
+	}
+	if n.isLeaf() {
+		return &vectorNode[E]{elems: n.elems[k:]}
+	}
+	idx, j := n.locate(sh, k)
+	children := slices.Clone(n.children[idx:])
+	children[0] = dropLeft(children[0], sh-vectorBits, j)
+	return newVectorBranch(children)
+}
+
+// Concat 返回 v 后接 o 的 Vector。
+func (v Vector[E]) Concat(o Vector[E]) Vector[E] {
+	if v.Len() == 0 {
+		return o
+	}
+	if o.Len() == 0 {
+		return v
+	}
+	root := concatVectorNodes(v.root, v.shift, o.root, o.shift)
+	return Vector[E]{root, max(v.shift, o.shift) + vectorBits}.collapse()
+}
+
+// concatVectorNodes 拼接 shift 分别为 lsh 和 rsh 的两棵子树，返回 shift 为 max(lsh, rsh)+vectorBits
+// 的节点，它有一个或两个孩子。只有 l 最右边和 r 最左边的路径上的节点需要重新平衡。
+func concatVectorNodes[E any](l *vectorNode[E], lsh int, r *vectorNode[E], rsh int) *vectorNode[E] {
+	switch {
+	case lsh > rsh:
+		c := concatVectorNodes(l.children[len(l.children)-1], lsh-vectorBits, r, rsh)
+		return rebalanceVector(l, c, nil)
+	case lsh < rsh:
+		c := concatVectorNodes(l, lsh, r.children[0], rsh-vectorBits)
+		return rebalanceVector(nil, c, r)
+	case l.isLeaf():
+		if len(l.elems)+len(r.elems) <= vectorWidth {
+			leaf := &vectorNode[E]{elems: slices.Concat(l.elems, r.elems)}
+			return newVectorBranch([]*vectorNode[E]{leaf})
+		}
+		return newVectorBranch([]*vectorNode[E]{l, r})
+	default:
+		c := concatVectorNodes(l.children[len(l.children)-1], lsh-vectorBits, r.children[0], rsh-vectorBits)
+		return rebalanceVector(l, c, r)
+	}
+}
+
+// rebalanceVector 将 l 除最后一个以外的孩子、c 的孩子和 r 除第一个以外的孩子重新分配到一个或两个节点中，
+// 返回以它们为孩子的节点。l 和 r 可以为 nil。
+func rebalanceVector[E any](l, c, r *vectorNode[E]) *vectorNode[E] {
+	var slots []*vectorNode[E]
+	if l != nil {
+		slots = append(slots, l.children[:len(l.children)-1]...)
+	}
+	slots = append(slots, c.children...)
+	if r != nil {
+		slots = append(slots, r.children[1:]...)
+	}
+	slots = executeConcatPlan(slots, concatPlan(slots))
+	if len(slots) <= vectorWidth {
+		return newVectorBranch([]*vectorNode[E]{newVectorBranch(slots)})
+	}
+	return newVectorBranch([]*vectorNode[E]{
+		newVectorBranch(slots[:vectorWidth:vectorWidth]),
+		newVectorBranch(slots[vectorWidth:]),
+	})
+}
+
+// concatPlan 计算重新分配后每个节点的大小：节点数超过最少的节点数加 vectorExtra 时，从左边
+// 找一个不够满的节点，把它的内容依次挤进后面的节点，节点数减一，直到节点数足够少。
+// 这就是 RRB 树论文中的搜索步数不变式，它保证 locate 向后扫描的次数有上限。
+func concatPlan[E any](slots []*vectorNode[E]) []int {
+	sizes := make([]int, len(slots))
+	total := 0
+	for i, s := range slots {
+		sizes[i] = s.slots()
+		total += sizes[i]
+	}
+	opt := (total + vectorWidth - 1) / vectorWidth
+	n, i := len(sizes), 0
+	for opt+vectorExtra < n {
+		for sizes[i] > vectorWidth-vectorExtra/2 {
+			i++
+		}
+		for remaining := sizes[i]; remaining > 0; i++ {
+			sz := min(remaining+sizes[i+1], vectorWidth)
+			sizes[i] = sz
+			remaining = remaining + sizes[i+1] - sz
+		}
+		// 第 i 个节点的内容已经全部分给了前面的节点
+		copy(sizes[i:n-1], sizes[i+1:n])
+		n--
+		i--
+	}
+	return sizes[:n]
+}
+
+// executeConcatPlan 按 plan 重新分配 slots 的内容，大小不变且没有被拆分的节点直接复用。
+func executeConcatPlan[E any](slots []*vectorNode[E], plan []int) []*vectorNode[E] {
+	if len(plan) == len(slots) {
+		return slots
+	}
+	ret := make([]*vectorNode[E], 0, len(plan))
+	si, off := 0, 0
+	for _, sz := range plan {
+		if off == 0 && slots[si].slots() == sz {
+			ret = append(ret, slots[si])
+			si++
+			continue
+		}
+		leaf := slots[si].isLeaf()
+		var elems []E
+		var children []*vectorNode[E]
+		for got := 0; got < sz; {
+			s := slots[si]
+			take := min(sz-got, s.slots()-off)
+			if leaf {
+				elems = append(elems, s.elems[off:off+take]...)
+			} else {
+				children = append(children, s.children[off:off+take]...)
+			}
+			got, off = got+take, off+take
+			if off == s.slots() {
+				si, off = si+1, 0
+			}
+		}
+		if leaf {
+			ret = append(ret, &vectorNode[E]{elems: elems})
+		} else {
+			ret = append(ret, newVectorBranch(children))
+		}
+	}
+	return ret
+}
+
+// PushBack 返回在末尾追加 e 后的 Vector。
+func (v Vector[E]) PushBack(e ...E) Vector[E] {
+	return v.Concat(VectorOf(e...))
+}
+
+// PushFront 返回在开头插入 e 后的 Vector。
+func (v Vector[E]) PushFront(e ...E) Vector[E] {
+	return VectorOf(e...).Concat(v)
+}
+
+// PopBack 返回去掉最后一个元素的 Vector 和这个元素，v 为空时 panic。
+func (v Vector[E]) PopBack() (Vector[E], E) {
+	n := v.Len()
+	if n == 0 {
+		panic(emptyError("PopBack"))
+	}
+	return v.slice(0, n-1), v.Get(n - 1)
+}
+
+// PopFront 返回去掉第一个元素的 Vector 和这个元素，v 为空时 panic。
+func (v Vector[E]) PopFront() (Vector[E], E) {
+	if v.Len() == 0 {
+		panic(emptyError("PopFront"))
+	}
+	return v.slice(1, v.Len()), v.Get(0)
+}
+
+// TryPopBack 与 PopBack 相同，但 v 为空时返回 false 而不是 panic。
+func (v Vector[E]) TryPopBack() (Vector[E], E, bool) {
+	if v.Len() == 0 {
+		var zeroValue E
+		return v, zeroValue, false
+	}
+	v, e := v.PopBack()
+	return v, e, true
+}
+
+// TryPopFront 与 PopFront 相同，但 v 为空时返回 false 而不是 panic。
+func (v Vector[E]) TryPopFront() (Vector[E], E, bool) {
+	if v.Len() == 0 {
+		var zeroValue E
+		return v, zeroValue, false
+	}
+	v, e := v.PopFront()
+	return v, e, true
+}
+
+// Insert 返回在位置 i 插入 e 后的 Vector。
+func (v Vector[E]) Insert(i int, e E) Vector[E] {
+	if err := checkPosition("Insert", i, v.Len()); err != nil {
+		panic(err)
+	}
+	return v.insert(i, VectorOf(e))
+}
+
+// InsertMany 返回在位置 i 插入 e 后的 Vector。
+func (v Vector[E]) InsertMany(i int, e ...E) Vector[E] {
+	if err := checkPosition("InsertMany", i, v.Len()); err != nil {
+		panic(err)
+	}
+	return v.insert(i, VectorOf(e...))
+}
+
+func (v Vector[E]) insert(i int, o Vector[E]) Vector[E] {
+	return v.slice(0, i).Concat(o).Concat(v.slice(i, v.Len()))
+}
+
+// Delete 返回删除第 i 个元素后的 Vector。
+func (v Vector[E]) Delete(i int) Vector[E] {
+	if err := checkIndex("Delete", i, v.Len()); err != nil {
+		panic(err)
+	}
+	return v.slice(0, i).Concat(v.slice(i+1, v.Len()))
+}
+
+// Cut 返回删除 [i, j) 中的元素后的 Vector。
+func (v Vector[E]) Cut(i, j int) Vector[E] {
+	if err := checkRange("Cut", i, j, v.Len()); err != nil {
+		panic(err)
+	}
+	return v.slice(0, i).Concat(v.slice(j, v.Len()))
+}
+
+// Expand 返回在位置 i 插入 n 个零值后的 Vector。
+func (v Vector[E]) Expand(i, n int) Vector[E] {
+	if err := checkExpand(i, n, v.Len()); err != nil {
+		panic(err)
+	}
+	return v.insert(i, VectorOf(make([]E, n)...))
+}
+
+// Filter 返回只包含满足 f 的元素的 Vector。
+func (v Vector[E]) Filter(f func(E) bool) Vector[E] {
+	return VectorOf(Filter(v.ToSlice(), f)...)
+}
+
+// Reverse 返回元素顺序相反的 Vector。
+func (v Vector[E]) Reverse() Vector[E] {
+	s := v.ToSlice()
+	Reverse(s)
+	return VectorOf(s...)
+}
+
+// Batch 将 v 分为若干个长度为 sz 的 Vector，最后一个可能更短。它们与 v 共享节点。
+func (v Vector[E]) Batch(sz int) []Vector[E] {
+	if err := checkSize("Batch", sz); err != nil {
+		panic(err)
+	}
+	n := v.Len()
+	if sz >= n {
+		return []Vector[E]{v}
+	}
+	batches := make([]Vector[E], 0, (n+sz-1)/sz)
+	for i := 0; i < n; i += sz {
+		batches = append(batches, v.slice(i, min(i+sz, n)))
+	}
+	return batches
+}
+
+// firstLeaf 和 lastLeaf 返回最左边和最右边的叶子中的元素，v 不能为空。
+func (v Vector[E]) firstLeaf() []E {
+	n := v.root
+	for !n.isLeaf() {
+		n = n.children[0]
+	}
+	return n.elems
+}
+
+func (v Vector[E]) lastLeaf() []E {
+	n := v.root
+	for !n.isLeaf() {
+		n = n.children[len(n.children)-1]
+	}
+	return n.elems
+}
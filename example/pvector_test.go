@@ -0,0 +1,300 @@
+package example
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// checkVector 检查 RRB 树的不变式：所有叶子深度相同且非空，节点不超过 vectorWidth，
+// sizes 与孩子的大小一致，每个孩子的大小不超过 1<<shift。
+func checkVector[E any](t *testing.T, v Vector[E]) {
+	t.Helper()
+	if v.root == nil {
+		if v.shift != 0 {
+			t.Fatalf("empty vector with shift %d", v.shift)
+		}
+		return
+	}
+	var check func(n *vectorNode[E], sh int) int
+	check = func(n *vectorNode[E], sh int) int {
+		if n.isLeaf() != (sh == 0) {
+			t.Fatalf("leaf at shift %d", sh)
+		}
+		if n.slots() == 0 || n.slots() > vectorWidth {
+			t.Fatalf("node with %d slots", n.slots())
+		}
+		if n.isLeaf() {
+			return len(n.elems)
+		}
+		total := 0
+		for i, c := range n.children {
+			size := check(c, sh-vectorBits)
+			if size > 1<<sh {
+				t.Fatalf("child of size %d under shift %d", size, sh)
+			}
+			if total += size; n.sizes[i] != total {
+				t.Fatalf("sizes[%d] = %d, want %d", i, n.sizes[i], total)
+			}
+		}
+		return total
+	}
+	check(v.root, v.shift)
+	if v.shift > 0 && len(v.root.children) == 1 {
+		t.Fatal("root with a single child")
+	}
+}
+
+func equalVector(t *testing.T, v Vector[int], want []int, desc string) {
+	t.Helper()
+	checkVector(t, v)
+	if got := v.ToSlice(); !slices.Equal(got, want) {
+		t.Fatalf("%s: want %v, but %v", desc, want, got)
+	}
+	if v.Len() != len(want) {
+		t.Fatalf("%s: want Len %d, but %d", desc, len(want), v.Len())
+	}
+	for i := range want {
+		if v.Get(i) != want[i] {
+			t.Fatalf("%s: Get(%d) = %d, want %d", desc, i, v.Get(i), want[i])
+		}
+	}
+}
+
+func seq(from, to int) []int {
+	ret := make([]int, 0, to-from)
+	for i := from; i < to; i++ {
+		ret = append(ret, i)
+	}
+	return ret
+}
+
+func TestVectorOf(t *testing.T) {
+	for _, n := range []int{0, 1, 31, 32, 33, 1024, 1025, 40000} {
+		v := VectorOf(seq(0, n)...)
+		equalVector(t, v, seq(0, n), fmt.Sprint("VectorOf ", n))
+		if n > 0 {
+			v2 := v.Set(n-1, -1)
+			if v.Get(n-1) != n-1 || v2.Get(n-1) != -1 {
+				t.Errorf("Set changed the old version")
+			}
+		}
+	}
+}
+
+func TestVectorConcatSlice(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	// 反复拼接和切片随机长度的向量，得到各种不规则的树
+	var v Vector[int]
+	var want []int
+	next := 0
+	for range 300 {
+		n := rnd.Intn(100)
+		if rnd.Intn(10) == 0 {
+			n = rnd.Intn(3000)
+		}
+		o := VectorOf(seq(next, next+n)...)
+		if rnd.Intn(2) == 0 {
+			v, want = v.Concat(o), append(want, seq(next, next+n)...)
+		} else {
+			v, want = o.Concat(v), append(seq(next, next+n), want...)
+		}
+		next += n
+		if len(want) > 5000 {
+			i := rnd.Intn(len(want))
+			j := i + rnd.Intn(len(want)-i+1)
+			v, want = v.Slice(i, j), slices.Clone(want[i:j])
+		}
+		equalVector(t, v, want, "concat and slice")
+	}
+}
+
+func TestVectorPersistence(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	type version struct {
+		v    Vector[int]
+		want []int
+	}
+	versions := []version{{}}
+	for step := range 3000 {
+		// 在随机的旧版本上操作，检查所有的版本都没有被修改
+		base := versions[rnd.Intn(len(versions))]
+		v, want := base.v, slices.Clone(base.want)
+		i := rnd.Intn(len(want) + 1)
+		var desc string
+		switch op := rnd.Intn(9); {
+		case op == 0 && len(want) > 0:
+			desc = "PopBack"
+			var e int
+			v, e = v.PopBack()
+			want, _ = PopBack(want)
+			if e != base.want[len(base.want)-1] {
+				t.Fatalf("PopBack: got %d", e)
+			}
+		case op == 1 && len(want) > 0:
+			desc = "PopFront"
+			v, _ = v.PopFront()
+			want, _ = PopFront(want)
+		case op == 2:
+			desc = "PushFront"
+			v, want = v.PushFront(step, -step), PushFront(want, step, -step)
+		case op == 3 && i < len(want):
+			desc = "Delete"
+			v, want = v.Delete(i), Delete(want, i)
+		case op == 4:
+			j := i + rnd.Intn(len(want)-i+1)
+			desc = fmt.Sprintf("Cut(%d, %d)", i, j)
+			v, want = v.Cut(i, j), Cut(want, i, j)
+		case op == 5:
+			desc = "Insert"
+			v, want = v.Insert(i, step), Insert(want, i, step)
+		case op == 6:
+			desc = "Expand"
+			v, want = v.Expand(i, 3), Expand(want, i, 3)
+		case op == 7 && i < len(want):
+			desc = "Set"
+			v, want[i] = v.Set(i, step), step
+		default:
+			desc = "PushBack"
+			many := seq(step, step+rnd.Intn(50))
+			v, want = v.PushBack(many...), append(want, many...)
+		}
+		equalVector(t, v, want, desc)
+		versions = append(versions, version{v, want})
+	}
+	for i, ver := range versions {
+		equalVector(t, ver.v, ver.want, fmt.Sprint("version ", i))
+	}
+}
+
+func TestVectorMisc(t *testing.T) {
+	v := VectorOf(seq(0, 100)...)
+	if got := v.Filter(func(x int) bool { return x%10 == 0 }).ToSlice(); !slices.Equal(got, []int{0, 10, 20, 30, 40, 50, 60, 70, 80, 90}) {
+		t.Errorf("Filter: %v", got)
+	}
+	if got := v.Reverse(); got.Get(0) != 99 || got.Get(99) != 0 {
+		t.Error("Reverse: unexpected result")
+	}
+	batches := v.Batch(30)
+	if len(batches) != 4 || batches[3].Len() != 10 || batches[1].Get(0) != 30 {
+		t.Errorf("Batch: unexpected result")
+	}
+	var empty Vector[int]
+	if _, _, ok := empty.TryPopBack(); ok {
+		t.Error("TryPopBack: want false on empty vector")
+	}
+	if _, ok := v.At(100); ok {
+		t.Error("At: want false for out of range index")
+	}
+	n := 0
+	v.Range(func(i, e int) bool {
+		n++
+		return i < 9
+	})
+	if n != 10 {
+		t.Errorf("Range: want to stop after 10 elements, but %d", n)
+	}
+	// 反复 PushBack 单个元素时叶子应该被填满
+	for i := 100; i < 10000; i++ {
+		v = v.PushBack(i)
+	}
+	equalVector(t, v, seq(0, 10000), "PushBack")
+	if v.shift != 2*vectorBits {
+		t.Errorf("want a tree of height 2 after PushBack, but shift %d", v.shift)
+	}
+}
+
+func TestDeque(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	type version struct {
+		d    Deque[int]
+		want []int
+	}
+	versions := []version{{}}
+	for step := range 5000 {
+		base := versions[rnd.Intn(len(versions))]
+		if rnd.Intn(4) > 0 {
+			// 多数时候在最新的版本上操作，让队列足够长
+			base = versions[len(versions)-1]
+		}
+		d, want := base.d, slices.Clone(base.want)
+		var desc string
+		switch op := rnd.Intn(10); {
+		case op < 3:
+			desc = "PushBack"
+			d, want = d.PushBack(step), append(want, step)
+		case op < 6:
+			desc = "PushFront"
+			d, want = d.PushFront(step, -step), PushFront(want, step, -step)
+		case op < 8:
+			desc = "PopBack"
+			var e int
+			var ok bool
+			if d, e, ok = d.TryPopBack(); ok != (len(want) > 0) || ok && e != want[len(want)-1] {
+				t.Fatalf("PopBack of %v: got %d %v", want, e, ok)
+			}
+			if ok {
+				want = want[:len(want)-1]
+			}
+		case op < 9:
+			desc = "PopFront"
+			var e int
+			var ok bool
+			if d, e, ok = d.TryPopFront(); ok != (len(want) > 0) || ok && e != want[0] {
+				t.Fatalf("PopFront of %v: got %d %v", want, e, ok)
+			}
+			if ok {
+				want = want[1:]
+			}
+		default:
+			i := rnd.Intn(len(want) + 1)
+			j := i + rnd.Intn(len(want)-i+1)
+			desc = fmt.Sprintf("Slice(%d, %d) and Concat", i, j)
+			d = d.Slice(i, j).Concat(d)
+			want = append(slices.Clone(want[i:j]), want...)
+		}
+		if got := d.ToSlice(); !slices.Equal(got, want) || d.Len() != len(want) {
+			t.Fatalf("%s: want %v, but %v", desc, want, got)
+		}
+		if len(want) > 0 {
+			i := rnd.Intn(len(want))
+			if d.Get(i) != want[i] {
+				t.Fatalf("%s: Get(%d) = %d, want %d", desc, i, d.Get(i), want[i])
+			}
+			d = d.Set(i, -1)
+			want[i] = -1
+		}
+		checkVector(t, d.mid)
+		versions = append(versions, version{d, want})
+	}
+	for i, ver := range versions {
+		if got := ver.d.ToSlice(); !slices.Equal(got, ver.want) {
+			t.Fatalf("version %d changed: want %v, but %v", i, ver.want, got)
+		}
+		if !slices.Equal(ver.d.Vector().ToSlice(), ver.want) {
+			t.Fatalf("version %d: Vector differs", i)
+		}
+	}
+}
+
+func BenchmarkDequePushPop(b *testing.B) {
+	var d Deque[int]
+	for i := range b.N {
+		d = d.PushBack(i).PushFront(i)
+		if i%3 == 0 {
+			d, _ = d.PopBack()
+			d, _ = d.PopFront()
+		}
+	}
+}
+
+func BenchmarkSlicePushFront(b *testing.B) {
+	var s []int
+	for i := range b.N {
+		s = PushFront(s, i)
+		if len(s) > 100000 {
+			s = s[:0]
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Package rng 提供可以指定种子的随机数工具。slice_utils.go 中的 Shuffle 使用全局的随机源，
+// 结果无法复现；这里的函数都接受一个 *rand.Rand，传入 New(seed) 得到的生成器时，相同的种子
+// 总是产生相同的结果。r 为 nil 时使用 math/rand/v2 的全局随机源。
+//
+// 测试中使用 rngtest.Rand 创建生成器，它会记录种子，并允许通过 -rng.seed 或 RNG_SEED 重放。
+package rng
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// EnvSeed 是指定种子的环境变量的名字。
+const EnvSeed = "RNG_SEED"
+
+// ErrInvalidWeights 表示权重为空、有负数或非有限值，或者全部为 0。
+var ErrInvalidWeights = errors.New("rng: invalid weights")
+
+// New 返回以 seed 为种子的 PCG 生成器。
+func New(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+}
+
+// NewSeed 返回一个随机的种子。
+func NewSeed() uint64 {
+	return rand.Uint64()
+}
+
+// ParseSeed 解析十进制或带 0x 前缀的十六进制种子。
+func ParseSeed(s string) (uint64, error) {
+	seed, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("rng: parse seed %q: %w", s, err)
+	}
+	return seed, nil
+}
+
+// SeedFromEnv 读取环境变量 EnvSeed 中的种子，没有设置时 ok 为 false。
+func SeedFromEnv() (seed uint64, ok bool, err error) {
+	s, ok := os.LookupEnv(EnvSeed)
+	if !ok || s == "" {
+		return 0, false, nil
+	}
+	seed, err = ParseSeed(s)
+	return seed, err == nil, err
+}
+
+func intN(r *rand.Rand, n int) int {
+	if r == nil {
+		return rand.IntN(n)
+	}
+	return r.IntN(n)
+}
+
+func float64n(r *rand.Rand) float64 {
+	if r == nil {
+		return rand.Float64()
+	}
+	return r.Float64()
+}
+
+// Shuffle 用 Fisher–Yates 算法原地打乱 s。
+func Shuffle[S ~[]E, E any](r *rand.Rand, s S) {
+	for i := len(s) - 1; i > 0; i-- {
+		j := intN(r, i+1)
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// Permutation 返回 [0, n) 的一个随机排列。
+func Permutation(r *rand.Rand, n int) []int {
+	p := make([]int, n)
+	for i := range p {
+		p[i] = i
+	}
+	Shuffle(r, p)
+	return p
+}
+
+// Sample 不放回地从 s 中随机取出 k 个元素，返回新的切片，s 不会被修改。
+// k 不在 [0, len(s)] 内时 panic。
+func Sample[S ~[]E, E any](r *rand.Rand, s S, k int) S {
+	if k < 0 || k > len(s) {
+		panic(fmt.Sprintf("rng: Sample %d elements from %d", k, len(s)))
+	}
+	// 只执行 Fisher–Yates 的前 k 步，结果与完整打乱后取前 k 个的分布相同
+	c := make(S, len(s))
+	copy(c, s)
+	for i := range k {
+		j := i + intN(r, len(c)-i)
+		c[i], c[j] = c[j], c[i]
+	}
+	return c[:k:k]
+}
+
+// Reservoir 用蓄水池抽样从 seq 中不放回地随机取出最多 k 个元素，只遍历 seq 一次，
+// 适合长度未知或很大的序列。返回的元素的顺序没有意义。
+func Reservoir[E any](r *rand.Rand, seq iter.Seq[E], k int) []E {
+	if k <= 0 {
+		return nil
+	}
+	ret := make([]E, 0, k)
+	n := 0
+	for e := range seq {
+		if n++; len(ret) < k {
+			ret = append(ret, e)
+		} else if j := intN(r, n); j < k {
+			ret[j] = e
+		}
+	}
+	return ret
+}
+
+// WeightedChoice 返回随机的下标 i，取到 i 的概率与 weights[i] 成正比。
+// 权重不合法时返回 ErrInvalidWeights。需要在同一组权重上多次抽取时使用 NewWeighted。
+func WeightedChoice(r *rand.Rand, weights []float64) (int, error) {
+	w, err := NewWeighted(weights)
+	if err != nil {
+		return 0, err
+	}
+	return w.Choose(r), nil
+}
+
+// Weighted 保存一组权重的前缀和，每次抽取是 O(log n) 的。
+type Weighted struct {
+	cumulative []float64
+}
+
+// NewWeighted 返回按 weights 抽取下标的 Weighted，weights 被复制。
+func NewWeighted(weights []float64) (*Weighted, error) {
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("%w: no weights", ErrInvalidWeights)
+	}
+	cumulative := make([]float64, len(weights))
+	total := 0.0
+	for i, w := range weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return nil, fmt.Errorf("%w: weights[%d] = %v", ErrInvalidWeights, i, w)
+		}
+		total += w
+		cumulative[i] = total
+	}
+	if total == 0 || math.IsInf(total, 0) {
+		return nil, fmt.Errorf("%w: total weight %v", ErrInvalidWeights, total)
+	}
+	return &Weighted{cumulative}, nil
+}
+
+// Choose 返回随机的下标，权重为 0 的下标不会被选中。
+func (w *Weighted) Choose(r *rand.Rand) int {
+	x := float64n(r) * w.cumulative[len(w.cumulative)-1]
+	// 第一个前缀和大于 x 的位置，跳过了权重为 0 的下标
+	return sort.Search(len(w.cumulative), func(i int) bool { return w.cumulative[i] > x })
+}
@@ -0,0 +1,99 @@
+package rng
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestDeterministic(t *testing.T) {
+	a, b := New(42), New(42)
+	s1, s2 := Permutation(a, 100), Permutation(b, 100)
+	if !slices.Equal(s1, s2) {
+		t.Fatal("same seed, different permutations")
+	}
+	if slices.Equal(Permutation(New(43), 100), s1) {
+		t.Error("different seeds, same permutation")
+	}
+	for i, v := range slices.Sorted(slices.Values(s1)) {
+		if i != v {
+			t.Fatalf("not a permutation: %v", s1)
+		}
+	}
+	if !slices.Equal(Sample(New(1), s1, 10), Sample(New(1), s1, 10)) {
+		t.Error("Sample is not deterministic")
+	}
+}
+
+func TestSample(t *testing.T) {
+	r := New(1)
+	s := Permutation(r, 50)
+	orig := slices.Clone(s)
+	for k := range 51 {
+		got := Sample(r, s, k)
+		if len(got) != k {
+			t.Fatalf("Sample(%d): got %d elements", k, len(got))
+		}
+		seen := map[int]bool{}
+		for _, e := range got {
+			if seen[e] || e < 0 || e >= 50 {
+				t.Fatalf("Sample(%d): invalid or repeated element in %v", k, got)
+			}
+			seen[e] = true
+		}
+	}
+	if !slices.Equal(s, orig) {
+		t.Error("Sample modified its input")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Sample: want panic when k > len(s)")
+		}
+	}()
+	Sample(r, s, 51)
+}
+
+func TestReservoir(t *testing.T) {
+	r := New(2)
+	// 每个元素被选中的概率应接近 k/n
+	const n, k, rounds = 20, 5, 20000
+	counts := make([]int, n)
+	for range rounds {
+		for _, e := range Reservoir(r, slices.Values(Permutation(nil, n)), k) {
+			counts[e]++
+		}
+	}
+	for i, c := range counts {
+		if p := float64(c) / rounds; math.Abs(p-float64(k)/n) > 0.02 {
+			t.Errorf("element %d chosen with probability %.3f", i, p)
+		}
+	}
+	if got := Reservoir(r, slices.Values([]int{1, 2}), 5); len(got) != 2 {
+		t.Errorf("Reservoir: want all elements of a short sequence, but %v", got)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	w, err := NewWeighted([]float64{1, 0, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(3)
+	counts := make([]int, 3)
+	const rounds = 40000
+	for range rounds {
+		counts[w.Choose(r)]++
+	}
+	if counts[1] != 0 {
+		t.Errorf("index with zero weight chosen %d times", counts[1])
+	}
+	if p := float64(counts[2]) / rounds; math.Abs(p-0.75) > 0.01 {
+		t.Errorf("want probability 0.75, but %.3f", p)
+	}
+	for _, weights := range [][]float64{nil, {0, 0}, {1, -1}, {math.NaN()}, {math.Inf(1)}} {
+		if _, err := WeightedChoice(r, weights); !errors.Is(err, ErrInvalidWeights) {
+			t.Errorf("%v: want %v, but %v", weights, ErrInvalidWeights, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Package rngtest 为测试提供可以重放的随机数生成器，只应在测试中导入。
+//
+// 导入它的测试二进制多了一个 -rng.seed 参数：
+//
+//	go test ./example/ext_sort -run TestExtMergeSortNWay -args -rng.seed=12345
+//	RNG_SEED=12345 go test ./example/ext_sort
+package rngtest
+
+import (
+	"flag"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example/rng"
+)
+
+var seedFlag = flag.String("rng.seed", "", "seed of random test data, overrides $"+rng.EnvSeed)
+
+// Seed 返回测试使用的种子：依次取 -rng.seed、环境变量 RNG_SEED，都没有时随机生成。
+// 种子被记录到 tb 的日志中，测试失败时可以用它重放。种子不合法时测试立即失败。
+func Seed(tb testing.TB) uint64 {
+	tb.Helper()
+	var (
+		seed uint64
+		ok   bool
+		err  error
+		from = "-rng.seed"
+	)
+	if *seedFlag != "" {
+		seed, err = rng.ParseSeed(*seedFlag)
+		ok = err == nil
+	} else {
+		from = rng.EnvSeed
+		seed, ok, err = rng.SeedFromEnv()
+	}
+	if err != nil {
+		tb.Fatalf("%s: %v", from, err)
+	}
+	if !ok {
+		from, seed = "random", rng.NewSeed()
+	}
+	tb.Logf("rng seed %d (%s), replay with -rng.seed=%d or %s=%d", seed, from, seed, rng.EnvSeed, seed)
+	return seed
+}
+
+// Rand 返回以 Seed(tb) 为种子的生成器。
+func Rand(tb testing.TB) *rand.Rand {
+	tb.Helper()
+	return rng.New(Seed(tb))
+}
@@ -0,0 +1,128 @@
+package example
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// robotsRule 是 robots.txt 中的一条 Allow 或 Disallow 规则。
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// RobotsRules 是 robots.txt 中适用于某个 User-agent 的规则。
+// 零值允许访问所有路径。
+type RobotsRules struct {
+	rules []robotsRule
+}
+
+// robotsAllowAll 和 robotsDisallowAll 分别对应 robots.txt 不存在和无法获取的情况。
+var (
+	robotsAllowAll    = &RobotsRules{}
+	robotsDisallowAll = &RobotsRules{rules: []robotsRule{{"/", false}}}
+)
+
+// ParseRobots 解析 robots.txt，返回适用于 userAgent 的规则。
+// 有多个组匹配 userAgent 时合并它们的规则，没有组匹配时使用 User-agent: * 的组。
+// 只支持 User-agent、Allow 和 Disallow，其他字段会被忽略。
+func ParseRobots(r io.Reader, userAgent string) (*RobotsRules, error) {
+	userAgent = strings.ToLower(userAgent)
+	var (
+		specific, wildcard []robotsRule
+		matched, isAny     bool // 当前组是否匹配 userAgent 或 *
+		hasSpecific        bool
+		inAgents           bool // 是否正在读取组开头的连续 User-agent 行
+	)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				matched, isAny = false, false
+			}
+			inAgents = true
+			agent := strings.ToLower(value)
+			if agent == "*" {
+				isAny = true
+			} else if agent != "" && strings.Contains(userAgent, agent) {
+				matched, hasSpecific = true, true
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if value == "" {
+				// an empty Disallow allows everything, which is the default
+				continue
+			}
+			rule := robotsRule{value, key == "allow"}
+			if matched {
+				specific = append(specific, rule)
+			}
+			if isAny {
+				wildcard = append(wildcard, rule)
+			}
+		default:
+			inAgents = false
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if hasSpecific {
+		return &RobotsRules{specific}, nil
+	}
+	return &RobotsRules{wildcard}, nil
+}
+
+// Allowed 判断 path（可以带 query）是否允许访问。
+// 匹配长度最长的规则生效，长度相同时 Allow 优先。
+func (rr *RobotsRules) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	best, allow := -1, true
+	for _, r := range rr.rules {
+		if !robotsMatch(r.pattern, path) {
+			continue
+		}
+		if l := len(r.pattern); l > best || (l == best && r.allow) {
+			best, allow = l, r.allow
+		}
+	}
+	return allow
+}
+
+// robotsMatch 判断 path 是否匹配 pattern，pattern 中的 * 匹配任意字符串，
+// 结尾的 $ 表示必须匹配到 path 的末尾，否则只需匹配 path 的前缀。
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return len(path)-pos >= len(part) && strings.HasSuffix(path, part)
+		}
+		j := strings.Index(path[pos:], part)
+		if j < 0 {
+			return false
+		}
+		pos += j + len(part)
+	}
+	return !anchored || pos == len(path)
+}
@@ -0,0 +1,20 @@
+package example
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestRot13ReaderDataWithEOF(t *testing.T) {
+	// DataErrReader returns the last chunk of data together with io.EOF
+	r := &rot13Reader{iotest.DataErrReader(iotest.OneByteReader(strings.NewReader("Lbh penpxrq gur pbqr!")))}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "You cracked the code!"; string(b) != want {
+		t.Errorf("want %q, but %q", want, b)
+	}
+}
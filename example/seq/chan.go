@@ -0,0 +1,34 @@
+package seq
+
+import (
+	"context"
+	"iter"
+)
+
+// FromChan 产生从 ch 中收到的值，直到 ch 被关闭。提前结束遍历时 ch 中剩下的值不会被读取。
+func FromChan[E any](ch <-chan E) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		for e := range ch {
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// ToChan 在新的 goroutine 中遍历 seq，把每个元素发送到返回的 channel 中，buf 是 channel 的缓冲区大小。
+// 遍历完成或 ctx 被取消时 channel 被关闭，goroutine 退出，所以接收方不再读取时应当取消 ctx。
+func ToChan[E any](ctx context.Context, seq iter.Seq[E], buf int) <-chan E {
+	ch := make(chan E, buf)
+	go func() {
+		defer close(ch)
+		for e := range seq {
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,272 @@
+// Package seq 是基于 iter.Seq 和 iter.Seq2 的惰性迭代器工具。与 slice_utils.go 中的 Filter、
+// Batch 不同，这里的函数不修改输入，也不在调用时做任何计算，只在 range 遍历结果时才逐个处理元素：
+//
+//	evens := seq.Filter(seq.FromSlice(nums), func(n int) bool { return n%2 == 0 })
+//	for chunk := range seq.Chunk(seq.Take(evens, 10), 3) {
+//		fmt.Println(chunk)
+//	}
+//
+// 除非特别说明，返回的迭代器可以多次遍历，每次都会重新遍历输入。
+package seq
+
+import (
+	"iter"
+	"slices"
+)
+
+// FromSlice 按顺序产生 s 中的元素，与 slices.Values 相同。
+func FromSlice[S ~[]E, E any](s S) iter.Seq[E] {
+	return slices.Values(s)
+}
+
+// Collect 将 seq 中的所有元素收集到一个新的切片中，与 slices.Collect 相同。
+func Collect[E any](seq iter.Seq[E]) []E {
+	return slices.Collect(seq)
+}
+
+// Of 按顺序产生 elems。
+func Of[E any](elems ...E) iter.Seq[E] {
+	return slices.Values(elems)
+}
+
+// Range 产生 [from, to) 中的整数。
+func Range(from, to int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := from; i < to; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// Enumerate 产生 seq 中的元素和它们的下标。
+func Enumerate[E any](seq iter.Seq[E]) iter.Seq2[int, E] {
+	return func(yield func(int, E) bool) {
+		i := 0
+		for e := range seq {
+			if !yield(i, e) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// Keys 产生 seq 中每一对的第一个值。
+func Keys[K, V any](seq iter.Seq2[K, V]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range seq {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Values 产生 seq 中每一对的第二个值。
+func Values[K, V any](seq iter.Seq2[K, V]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range seq {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Map 产生 f 作用于每个元素的结果。
+func Map[T, U any](seq iter.Seq[T], f func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for e := range seq {
+			if !yield(f(e)) {
+				return
+			}
+		}
+	}
+}
+
+// Filter 只产生满足 f 的元素。
+func Filter[E any](seq iter.Seq[E], f func(E) bool) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		for e := range seq {
+			if f(e) && !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// FlatMap 依次产生 f 对每个元素返回的迭代器中的元素。
+func FlatMap[T, U any](seq iter.Seq[T], f func(T) iter.Seq[U]) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for e := range seq {
+			for u := range f(e) {
+				if !yield(u) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Zip 成对地产生 a 和 b 中的元素，较短的一个结束时停止。b 通过 iter.Pull 遍历。
+func Zip[A, B any](a iter.Seq[A], b iter.Seq[B]) iter.Seq2[A, B] {
+	return func(yield func(A, B) bool) {
+		next, stop := iter.Pull(b)
+		defer stop()
+		for x := range a {
+			y, ok := next()
+			if !ok || !yield(x, y) {
+				return
+			}
+		}
+	}
+}
+
+// Chunk 将 seq 分为长度为 n 的切片，最后一个可能更短。n 不是正数时 panic。
+// 每个切片都是新分配的，可以保留。
+func Chunk[E any](seq iter.Seq[E], n int) iter.Seq[[]E] {
+	if n <= 0 {
+		panic("seq: Chunk size must be positive")
+	}
+	return func(yield func([]E) bool) {
+		chunk := make([]E, 0, n)
+		for e := range seq {
+			if chunk = append(chunk, e); len(chunk) == n {
+				if !yield(chunk) {
+					return
+				}
+				chunk = make([]E, 0, n)
+			}
+		}
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
+
+// Window 产生 seq 中所有长度为 n 的连续子序列（滑动窗口），元素不足 n 个时什么都不产生。
+// n 不是正数时 panic。每个切片都是新分配的，可以保留。
+func Window[E any](seq iter.Seq[E], n int) iter.Seq[[]E] {
+	if n <= 0 {
+		panic("seq: Window size must be positive")
+	}
+	return func(yield func([]E) bool) {
+		// buf 是长度为 2n 的环，窗口总是 buf 中连续的 n 个元素，避免每次移动元素
+		buf := make([]E, 0, 2*n)
+		for e := range seq {
+			if len(buf) == cap(buf) {
+				buf = append(buf[:0], buf[n+1:]...)
+			}
+			if buf = append(buf, e); len(buf) >= n && !yield(slices.Clone(buf[len(buf)-n:])) {
+				return
+			}
+		}
+	}
+}
+
+// Take 最多产生前 n 个元素，之后不再从 seq 中读取。
+func Take[E any](seq iter.Seq[E], n int) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for e := range seq {
+			if !yield(e) {
+				return
+			}
+			if i++; i == n {
+				return
+			}
+		}
+	}
+}
+
+// Drop 跳过前 n 个元素，产生剩下的元素。
+func Drop[E any](seq iter.Seq[E], n int) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		i := 0
+		for e := range seq {
+			if i++; i > n && !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// TakeWhile 产生开头满足 f 的元素，遇到第一个不满足的元素时停止。
+func TakeWhile[E any](seq iter.Seq[E], f func(E) bool) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		for e := range seq {
+			if !f(e) || !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// DropWhile 跳过开头满足 f 的元素，从第一个不满足的元素开始产生。
+func DropWhile[E any](seq iter.Seq[E], f func(E) bool) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		dropping := true
+		for e := range seq {
+			if dropping = dropping && f(e); !dropping && !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// GroupBy 将 key 相同的相邻元素分为一组，产生每组的 key 和元素。与 SQL 的 GROUP BY 不同，
+// 不相邻的相同 key 会产生多个组，需要时先排序，或者使用 Group。
+func GroupBy[E any, K comparable](seq iter.Seq[E], key func(E) K) iter.Seq2[K, []E] {
+	return func(yield func(K, []E) bool) {
+		var (
+			cur   K
+			group []E
+		)
+		for e := range seq {
+			k := key(e)
+			if len(group) > 0 && k != cur {
+				if !yield(cur, group) {
+					return
+				}
+				group = nil
+			}
+			cur, group = k, append(group, e)
+		}
+		if len(group) > 0 {
+			yield(cur, group)
+		}
+	}
+}
+
+// Group 读取 seq 中的所有元素，按 key 分组，组内保持原来的顺序。
+func Group[E any, K comparable](seq iter.Seq[E], key func(E) K) map[K][]E {
+	ret := make(map[K][]E)
+	for e := range seq {
+		k := key(e)
+		ret[k] = append(ret[k], e)
+	}
+	return ret
+}
+
+// Reduce 从 init 开始依次用 f 合并每个元素，返回最终的结果。
+func Reduce[E, A any](seq iter.Seq[E], init A, f func(A, E) A) A {
+	acc := init
+	for e := range seq {
+		acc = f(acc, e)
+	}
+	return acc
+}
+
+// Count 返回 seq 中元素的个数。
+func Count[E any](seq iter.Seq[E]) int {
+	n := 0
+	for range seq {
+		n++
+	}
+	return n
+}
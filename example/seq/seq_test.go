@@ -0,0 +1,235 @@
+package seq
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"iter"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// naturals 产生 0, 1, 2, ...，永不结束，用于检查各函数都是惰性的。
+func naturals(yield func(int) bool) {
+	for i := 0; yield(i); i++ {
+	}
+}
+
+func isEven(n int) bool { return n%2 == 0 }
+
+func TestCombinators(t *testing.T) {
+	input := []int{3, 1, 4, 1, 5, 9, 2, 6}
+	s := FromSlice(input)
+	testcases := []struct {
+		name      string
+		got, want []int
+	}{
+		{"Map", Collect(Map(s, func(n int) int { return n * 10 })), []int{30, 10, 40, 10, 50, 90, 20, 60}},
+		{"Filter", Collect(Filter(s, isEven)), []int{4, 2, 6}},
+		{"FlatMap", Collect(FlatMap(Of(1, 2, 3), func(n int) iter.Seq[int] { return Range(0, n) })), []int{0, 0, 1, 0, 1, 2}},
+		{"Take", Collect(Take(naturals, 3)), []int{0, 1, 2}},
+		{"Take zero", Collect(Take(naturals, 0)), nil},
+		{"Drop", Collect(Drop(s, 6)), []int{2, 6}},
+		{"Drop all", Collect(Drop(s, 100)), nil},
+		{"TakeWhile", Collect(TakeWhile(naturals, func(n int) bool { return n < 4 })), []int{0, 1, 2, 3}},
+		{"DropWhile", Collect(DropWhile(s, func(n int) bool { return n != 5 })), []int{5, 9, 2, 6}},
+		{"Keys", Collect(Keys(Enumerate(Take(naturals, 3)))), []int{0, 1, 2}},
+		{"Values", Collect(Values(Enumerate(s))), input},
+		{"lazy", Collect(Take(Filter(Map(naturals, func(n int) int { return n * n }), isEven), 4)), []int{0, 4, 16, 36}},
+	}
+	for _, tc := range testcases {
+		if !slices.Equal(tc.got, tc.want) {
+			t.Errorf("%s: want %v, but %v", tc.name, tc.want, tc.got)
+		}
+	}
+	if !slices.Equal(input, []int{3, 1, 4, 1, 5, 9, 2, 6}) {
+		t.Errorf("input modified: %v", input)
+	}
+	if got := Reduce(s, "", func(acc string, n int) string { return acc + strconv.Itoa(n) }); got != "31415926" {
+		t.Errorf("Reduce: want 31415926, but %s", got)
+	}
+	if got := Count(Filter(s, isEven)); got != 3 {
+		t.Errorf("Count: want 3, but %d", got)
+	}
+}
+
+func TestZip(t *testing.T) {
+	var got []string
+	for a, b := range Zip(Of("a", "b", "c"), naturals) {
+		got = append(got, a+strconv.Itoa(b))
+	}
+	if want := []string{"a0", "b1", "c2"}; !slices.Equal(got, want) {
+		t.Errorf("Zip: want %v, but %v", want, got)
+	}
+	n := 0
+	for range Zip(naturals, Of(1, 2)) {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("Zip: want to stop at the shorter sequence, but got %d pairs", n)
+	}
+}
+
+func TestChunkWindow(t *testing.T) {
+	chunks := Collect(Chunk(Range(0, 7), 3))
+	if want := [][]int{{0, 1, 2}, {3, 4, 5}, {6}}; !slices.EqualFunc(chunks, want, slices.Equal) {
+		t.Errorf("Chunk: want %v, but %v", want, chunks)
+	}
+	// 保留的切片不应被后续的元素覆盖
+	chunks[0] = append(chunks[0], -1)
+	if chunks[1][0] != 3 {
+		t.Error("Chunk: chunks share memory")
+	}
+	windows := Collect(Window(Range(0, 8), 3))
+	if len(windows) != 6 {
+		t.Fatalf("Window: want 6 windows, but %v", windows)
+	}
+	for i, w := range windows {
+		if !slices.Equal(w, []int{i, i + 1, i + 2}) {
+			t.Errorf("Window %d: got %v", i, w)
+		}
+	}
+	if got := Collect(Window(Range(0, 2), 3)); got != nil {
+		t.Errorf("Window: want nothing for a short sequence, but %v", got)
+	}
+	if got := Collect(Take(Chunk(naturals, 2), 2)); len(got) != 2 {
+		t.Errorf("Chunk: not lazy, got %v", got)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	words := Of("apple", "avocado", "banana", "blueberry", "cherry", "apricot")
+	first := func(s string) byte { return s[0] }
+	var keys []byte
+	var sizes []int
+	for k, g := range GroupBy(words, first) {
+		keys, sizes = append(keys, k), append(sizes, len(g))
+	}
+	if string(keys) != "abca" || !slices.Equal(sizes, []int{2, 2, 1, 1}) {
+		t.Errorf("GroupBy: got keys %q sizes %v", keys, sizes)
+	}
+	groups := Group(words, first)
+	if want := []string{"apple", "avocado", "apricot"}; !slices.Equal(groups['a'], want) {
+		t.Errorf("Group: want %v, but %v", want, groups['a'])
+	}
+}
+
+func TestChan(t *testing.T) {
+	if got := Collect(FromChan(ToChan(context.Background(), Range(0, 5), 2))); !slices.Equal(got, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("ToChan/FromChan: got %v", got)
+	}
+	// 取消 ctx 后发送方应当关闭 channel 并退出，即使 seq 是无限的
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := ToChan(ctx, naturals, 0)
+	if got := Collect(Take(FromChan(ch), 3)); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Errorf("FromChan: got %v", got)
+	}
+	cancel()
+	for range ch {
+	}
+}
+
+// 以下是一个最小的 database/sql 驱动，每个查询返回 "n" 行，每行一个整数列。
+// 查询 "fail" 在第二行之后返回错误。
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt(query), nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.ErrUnsupported }
+
+type fakeStmt string
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return 0 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.ErrUnsupported }
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	if s == "fail" {
+		return &fakeRows{n: 5, failAt: 2}, nil
+	}
+	n, err := strconv.Atoi(string(s))
+	return &fakeRows{n: n, failAt: -1}, err
+}
+
+type fakeRows struct {
+	i, n, failAt int
+}
+
+var errFake = errors.New("fake driver error")
+
+func (r *fakeRows) Columns() []string { return []string{"v"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	switch r.i {
+	case r.n:
+		return io.EOF
+	case r.failAt:
+		return errFake
+	}
+	dest[0] = int64(r.i)
+	r.i++
+	return nil
+}
+
+func init() {
+	sql.Register("seqfake", fakeDriver{})
+}
+
+func scanInt(rows *sql.Rows) (int, error) {
+	var v int
+	err := rows.Scan(&v)
+	return v, err
+}
+
+func TestRows(t *testing.T) {
+	db, err := sql.Open("seqfake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	query := func(q string) *sql.Rows {
+		t.Helper()
+		rows, err := db.Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	got, err := ScanRows(query("4"), scanInt)
+	if err != nil || !slices.Equal(got, []int{0, 1, 2, 3}) {
+		t.Errorf("ScanRows: got %v %v", got, err)
+	}
+	got, err = ScanRows(query("fail"), scanInt)
+	if !errors.Is(err, errFake) || !slices.Equal(got, []int{0, 1}) {
+		t.Errorf("ScanRows: want %v after 2 rows, but %v %v", errFake, got, err)
+	}
+	got, err = ScanRows(query("2"), func(rows *sql.Rows) (int, error) {
+		var s struct{}
+		return 0, rows.Scan(&s)
+	})
+	if err == nil || got != nil {
+		t.Errorf("ScanRows: want scan error, but %v %v", got, err)
+	}
+
+	// 提前 break 时 rows 应被关闭，连接被归还，之后的查询可以复用它
+	rows := query("100")
+	for v := range Values(Enumerate(Keys(Rows(rows, scanInt)))) {
+		if v == 2 {
+			break
+		}
+	}
+	if rows.Next() {
+		t.Error("Rows: rows not closed after break")
+	}
+	if stats := db.Stats(); stats.InUse != 0 {
+		t.Errorf("Rows: %d connections in use after break", stats.InUse)
+	}
+}
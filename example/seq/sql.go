@@ -0,0 +1,49 @@
+package seq
+
+import (
+	"database/sql"
+	"fmt"
+	"iter"
+)
+
+// Rows 用 scan 依次读取 rows 中的每一行。scan 返回错误时把错误与零值一起产生并停止；
+// 遍历结束后如果 rows.Err() 不为 nil 也会产生一次。遍历结束或提前 break 时 rows 都会被关闭，
+// 所以返回的迭代器只能遍历一次。
+//
+//	for user, err := range seq.Rows(rows, scanUser) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func Rows[T any](rows *sql.Rows, scan func(*sql.Rows) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer rows.Close()
+		var zeroValue T
+		for rows.Next() {
+			v, err := scan(rows)
+			if err != nil {
+				yield(zeroValue, fmt.Errorf("seq: scan row: %w", err))
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zeroValue, fmt.Errorf("seq: iterate rows: %w", err))
+		}
+	}
+}
+
+// ScanRows 读取 rows 中的所有行，遇到第一个错误时返回已读取的行和这个错误。
+func ScanRows[T any](rows *sql.Rows, scan func(*sql.Rows) (T, error)) ([]T, error) {
+	var ret []T
+	for v, err := range Rows(rows, scan) {
+		if err != nil {
+			return ret, err
+		}
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
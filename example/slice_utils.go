@@ -3,13 +3,16 @@ package example
 import (
 	"fmt"
 	"io"
-	"math/rand"
 	"os"
 	"sort"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example/rng"
 )
 
+// SprintSlice 返回 s 的长度、容量、底层数组的地址和内容。%p 对切片输出第 0 个元素的地址，
+// 与 &s[0] 相同，但对空切片不会 panic，nil 切片的地址为 0x0。
 func SprintSlice[S ~[]E, E any](s S) string {
-	return fmt.Sprintf("len: %d\tcap: %d\t0addr:%p\t%v\n", len(s), cap(s), &s[0], s)
+	return fmt.Sprintf("len: %d\tcap: %d\t0addr:%p\t%v\n", len(s), cap(s), s, s)
 }
 
 func FprintSlice[S ~[]E, E any](w io.Writer, s S) (int, error) {
@@ -26,7 +29,11 @@ func Clone[S ~[]E, E any](s S) S {
 	return ret
 }
 
+// PopBack 删除并返回最后一个元素，s 为空时 panic，不想 panic 时使用 TryPopBack
 func PopBack[S ~[]E, E any](s S) (S, E) {
+	if len(s) == 0 {
+		panic(emptyError("PopBack"))
+	}
 	return s[:len(s)-1], s[len(s)-1]
 }
 
@@ -34,13 +41,17 @@ func PushFront[S ~[]E, E any](s S, e ...E) S {
 	return append(e, s...)
 }
 
+// PopFront 删除并返回第一个元素，s 为空时 panic，不想 panic 时使用 TryPopFront
 func PopFront[S ~[]E, E any](s S) (S, E) {
+	if len(s) == 0 {
+		panic(emptyError("PopFront"))
+	}
 	return s[1:], s[0]
 }
 
 func Insert[S ~[]E, E any](s S, i int, e E) S {
-	if i < 0 || i > len(s) {
-		panic("invalid args of InsertMany")
+	if err := checkPosition("Insert", i, len(s)); err != nil {
+		panic(err)
 	}
 	var zeroValue E
 	s = append(s, zeroValue) // avoid memory leak
@@ -50,8 +61,8 @@ func Insert[S ~[]E, E any](s S, i int, e E) S {
 }
 
 func InsertMany[S ~[]E, E any](s S, i int, e ...E) S {
-	if i < 0 || i > len(s) {
-		panic("invalid args of InsertMany")
+	if err := checkPosition("InsertMany", i, len(s)); err != nil {
+		panic(err)
 	}
 	if n := len(s) + len(e); n <= cap(s) {
 		s2 := s[:n]
@@ -67,8 +78,8 @@ func InsertMany[S ~[]E, E any](s S, i int, e ...E) S {
 }
 
 func Delete[S ~[]E, E any](s S, i int) S {
-	if i < 0 || i >= len(s) {
-		panic("invalid args of Delete")
+	if err := checkIndex("Delete", i, len(s)); err != nil {
+		panic(err)
 	}
 	copy(s[i:], s[i+1:])
 	var zeroValue E
@@ -76,6 +87,7 @@ func Delete[S ~[]E, E any](s S, i int) S {
 	return s[:len(s)-1]
 }
 
+// Filter 原地保留满足 f 的元素，s 的内容会被覆盖。需要保留 s 时使用 seq.Filter。
 func Filter[S ~[]E, E any](s S, f func(E) bool) S {
 	n := 0
 	for i := range s {
@@ -89,8 +101,8 @@ func Filter[S ~[]E, E any](s S, f func(E) bool) S {
 
 // Delete elements in s[i:j]
 func Cut[S ~[]E, E any](s S, i, j int) S {
-	if i < 0 || i > j || j > len(s) {
-		panic("invalid args of Cut")
+	if err := checkRange("Cut", i, j, len(s)); err != nil {
+		panic(err)
 	}
 	copy(s[i:], s[j:])
 	var zeroValue E
@@ -108,8 +120,8 @@ func Reverse[S ~[]E, E any](s S) {
 }
 
 func Batch[S ~[]E, E any](s S, sz int) []S {
-	if sz <= 0 {
-		panic("invalid args of Expand")
+	if err := checkSize("Batch", sz); err != nil {
+		panic(err)
 	}
 	if sz >= len(s) {
 		return []S{s}
@@ -123,19 +135,22 @@ func Batch[S ~[]E, E any](s S, sz int) []S {
 
 // Insert n zero-value elements at position i:
 func Expand[S ~[]E, E any](s S, i, n int) S {
-	if n < 0 || i < 0 || i > len(s) {
-		panic("invalid args of Expand")
+	if err := checkExpand(i, n, len(s)); err != nil {
+		panic(err)
 	}
 	return append(s[:i], append(make(S, n), s[i:]...)...)
 }
 
+// Shuffle 使用全局的随机源原地打乱 s，结果无法复现，需要指定种子时使用 rng.Shuffle。
 func Shuffle[S ~[]E, E any](s S) {
-	rand.Shuffle(len(s), func(i, j int) {
-		s[i], s[j] = s[j], s[i]
-	})
+	rng.Shuffle(nil, s)
 }
 
+// SortAndDeduplicate 按 lt 原地排序 s，并去掉相等（互不小于）的元素，每组相等的元素只保留一个。
 func SortAndDeduplicate[S ~[]E, Lt func(E, E) bool, E any](s S, lt Lt) S {
+	if len(s) == 0 {
+		return s
+	}
 	sort.Slice(s, func(i, j int) bool {
 		return lt(s[i], s[j])
 	})
@@ -0,0 +1,139 @@
+package example
+
+import (
+	"errors"
+	"fmt"
+)
+
+// slice_utils.go 中的函数遇到非法的下标时 panic，适合下标由程序自己算出的场合；
+// 下标来自用户输入时使用这里的版本，它们返回错误或 ok，而不是 panic。
+// 两组函数使用相同的检查，panic 的值就是这里返回的错误。
+
+var (
+	// ErrIndexOutOfRange 表示下标或下标区间超出了切片的范围。
+	ErrIndexOutOfRange = errors.New("index out of range")
+	// ErrEmpty 表示对空切片执行了需要元素的操作。
+	ErrEmpty = errors.New("empty slice")
+	// ErrInvalidSize 表示分批大小或元素个数不合法。
+	ErrInvalidSize = errors.New("invalid size")
+)
+
+func emptyError(op string) error {
+	return fmt.Errorf("%s: %w", op, ErrEmpty)
+}
+
+// checkIndex 检查 i 是否是已有元素的下标，即 0 <= i < n。
+func checkIndex(op string, i, n int) error {
+	if i < 0 || i >= n {
+		return fmt.Errorf("%s: %w: index %d with length %d", op, ErrIndexOutOfRange, i, n)
+	}
+	return nil
+}
+
+// checkPosition 检查 i 是否是可以插入的位置，即 0 <= i <= n。
+func checkPosition(op string, i, n int) error {
+	if i < 0 || i > n {
+		return fmt.Errorf("%s: %w: position %d with length %d", op, ErrIndexOutOfRange, i, n)
+	}
+	return nil
+}
+
+// checkRange 检查 [i, j) 是否是合法的区间，即 0 <= i <= j <= n。
+func checkRange(op string, i, j, n int) error {
+	if i < 0 || i > j || j > n {
+		return fmt.Errorf("%s: %w: range [%d:%d] with length %d", op, ErrIndexOutOfRange, i, j, n)
+	}
+	return nil
+}
+
+func checkSize(op string, sz int) error {
+	if sz <= 0 {
+		return fmt.Errorf("%s: %w: %d", op, ErrInvalidSize, sz)
+	}
+	return nil
+}
+
+func checkExpand(i, n, l int) error {
+	if n < 0 {
+		return fmt.Errorf("Expand: %w: %d", ErrInvalidSize, n)
+	}
+	return checkPosition("Expand", i, l)
+}
+
+// TryPopBack 与 PopBack 相同，但 s 为空时返回 false 而不是 panic。
+func TryPopBack[S ~[]E, E any](s S) (S, E, bool) {
+	if len(s) == 0 {
+		var zeroValue E
+		return s, zeroValue, false
+	}
+	s, e := PopBack(s)
+	return s, e, true
+}
+
+// TryPopFront 与 PopFront 相同，但 s 为空时返回 false 而不是 panic。
+func TryPopFront[S ~[]E, E any](s S) (S, E, bool) {
+	if len(s) == 0 {
+		var zeroValue E
+		return s, zeroValue, false
+	}
+	s, e := PopFront(s)
+	return s, e, true
+}
+
+// At 返回 s[i]，i 超出范围时返回 false。
+func At[S ~[]E, E any](s S, i int) (E, bool) {
+	if i < 0 || i >= len(s) {
+		var zeroValue E
+		return zeroValue, false
+	}
+	return s[i], true
+}
+
+// CheckedInsert 与 Insert 相同，但 i 不在 [0, len(s)] 内时返回 ErrIndexOutOfRange，s 不变。
+func CheckedInsert[S ~[]E, E any](s S, i int, e E) (S, error) {
+	if err := checkPosition("Insert", i, len(s)); err != nil {
+		return s, err
+	}
+	return Insert(s, i, e), nil
+}
+
+// CheckedInsertMany 与 InsertMany 相同，但 i 不在 [0, len(s)] 内时返回 ErrIndexOutOfRange，s 不变。
+func CheckedInsertMany[S ~[]E, E any](s S, i int, e ...E) (S, error) {
+	if err := checkPosition("InsertMany", i, len(s)); err != nil {
+		return s, err
+	}
+	return InsertMany(s, i, e...), nil
+}
+
+// CheckedDelete 与 Delete 相同，但 i 不在 [0, len(s)) 内时返回 ErrIndexOutOfRange，s 不变。
+func CheckedDelete[S ~[]E, E any](s S, i int) (S, error) {
+	if err := checkIndex("Delete", i, len(s)); err != nil {
+		return s, err
+	}
+	return Delete(s, i), nil
+}
+
+// CheckedCut 与 Cut 相同，但 [i, j) 不合法时返回 ErrIndexOutOfRange，s 不变。
+func CheckedCut[S ~[]E, E any](s S, i, j int) (S, error) {
+	if err := checkRange("Cut", i, j, len(s)); err != nil {
+		return s, err
+	}
+	return Cut(s, i, j), nil
+}
+
+// CheckedBatch 与 Batch 相同，但 sz 不是正数时返回 ErrInvalidSize。
+func CheckedBatch[S ~[]E, E any](s S, sz int) ([]S, error) {
+	if err := checkSize("Batch", sz); err != nil {
+		return nil, err
+	}
+	return Batch(s, sz), nil
+}
+
+// CheckedExpand 与 Expand 相同，但 n 为负时返回 ErrInvalidSize，i 不在 [0, len(s)] 内时
+// 返回 ErrIndexOutOfRange，s 不变。
+func CheckedExpand[S ~[]E, E any](s S, i, n int) (S, error) {
+	if err := checkExpand(i, n, len(s)); err != nil {
+		return s, err
+	}
+	return Expand(s, i, n), nil
+}
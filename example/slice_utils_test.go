@@ -0,0 +1,211 @@
+package example
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 以下 ref* 是 slice_utils 中各个操作的朴素实现：总是分配新的切片，用 ok 表示参数是否合法。
+
+func refInsertMany(s []byte, i int, e ...byte) ([]byte, bool) {
+	if i < 0 || i > len(s) {
+		return nil, false
+	}
+	var ret []byte
+	ret = append(ret, s[:i]...)
+	ret = append(ret, e...)
+	return append(ret, s[i:]...), true
+}
+
+func refDelete(s []byte, i int) ([]byte, bool) {
+	return refCut(s, i, i+1)
+}
+
+func refCut(s []byte, i, j int) ([]byte, bool) {
+	if i < 0 || j < i || j > len(s) {
+		return nil, false
+	}
+	var ret []byte
+	for k, b := range s {
+		if k < i || k >= j {
+			ret = append(ret, b)
+		}
+	}
+	return ret, true
+}
+
+func refBatch(s []byte, sz int) ([][]byte, bool) {
+	if sz <= 0 {
+		return nil, false
+	}
+	var ret [][]byte
+	for len(s) > sz {
+		ret = append(ret, s[:sz])
+		s = s[sz:]
+	}
+	return append(ret, s), true
+}
+
+func refExpand(s []byte, i, n int) ([]byte, bool) {
+	if n < 0 {
+		return nil, false
+	}
+	return refInsertMany(s, i, make([]byte, n)...)
+}
+
+// checkErr 判断 err 与参考实现的 ok 是否一致，不一致时返回描述。
+func checkErr(err error, ok bool, want error) string {
+	switch {
+	case ok && err != nil:
+		return fmt.Sprintf("unexpected error %v", err)
+	case !ok && !errors.Is(err, want):
+		return fmt.Sprintf("want %v, but %v", want, err)
+	}
+	return ""
+}
+
+// FuzzSliceOps 把 ops 解释为一串 (操作, 参数1, 参数2) 的三元组，参数是有符号的，
+// 依次对同一个切片执行 Checked* 和参考实现，并比较结果。
+func FuzzSliceOps(f *testing.F) {
+	f.Add([]byte("hello"), []byte{0, 2, 'x', 1, 0, 0, 2, 1, 3, 3, 2, 0, 4, 5, 2, 5, 0, 0, 6, 0, 0})
+	f.Add([]byte{}, []byte{0, 1, 0, 1, 0xff, 0, 2, 0, 0, 3, 0, 0xfe, 4, 0, 0, 5, 0, 0})
+	f.Add([]byte("abcdefgh"), []byte{4, 3, 0, 4, 0x80, 0, 5, 8, 0x81, 1, 8, 2, 2, 2, 5})
+	f.Fuzz(func(t *testing.T, data, ops []byte) {
+		// 留出多余的容量，覆盖原地修改的分支
+		s := make([]byte, len(data), len(data)+4)
+		copy(s, data)
+		ref := slices.Clone(data)
+		for k := 0; k+2 < len(ops); k += 3 {
+			op, a, b := ops[k]%8, int(int8(ops[k+1])), int(int8(ops[k+2]))
+			var (
+				err  error
+				ok   bool
+				want = ErrIndexOutOfRange
+				next []byte
+				desc string
+			)
+			switch op {
+			case 0:
+				desc = fmt.Sprintf("Insert(%d, %d)", a, b)
+				s, err = CheckedInsert(s, a, byte(b))
+				next, ok = refInsertMany(ref, a, byte(b))
+			case 1:
+				desc = fmt.Sprintf("InsertMany(%d, %d elements)", a, b&3)
+				e := bytes.Repeat([]byte{byte(b)}, b&3)
+				s, err = CheckedInsertMany(s, a, e...)
+				next, ok = refInsertMany(ref, a, e...)
+			case 2:
+				desc = fmt.Sprintf("Delete(%d)", a)
+				s, err = CheckedDelete(s, a)
+				next, ok = refDelete(ref, a)
+			case 3:
+				desc = fmt.Sprintf("Cut(%d, %d)", a, b)
+				s, err = CheckedCut(s, a, b)
+				next, ok = refCut(ref, a, b)
+			case 4:
+				desc = fmt.Sprintf("Expand(%d, %d)", a, b)
+				s, err = CheckedExpand(s, a, b)
+				next, ok = refExpand(ref, a, b)
+				if b < 0 {
+					want = ErrInvalidSize
+				}
+			case 5:
+				desc = fmt.Sprintf("Batch(%d)", a)
+				batches, err := CheckedBatch(s, a)
+				refBatches, ok := refBatch(ref, a)
+				if msg := checkErr(err, ok, ErrInvalidSize); msg != "" {
+					t.Fatalf("%s: %s", desc, msg)
+				}
+				if !slices.EqualFunc(batches, refBatches, slices.Equal) {
+					t.Fatalf("%s of %v: want %v, but %v", desc, ref, refBatches, batches)
+				}
+				for _, batch := range batches[:max(len(batches)-1, 0)] {
+					// 除最后一批外每一批的容量都被限制，append 不会覆盖下一批
+					if cap(batch) != len(batch) {
+						t.Fatalf("%s: batch %v has extra capacity %d", desc, batch, cap(batch))
+					}
+				}
+				continue
+			case 6:
+				desc = "PopBack"
+				var e byte
+				s, e, ok = TryPopBack(s)
+				if ok != (len(ref) > 0) || ok && e != ref[len(ref)-1] {
+					t.Fatalf("%s of %v: got %v %v", desc, ref, e, ok)
+				}
+				if ok {
+					ref = ref[:len(ref)-1]
+				}
+				next, ok = ref, true
+			case 7:
+				desc = "PopFront"
+				var e byte
+				s, e, ok = TryPopFront(s)
+				if ok != (len(ref) > 0) || ok && e != ref[0] {
+					t.Fatalf("%s of %v: got %v %v", desc, ref, e, ok)
+				}
+				if ok {
+					ref = ref[1:]
+				}
+				next, ok = ref, true
+			}
+			if msg := checkErr(err, ok, want); msg != "" {
+				t.Fatalf("%s of %v: %s", desc, ref, msg)
+			}
+			if ok {
+				ref = next
+			}
+			if !slices.Equal(s, ref) {
+				t.Fatalf("%s: want %v, but %v", desc, ref, s)
+			}
+		}
+	})
+}
+
+func TestPanicMessages(t *testing.T) {
+	testcases := []struct {
+		name string
+		f    func()
+		want error
+	}{
+		{"Insert", func() { Insert([]int{1}, 2, 0) }, ErrIndexOutOfRange},
+		{"InsertMany", func() { InsertMany([]int{1}, -1, 0) }, ErrIndexOutOfRange},
+		{"Delete", func() { Delete([]int{1}, 1) }, ErrIndexOutOfRange},
+		{"Cut", func() { Cut([]int{1}, 1, 0) }, ErrIndexOutOfRange},
+		{"Batch", func() { Batch([]int{1}, 0) }, ErrInvalidSize},
+		{"Expand", func() { Expand([]int{1}, 0, -1) }, ErrInvalidSize},
+		{"PopBack", func() { PopBack([]int{}) }, ErrEmpty},
+		{"PopFront", func() { PopFront([]int(nil)) }, ErrEmpty},
+	}
+	for _, tc := range testcases {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, tc.want) || !strings.HasPrefix(err.Error(), tc.name+":") {
+					t.Errorf("%s: want panic with %v, but %v", tc.name, tc.want, err)
+				}
+			}()
+			tc.f()
+		}()
+	}
+	if _, ok := At([]int{1}, 1); ok {
+		t.Error("At: want false for out of range index")
+	}
+}
+
+func TestSprintSlice(t *testing.T) {
+	s := make([]int, 2, 4)
+	if got, want := SprintSlice(s), fmt.Sprintf("len: 2\tcap: 4\t0addr:%p\t[0 0]\n", &s[0]); got != want {
+		t.Errorf("want %q, but %q", want, got)
+	}
+	if got := SprintSlice([]int(nil)); got != "len: 0\tcap: 0\t0addr:0x0\t[]\n" {
+		t.Errorf("nil slice: got %q", got)
+	}
+	if got := SprintSlice(s[:0]); !strings.HasPrefix(got, "len: 0\tcap: 4\t0addr:0x") {
+		t.Errorf("empty slice: got %q", got)
+	}
+}
@@ -0,0 +1,228 @@
+package example
+
+import (
+	"cmp"
+	"iter"
+	"sort"
+)
+
+// multiMapEntry 是 SortedMultiMap 中的一个键值对。
+type multiMapEntry[K, V any] struct {
+	key K
+	val V
+}
+
+// SortedMultiMap 是用有序切片实现的多重映射：一个键可以对应多个值，所有键值对按键从小到大排列，
+// 同一个键的值保持插入的顺序。与 SortedSet 一样，互不小于的两个键被视为相等，查找是 O(log n) 的，
+// 插入和删除是 O(n) 的。SortedMultiMap 需要用 NewSortedMultiMap 或 NewSortedMultiMapFunc 创建，
+// 不是并发安全的，遍历期间不能修改。
+type SortedMultiMap[K, V any] struct {
+	entries []multiMapEntry[K, V]
+	lt      func(K, K) bool
+}
+
+// NewSortedMultiMap 返回键按 < 排序的空 SortedMultiMap。
+func NewSortedMultiMap[K cmp.Ordered, V any]() *SortedMultiMap[K, V] {
+	return NewSortedMultiMapFunc[K, V](cmp.Less[K])
+}
+
+// NewSortedMultiMapFunc 返回键按 lt 排序的空 SortedMultiMap。
+func NewSortedMultiMapFunc[K, V any](lt func(K, K) bool) *SortedMultiMap[K, V] {
+	return &SortedMultiMap[K, V]{lt: lt}
+}
+
+// Len 返回键值对的个数。
+func (m *SortedMultiMap[K, V]) Len() int {
+	return len(m.entries)
+}
+
+// lowerBound 返回第一个键不小于 k 的键值对的下标。
+func (m *SortedMultiMap[K, V]) lowerBound(k K) int {
+	return sort.Search(len(m.entries), func(i int) bool { return !m.lt(m.entries[i].key, k) })
+}
+
+// upperBound 返回第一个键大于 k 的键值对的下标。
+func (m *SortedMultiMap[K, V]) upperBound(k K) int {
+	return sort.Search(len(m.entries), func(i int) bool { return m.lt(k, m.entries[i].key) })
+}
+
+// equalRange 返回键与 k 相等的键值对的下标范围 [i, j)。
+func (m *SortedMultiMap[K, V]) equalRange(k K) (int, int) {
+	i := m.lowerBound(k)
+	return i, i + sort.Search(len(m.entries)-i, func(j int) bool { return m.lt(k, m.entries[i+j].key) })
+}
+
+// Insert 在键 k 的所有值之后插入 v。
+func (m *SortedMultiMap[K, V]) Insert(k K, v V) {
+	m.entries = Insert(m.entries, m.upperBound(k), multiMapEntry[K, V]{k, v})
+}
+
+// InsertAll 批量插入 seq 中的键值对。所有键值对先被追加到末尾，再以（键，插入顺序）为序用
+// SortAndDeduplicate 统一排序，插入顺序互不相同，所以不会有键值对被去掉，效果与依次 Insert 相同。
+func (m *SortedMultiMap[K, V]) InsertAll(seq iter.Seq2[K, V]) {
+	type indexed struct {
+		multiMapEntry[K, V]
+		i int
+	}
+	all := make([]indexed, 0, len(m.entries))
+	for i, e := range m.entries {
+		all = append(all, indexed{e, i})
+	}
+	for k, v := range seq {
+		all = append(all, indexed{multiMapEntry[K, V]{k, v}, len(all)})
+	}
+	all = SortAndDeduplicate(all, func(a, b indexed) bool {
+		switch {
+		case m.lt(a.key, b.key):
+			return true
+		case m.lt(b.key, a.key):
+			return false
+		}
+		return a.i < b.i
+	})
+	m.entries = make([]multiMapEntry[K, V], len(all))
+	for i, e := range all {
+		m.entries[i] = e.multiMapEntry
+	}
+}
+
+// Contains 返回 k 是否至少有一个值。
+func (m *SortedMultiMap[K, V]) Contains(k K) bool {
+	i := m.lowerBound(k)
+	return i < len(m.entries) && !m.lt(k, m.entries[i].key)
+}
+
+// Get 返回 k 的所有值组成的新切片，没有时返回 nil。
+func (m *SortedMultiMap[K, V]) Get(k K) []V {
+	i, j := m.equalRange(k)
+	if i == j {
+		return nil
+	}
+	ret := make([]V, 0, j-i)
+	for _, e := range m.entries[i:j] {
+		ret = append(ret, e.val)
+	}
+	return ret
+}
+
+// Count 返回 k 的值的个数。
+func (m *SortedMultiMap[K, V]) Count(k K) int {
+	i, j := m.equalRange(k)
+	return j - i
+}
+
+// Remove 删除 k 的所有值，返回删除的个数。
+func (m *SortedMultiMap[K, V]) Remove(k K) int {
+	i, j := m.equalRange(k)
+	m.entries = Cut(m.entries, i, j)
+	return j - i
+}
+
+// RemoveFunc 删除 k 的值中使 f 返回 true 的那些，返回删除的个数。
+func (m *SortedMultiMap[K, V]) RemoveFunc(k K, f func(V) bool) int {
+	i, j := m.equalRange(k)
+	kept := Filter(m.entries[i:j], func(e multiMapEntry[K, V]) bool { return !f(e.val) })
+	m.entries = Cut(m.entries, i+len(kept), j)
+	return j - i - len(kept)
+}
+
+// Rank 返回键小于 k 的键值对的个数。
+func (m *SortedMultiMap[K, V]) Rank(k K) int {
+	return m.lowerBound(k)
+}
+
+// At 返回第 i 个键值对（从 0 开始），i 超出范围时 panic。
+func (m *SortedMultiMap[K, V]) At(i int) (K, V) {
+	if err := checkIndex("At", i, len(m.entries)); err != nil {
+		panic(err)
+	}
+	return m.entries[i].key, m.entries[i].val
+}
+
+// Range 按顺序产生键在 [lo, hi) 中的键值对。
+func (m *SortedMultiMap[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		i := m.lowerBound(lo)
+		for _, e := range m.entries[i:max(m.lowerBound(hi), i)] {
+			if !yield(e.key, e.val) {
+				return
+			}
+		}
+	}
+}
+
+// All 按顺序产生所有键值对。
+func (m *SortedMultiMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, e := range m.entries {
+			if !yield(e.key, e.val) {
+				return
+			}
+		}
+	}
+}
+
+// Keys 按顺序产生所有不同的键，每个键只产生一次。
+func (m *SortedMultiMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for i := 0; i < len(m.entries); i = m.upperBound(m.entries[i].key) {
+			if !yield(m.entries[i].key) {
+				return
+			}
+		}
+	}
+}
+
+// merge 按键归并 m 和 o，onlyM、onlyO 和 both 分别决定只在 m 中、只在 o 中和两边都有的键是否保留。
+// 保留两边都有的键时 m 和 o 的值都保留，m 的在前。o 必须与 m 使用相同的顺序。
+func (m *SortedMultiMap[K, V]) merge(o *SortedMultiMap[K, V], onlyM, onlyO, both bool) *SortedMultiMap[K, V] {
+	ret := &SortedMultiMap[K, V]{lt: m.lt}
+	a, b := m.entries, o.entries
+	for len(a) > 0 && len(b) > 0 {
+		// 每次处理一个键在 a 或 b 中的所有值
+		ka, kb := a[0].key, b[0].key
+		na := sort.Search(len(a), func(i int) bool { return m.lt(ka, a[i].key) })
+		nb := sort.Search(len(b), func(i int) bool { return m.lt(kb, b[i].key) })
+		switch {
+		case m.lt(ka, kb):
+			if onlyM {
+				ret.entries = append(ret.entries, a[:na]...)
+			}
+			a = a[na:]
+		case m.lt(kb, ka):
+			if onlyO {
+				ret.entries = append(ret.entries, b[:nb]...)
+			}
+			b = b[nb:]
+		default:
+			if both {
+				ret.entries = append(append(ret.entries, a[:na]...), b[:nb]...)
+			}
+			a, b = a[na:], b[nb:]
+		}
+	}
+	if onlyM {
+		ret.entries = append(ret.entries, a...)
+	}
+	if onlyO {
+		ret.entries = append(ret.entries, b...)
+	}
+	return ret
+}
+
+// Union 返回包含 m 和 o 所有键值对的 SortedMultiMap，同一个键的值中 m 的在前。
+// o 必须与 m 使用相同的顺序。
+func (m *SortedMultiMap[K, V]) Union(o *SortedMultiMap[K, V]) *SortedMultiMap[K, V] {
+	return m.merge(o, true, true, true)
+}
+
+// Intersect 返回 m 和 o 中都有的键以及它们在两边的所有值，m 的值在前。
+// o 必须与 m 使用相同的顺序。
+func (m *SortedMultiMap[K, V]) Intersect(o *SortedMultiMap[K, V]) *SortedMultiMap[K, V] {
+	return m.merge(o, false, false, true)
+}
+
+// Difference 返回 m 中键不在 o 中的键值对，o 必须与 m 使用相同的顺序。
+func (m *SortedMultiMap[K, V]) Difference(o *SortedMultiMap[K, V]) *SortedMultiMap[K, V] {
+	return m.merge(o, true, false, false)
+}
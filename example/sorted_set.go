@@ -0,0 +1,189 @@
+package example
+
+import (
+	"cmp"
+	"iter"
+	"sort"
+)
+
+// SortedSet 是用有序切片实现的集合，元素按 lt 从小到大排列，互不小于的两个元素被视为相等。
+// 查找和 Rank 是 O(log n) 的，Insert 和 Remove 需要移动元素，是 O(n) 的，适合读多写少的场景。
+// SortedSet 需要用 NewSortedSet 或 NewSortedSetFunc 创建，不是并发安全的，遍历期间不能修改。
+type SortedSet[E any] struct {
+	elems []E
+	lt    func(E, E) bool
+}
+
+// NewSortedSet 返回包含 elems 的 SortedSet，元素按 < 排序，elems 不会被修改。
+func NewSortedSet[E cmp.Ordered](elems ...E) *SortedSet[E] {
+	return NewSortedSetFunc(cmp.Less[E], elems...)
+}
+
+// NewSortedSetFunc 返回包含 elems 的 SortedSet，元素按 lt 排序，elems 不会被修改。
+func NewSortedSetFunc[E any](lt func(E, E) bool, elems ...E) *SortedSet[E] {
+	s := &SortedSet[E]{lt: lt}
+	s.InsertAll(elems...)
+	return s
+}
+
+func (s *SortedSet[E]) Len() int {
+	return len(s.elems)
+}
+
+// search 返回第一个不小于 e 的元素的下标，以及这个元素是否与 e 相等。
+func (s *SortedSet[E]) search(e E) (int, bool) {
+	i := sort.Search(len(s.elems), func(i int) bool { return !s.lt(s.elems[i], e) })
+	return i, i < len(s.elems) && !s.lt(e, s.elems[i])
+}
+
+func (s *SortedSet[E]) Contains(e E) bool {
+	_, ok := s.search(e)
+	return ok
+}
+
+// Insert 插入 e，已有相等的元素时不做任何事并返回 false。
+func (s *SortedSet[E]) Insert(e E) bool {
+	i, ok := s.search(e)
+	if ok {
+		return false
+	}
+	s.elems = Insert(s.elems, i, e)
+	return true
+}
+
+// InsertAll 批量插入 elems。elems 被追加到末尾后统一用 SortAndDeduplicate 排序去重，
+// 比逐个 Insert 快；相等的元素只保留一个，但不保证保留的是哪一个。
+func (s *SortedSet[E]) InsertAll(elems ...E) {
+	if len(elems) == 0 {
+		return
+	}
+	s.elems = SortAndDeduplicate(append(Clone(s.elems), elems...), s.lt)
+}
+
+// Remove 删除与 e 相等的元素，不存在时返回 false。
+func (s *SortedSet[E]) Remove(e E) bool {
+	i, ok := s.search(e)
+	if ok {
+		s.elems = Delete(s.elems, i)
+	}
+	return ok
+}
+
+// At 返回第 i 小的元素（从 0 开始），i 超出范围时 panic。
+func (s *SortedSet[E]) At(i int) E {
+	if err := checkIndex("At", i, len(s.elems)); err != nil {
+		panic(err)
+	}
+	return s.elems[i]
+}
+
+// Rank 返回小于 e 的元素的个数。e 在集合中时就是 e 的下标，At(Rank(e)) 与 e 相等。
+func (s *SortedSet[E]) Rank(e E) int {
+	i, _ := s.search(e)
+	return i
+}
+
+// Min 返回最小的元素，集合为空时返回 false。
+func (s *SortedSet[E]) Min() (E, bool) {
+	return At(s.elems, 0)
+}
+
+// Max 返回最大的元素，集合为空时返回 false。
+func (s *SortedSet[E]) Max() (E, bool) {
+	return At(s.elems, len(s.elems)-1)
+}
+
+// Ceiling 返回不小于 e 的最小元素，不存在时返回 false。
+func (s *SortedSet[E]) Ceiling(e E) (E, bool) {
+	return At(s.elems, s.Rank(e))
+}
+
+// Floor 返回不大于 e 的最大元素，不存在时返回 false。
+func (s *SortedSet[E]) Floor(e E) (E, bool) {
+	i, ok := s.search(e)
+	if ok {
+		return s.elems[i], true
+	}
+	return At(s.elems, i-1)
+}
+
+// Range 按顺序产生 [lo, hi) 中的元素。
+func (s *SortedSet[E]) Range(lo, hi E) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		i := s.Rank(lo)
+		for _, e := range s.elems[i:max(s.Rank(hi), i)] {
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// Count 返回 [lo, hi) 中元素的个数。
+func (s *SortedSet[E]) Count(lo, hi E) int {
+	return max(s.Rank(hi)-s.Rank(lo), 0)
+}
+
+// All 按顺序产生所有元素。
+func (s *SortedSet[E]) All() iter.Seq[E] {
+	return func(yield func(E) bool) {
+		for _, e := range s.elems {
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// Slice 返回包含所有元素的新切片。
+func (s *SortedSet[E]) Slice() []E {
+	return Clone(s.elems)
+}
+
+// merge 归并 s 和 o 的元素，onlyS、onlyO 和 both 分别决定只在 s 中、只在 o 中和两边都有的元素是否保留。
+// 两边都有时保留 s 中的元素。o 必须与 s 使用相同的顺序。
+func (s *SortedSet[E]) merge(o *SortedSet[E], onlyS, onlyO, both bool) *SortedSet[E] {
+	a, b := s.elems, o.elems
+	ret := make([]E, 0, max(len(a), len(b)))
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case s.lt(a[0], b[0]):
+			if onlyS {
+				ret = append(ret, a[0])
+			}
+			a = a[1:]
+		case s.lt(b[0], a[0]):
+			if onlyO {
+				ret = append(ret, b[0])
+			}
+			b = b[1:]
+		default:
+			if both {
+				ret = append(ret, a[0])
+			}
+			a, b = a[1:], b[1:]
+		}
+	}
+	if onlyS {
+		ret = append(ret, a...)
+	}
+	if onlyO {
+		ret = append(ret, b...)
+	}
+	return &SortedSet[E]{elems: ret, lt: s.lt}
+}
+
+// Union 返回 s 和 o 的并集，o 必须与 s 使用相同的顺序。
+func (s *SortedSet[E]) Union(o *SortedSet[E]) *SortedSet[E] {
+	return s.merge(o, true, true, true)
+}
+
+// Intersect 返回 s 和 o 的交集，o 必须与 s 使用相同的顺序。
+func (s *SortedSet[E]) Intersect(o *SortedSet[E]) *SortedSet[E] {
+	return s.merge(o, false, false, true)
+}
+
+// Difference 返回在 s 中但不在 o 中的元素，o 必须与 s 使用相同的顺序。
+func (s *SortedSet[E]) Difference(o *SortedSet[E]) *SortedSet[E] {
+	return s.merge(o, true, false, false)
+}
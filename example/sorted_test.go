@@ -0,0 +1,238 @@
+package example
+
+import (
+	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSortAndDeduplicateEmpty(t *testing.T) {
+	lt := func(a, b int) bool { return a < b }
+	if got := SortAndDeduplicate([]int(nil), lt); len(got) != 0 {
+		t.Errorf("want empty result, but %v", got)
+	}
+	if got := SortAndDeduplicate([]int{3, 1, 3, 2, 1}, lt); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("want [1 2 3], but %v", got)
+	}
+}
+
+func TestSortedSet(t *testing.T) {
+	rnd := rand.New(rand.NewSource(4))
+	s := NewSortedSet[int]()
+	want := map[int]bool{}
+	check := func(desc string) {
+		t.Helper()
+		keys := slices.Sorted(maps.Keys(want))
+		if got := s.Slice(); !slices.Equal(got, keys) {
+			t.Fatalf("%s: want %v, but %v", desc, keys, got)
+		}
+	}
+	for step := range 2000 {
+		e := rnd.Intn(200)
+		var desc string
+		switch op := rnd.Intn(4); op {
+		case 0:
+			desc = fmt.Sprint("Insert ", e)
+			if s.Insert(e) == want[e] {
+				t.Fatalf("%s: wrong result", desc)
+			}
+			want[e] = true
+		case 1:
+			desc = fmt.Sprint("Remove ", e)
+			if s.Remove(e) != want[e] {
+				t.Fatalf("%s: wrong result", desc)
+			}
+			delete(want, e)
+		case 2:
+			desc = "InsertAll"
+			elems := make([]int, rnd.Intn(10))
+			for i := range elems {
+				elems[i] = rnd.Intn(200)
+				want[elems[i]] = true
+			}
+			s.InsertAll(elems...)
+		case 3:
+			desc = fmt.Sprint("Rank ", e)
+			rank := 0
+			for k := range want {
+				if k < e {
+					rank++
+				}
+			}
+			if s.Rank(e) != rank || s.Contains(e) != want[e] {
+				t.Fatalf("%s: want %d %v, but %d %v", desc, rank, want[e], s.Rank(e), s.Contains(e))
+			}
+			if want[e] && s.At(rank) != e {
+				t.Fatalf("%s: At(Rank) = %d", desc, s.At(rank))
+			}
+		}
+		if step%100 == 0 {
+			check(desc)
+		}
+	}
+	check("final")
+}
+
+func TestSortedSetQueries(t *testing.T) {
+	s := NewSortedSet(5, 1, 9, 3, 7, 3)
+	if got := slices.Collect(s.Range(3, 8)); !slices.Equal(got, []int{3, 5, 7}) {
+		t.Errorf("Range: got %v", got)
+	}
+	if got := slices.Collect(s.Range(8, 3)); got != nil || s.Count(8, 3) != 0 || s.Count(0, 100) != 5 {
+		t.Errorf("Range: want nothing for an empty range, but %v", got)
+	}
+	if e, ok := s.Floor(6); !ok || e != 5 {
+		t.Errorf("Floor(6): got %d %v", e, ok)
+	}
+	if e, ok := s.Ceiling(6); !ok || e != 7 {
+		t.Errorf("Ceiling(6): got %d %v", e, ok)
+	}
+	if _, ok := s.Floor(0); ok {
+		t.Error("Floor(0): want false")
+	}
+	if _, ok := s.Ceiling(10); ok {
+		t.Error("Ceiling(10): want false")
+	}
+	if lo, _ := s.Min(); lo != 1 {
+		t.Errorf("Min: got %d", lo)
+	}
+	if hi, _ := s.Max(); hi != 9 {
+		t.Errorf("Max: got %d", hi)
+	}
+
+	o := NewSortedSet(2, 3, 4, 5)
+	for _, tc := range []struct {
+		name string
+		got  *SortedSet[int]
+		want []int
+	}{
+		{"Union", s.Union(o), []int{1, 2, 3, 4, 5, 7, 9}},
+		{"Intersect", s.Intersect(o), []int{3, 5}},
+		{"Difference", s.Difference(o), []int{1, 7, 9}},
+		{"Difference", o.Difference(s), []int{2, 4}},
+	} {
+		if got := tc.got.Slice(); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: want %v, but %v", tc.name, tc.want, got)
+		}
+	}
+
+	// 自定义的顺序：忽略大小写
+	ci := NewSortedSetFunc(func(a, b string) bool { return strings.ToLower(a) < strings.ToLower(b) }, "b", "A", "a", "C")
+	if ci.Len() != 3 || !ci.Contains("B") || ci.Insert("c") {
+		t.Errorf("case insensitive set: %v", ci.Slice())
+	}
+}
+
+func TestSortedMultiMap(t *testing.T) {
+	rnd := rand.New(rand.NewSource(5))
+	m := NewSortedMultiMap[int, int]()
+	want := map[int][]int{}
+	check := func(desc string) {
+		t.Helper()
+		var wantKeys []int
+		n := 0
+		for _, k := range slices.Sorted(maps.Keys(want)) {
+			if len(want[k]) > 0 {
+				wantKeys = append(wantKeys, k)
+			}
+			if got := m.Get(k); !slices.Equal(got, want[k]) {
+				t.Fatalf("%s: Get(%d) want %v, but %v", desc, k, want[k], got)
+			}
+			if m.Rank(k) != n {
+				t.Fatalf("%s: Rank(%d) want %d, but %d", desc, k, n, m.Rank(k))
+			}
+			n += len(want[k])
+		}
+		if got := slices.Collect(m.Keys()); !slices.Equal(got, wantKeys) || m.Len() != n {
+			t.Fatalf("%s: Keys want %v, but %v", desc, wantKeys, got)
+		}
+	}
+	for step := range 2000 {
+		k, v := rnd.Intn(50), step
+		var desc string
+		switch op := rnd.Intn(5); op {
+		case 0, 1:
+			desc = fmt.Sprintf("Insert(%d, %d)", k, v)
+			m.Insert(k, v)
+			want[k] = append(want[k], v)
+		case 2:
+			desc = fmt.Sprintf("Remove(%d)", k)
+			if n := m.Remove(k); n != len(want[k]) {
+				t.Fatalf("%s: want %d, but %d", desc, len(want[k]), n)
+			}
+			delete(want, k)
+		case 3:
+			desc = fmt.Sprintf("RemoveFunc(%d)", k)
+			odd := func(v int) bool { return v%2 == 1 }
+			n := len(want[k])
+			want[k] = slices.DeleteFunc(want[k], odd)
+			if got := m.RemoveFunc(k, odd); got != n-len(want[k]) {
+				t.Fatalf("%s: want %d, but %d", desc, n-len(want[k]), got)
+			}
+		case 4:
+			desc = "InsertAll"
+			pairs := make([][2]int, rnd.Intn(10))
+			for i := range pairs {
+				pairs[i] = [2]int{rnd.Intn(50), -i}
+				want[pairs[i][0]] = append(want[pairs[i][0]], -i)
+			}
+			m.InsertAll(func(yield func(int, int) bool) {
+				for _, p := range pairs {
+					if !yield(p[0], p[1]) {
+						return
+					}
+				}
+			})
+		}
+		if step%50 == 0 {
+			check(desc)
+		}
+	}
+	check("final")
+}
+
+func TestSortedMultiMapSetOps(t *testing.T) {
+	build := func(pairs ...string) *SortedMultiMap[string, string] {
+		m := NewSortedMultiMap[string, string]()
+		for _, p := range pairs {
+			k, v, _ := strings.Cut(p, "=")
+			m.Insert(k, v)
+		}
+		return m
+	}
+	dump := func(m *SortedMultiMap[string, string]) string {
+		var b strings.Builder
+		for k, v := range m.All() {
+			fmt.Fprintf(&b, "%s=%s ", k, v)
+		}
+		return strings.TrimSpace(b.String())
+	}
+	a := build("x=1", "y=2", "x=3", "z=4")
+	b := build("y=5", "w=6", "x=7")
+	for _, tc := range []struct {
+		name string
+		got  *SortedMultiMap[string, string]
+		want string
+	}{
+		{"Union", a.Union(b), "w=6 x=1 x=3 x=7 y=2 y=5 z=4"},
+		{"Intersect", a.Intersect(b), "x=1 x=3 x=7 y=2 y=5"},
+		{"Difference", a.Difference(b), "z=4"},
+	} {
+		if got := dump(tc.got); got != tc.want {
+			t.Errorf("%s: want %q, but %q", tc.name, tc.want, got)
+		}
+	}
+	var got []string
+	for k, v := range a.Range("x", "z") {
+		got = append(got, k+v)
+	}
+	if !slices.Equal(got, []string{"x1", "x3", "y2"}) {
+		t.Errorf("Range: got %v", got)
+	}
+	if k, v := a.At(1); k != "x" || v != "3" || a.Count("x") != 2 || a.Contains("w") {
+		t.Errorf("At(1): got %s=%s", k, v)
+	}
+}
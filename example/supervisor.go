@@ -0,0 +1,279 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Queue 是可以被 Supervisor 管理的请求队列，Poller 和 ChanPoller 都实现了它。
+type Queue interface {
+	// Len 返回队列中尚未处理完毕的 Request 个数。
+	Len() int
+	// Poll 在当前 goroutine 中不断处理队列中的 Request，直到 ctx 结束。队列为空时
+	// Poll 应当阻塞而不是忙等，Supervisor 会让 MinWorkers 个 worker 一直调用它。
+	Poll(ctx context.Context, action Action)
+	// Close 停止接收新的 Request，已在队列中的 Request 仍会被处理。
+	Close()
+}
+
+var (
+	_ Queue = (*Poller)(nil)
+	_ Queue = (*ChanPoller)(nil)
+)
+
+// PanicError 是 action 发生 panic 时 Supervisor 返回给队列的 error，
+// 队列会像对待其他 error 一样重试或将 Request 放入死信队列。
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("action panicked: %v", e.Value)
+}
+
+// SupervisorConfig 是 NewSupervisor 的参数，零值字段使用默认值。
+type SupervisorConfig struct {
+	MinWorkers int // 默认 1
+	MaxWorkers int // 默认 MinWorkers
+	// Interval 是检查是否需要扩缩容的周期，默认 100ms。
+	Interval time.Duration
+	// 平均每个 worker 的排队数超过 ScaleUpDepth 时扩容，默认 1。
+	ScaleUpDepth int
+	// 队列非空且上个周期的平均处理耗时超过 TargetLatency 时扩容，0 表示不考虑耗时。
+	TargetLatency time.Duration
+	// 队列连续 IdleRounds 个周期为空时缩容一个 worker，默认 3。
+	IdleRounds int
+}
+
+// SupervisorStats 是 Supervisor 某一时刻的统计信息。
+type SupervisorStats struct {
+	QueueLen  int
+	Workers   int
+	InFlight  int
+	Processed uint64 // action 返回的总次数，包括失败和 panic
+	Failed    uint64 // action 返回 error 或 panic 的总次数
+	Panics    uint64 // action 发生 panic 的总次数
+	Restarts  uint64 // worker 因 Poll 本身 panic 而被重启的总次数
+	// 上一个检查周期内每秒完成的 action 数和 action 的平均耗时。
+	Throughput float64
+	AvgLatency time.Duration
+}
+
+// Supervisor 管理一组对同一个 Queue 调用 Poll 的 worker goroutine。
+// 它会根据排队数和处理耗时在 MinWorkers 和 MaxWorkers 之间调整 worker 数量，
+// 重启因 panic 退出的 worker，并支持优雅退出。
+type Supervisor struct {
+	q      Queue
+	action Action
+	cfg    SupervisorConfig
+
+	mu      sync.Mutex
+	ctx     context.Context
+	workers []context.CancelFunc
+	wg      sync.WaitGroup
+	stop    context.CancelFunc
+	ctlDone chan struct{}
+	stats   SupervisorStats
+
+	inFlight  atomic.Int64
+	processed atomic.Uint64
+	failed    atomic.Uint64
+	panics    atomic.Uint64
+	restarts  atomic.Uint64
+	latency   atomic.Int64 // action 耗时的纳秒数之和
+}
+
+func NewSupervisor(q Queue, action Action, cfg SupervisorConfig) *Supervisor {
+	cfg.MinWorkers = max(cfg.MinWorkers, 1)
+	cfg.MaxWorkers = max(cfg.MaxWorkers, cfg.MinWorkers)
+	if cfg.Interval <= 0 {
+		cfg.Interval = 100 * time.Millisecond
+	}
+	if cfg.ScaleUpDepth <= 0 {
+		cfg.ScaleUpDepth = 1
+	}
+	if cfg.IdleRounds <= 0 {
+		cfg.IdleRounds = 3
+	}
+	return &Supervisor{q: q, action: action, cfg: cfg}
+}
+
+// Start 启动 MinWorkers 个 worker 和负责扩缩容的 goroutine。
+// ctx 结束时所有 worker 立即停止，不会等待队列中的 Request 处理完毕。
+func (s *Supervisor) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx, s.stop = context.WithCancel(ctx)
+	s.ctlDone = make(chan struct{})
+	for range s.cfg.MinWorkers {
+		s.addWorker()
+	}
+	s.stats.Workers = len(s.workers)
+	go s.control()
+}
+
+// Shutdown 停止接收新的 Request，等待队列清空且正在处理的 action 全部返回，然后停止
+// 所有 worker。若 ctx 先结束，则立即停止所有 worker 并返回 ctx.Err()。
+// 没有调用过 Start 时只关闭 Queue，队列中的 Request 不会被处理。
+func (s *Supervisor) Shutdown(ctx context.Context) error {
+	s.q.Close()
+	s.mu.Lock()
+	stop, ctlDone := s.stop, s.ctlDone
+	s.mu.Unlock()
+	if stop == nil {
+		return nil
+	}
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	var err error
+	for err == nil && (s.q.Len() > 0 || s.inFlight.Load() > 0) {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	stop()
+	<-ctlDone
+	s.mu.Lock()
+	for _, cancel := range s.workers {
+		cancel()
+	}
+	s.workers = nil
+	s.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	return err
+}
+
+// Stats 返回当前的统计信息。
+func (s *Supervisor) Stats() SupervisorStats {
+	s.mu.Lock()
+	stats := s.stats
+	stats.Workers = len(s.workers)
+	s.mu.Unlock()
+	stats.QueueLen = s.q.Len()
+	stats.InFlight = int(s.inFlight.Load())
+	stats.Processed = s.processed.Load()
+	stats.Failed = s.failed.Load()
+	stats.Panics = s.panics.Load()
+	stats.Restarts = s.restarts.Load()
+	return stats
+}
+
+// addWorker 需要持有 s.mu。
+func (s *Supervisor) addWorker() {
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.workers = append(s.workers, cancel)
+	s.wg.Add(1)
+	go s.work(ctx)
+}
+
+// removeWorker 需要持有 s.mu。被停止的 worker 会处理完当前的 action 后再退出。
+func (s *Supervisor) removeWorker() {
+	var cancel context.CancelFunc
+	s.workers, cancel = PopBack(s.workers)
+	cancel()
+}
+
+func (s *Supervisor) work(ctx context.Context) {
+	defer s.wg.Done()
+	for ctx.Err() == nil {
+		if !s.poll(ctx) {
+			return
+		}
+		s.restarts.Add(1)
+	}
+}
+
+// poll 返回 true 表示 Poll 因 panic 退出，需要重启。
+func (s *Supervisor) poll(ctx context.Context) (panicked bool) {
+	defer func() {
+		if recover() != nil {
+			panicked = true
+		}
+	}()
+	s.q.Poll(ctx, s.run)
+	return false
+}
+
+// run 包装 action，统计耗时并将 panic 转换为 *PanicError。
+func (s *Supervisor) run(req *Request) (err error) {
+	s.inFlight.Add(1)
+	start := time.Now()
+	defer func() {
+		if v := recover(); v != nil {
+			s.panics.Add(1)
+			err = &PanicError{v, debug.Stack()}
+		}
+		if err != nil {
+			s.failed.Add(1)
+		}
+		s.latency.Add(int64(time.Since(start)))
+		s.processed.Add(1)
+		s.inFlight.Add(-1)
+	}()
+	return s.action(req)
+}
+
+func (s *Supervisor) control() {
+	defer close(s.ctlDone)
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	lastTime := time.Now()
+	var lastProcessed uint64
+	var lastLatency int64
+	idle := 0
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			processed, latency := s.processed.Load(), s.latency.Load()
+			n := processed - lastProcessed
+			var avg time.Duration
+			if n > 0 {
+				avg = time.Duration(uint64(latency-lastLatency) / n)
+			}
+			throughput := float64(n) / now.Sub(lastTime).Seconds()
+			lastTime, lastProcessed, lastLatency = now, processed, latency
+
+			queueLen := s.q.Len()
+			if queueLen == 0 {
+				idle++
+			} else {
+				idle = 0
+			}
+			s.mu.Lock()
+			s.stats.Throughput, s.stats.AvgLatency = throughput, avg
+			workers := len(s.workers)
+			switch {
+			case queueLen > workers*s.cfg.ScaleUpDepth,
+				queueLen > 0 && s.cfg.TargetLatency > 0 && avg > s.cfg.TargetLatency:
+				want := min(max(workers+1, (queueLen+s.cfg.ScaleUpDepth-1)/s.cfg.ScaleUpDepth), s.cfg.MaxWorkers)
+				for range want - workers {
+					s.addWorker()
+				}
+			case idle >= s.cfg.IdleRounds && workers > s.cfg.MinWorkers:
+				s.removeWorker()
+				idle = 0
+			}
+			s.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package example
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSupervisorScaleAndDrain(t *testing.T) {
+	const REQUEST_NUM = 200
+	cp := NewChanPoller(REQUEST_NUM)
+	var sum atomic.Int64
+	sup := NewSupervisor(cp, func(r *Request) error {
+		time.Sleep(time.Millisecond)
+		i, err := strconv.Atoi(string(*r))
+		if err != nil {
+			return err
+		}
+		sum.Add(int64(i))
+		return nil
+	}, SupervisorConfig{MinWorkers: 1, MaxWorkers: 8, Interval: 5 * time.Millisecond, IdleRounds: 2})
+	sup.Start(context.Background())
+	req := Request("1")
+	futures := make([]*Future, REQUEST_NUM)
+	for i := range futures {
+		f, err := cp.Submit(context.Background(), &req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		futures[i] = f
+	}
+	waitFor(t, "scaling up", func() bool { return sup.Stats().Workers == 8 })
+	if err := sup.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sum.Load() != REQUEST_NUM {
+		t.Errorf("want: %d, but: %d", REQUEST_NUM, sum.Load())
+	}
+	for _, f := range futures {
+		if f.Err() != nil {
+			t.Fatal(f.Err())
+		}
+	}
+	stats := sup.Stats()
+	if stats.Processed != REQUEST_NUM || stats.QueueLen != 0 || stats.InFlight != 0 || stats.Workers != 0 {
+		t.Errorf("unexpected stats after shutdown: %+v", stats)
+	}
+	if _, err := cp.Submit(context.Background(), &req); err != ErrQueueClosed {
+		t.Errorf("submit after shutdown: want ErrQueueClosed, but %v", err)
+	}
+}
+
+func TestSupervisorScaleDown(t *testing.T) {
+	plr := new(Poller)
+	sup := NewSupervisor(plr, func(*Request) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	}, SupervisorConfig{MinWorkers: 2, MaxWorkers: 16, Interval: 5 * time.Millisecond, ScaleUpDepth: 4, IdleRounds: 1})
+	sup.Start(context.Background())
+	req := Request("1")
+	for range BUFFER_SIZE {
+		if !plr.TryAddRequest(&req) {
+			t.Fatal("TryAddRequest failed")
+		}
+	}
+	waitFor(t, "scaling up", func() bool {
+		stats := sup.Stats()
+		return stats.Workers > 2 && stats.Throughput > 0 && stats.AvgLatency >= time.Millisecond
+	})
+	waitFor(t, "scaling down", func() bool { return sup.Stats().Workers == 2 })
+	if err := sup.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if plr.TryAddRequest(&req) {
+		t.Error("TryAddRequest should fail after shutdown")
+	}
+	if stats := sup.Stats(); stats.Processed != BUFFER_SIZE {
+		t.Errorf("unexpected stats after shutdown: %+v", stats)
+	}
+}
+
+func TestSupervisorActionPanic(t *testing.T) {
+	cp := NewChanPoller(BUFFER_SIZE)
+	cp.Retry = RetryPolicy{MaxAttempts: 2}
+	var calls atomic.Int64
+	sup := NewSupervisor(cp, func(r *Request) error {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		return nil
+	}, SupervisorConfig{})
+	sup.Start(context.Background())
+	req := Request("1")
+	f, err := cp.Submit(context.Background(), &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Wait(context.Background()); err != nil {
+		t.Errorf("want success after retry, but %v", err)
+	}
+	if err := sup.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if stats := sup.Stats(); stats.Panics != 1 || stats.Failed != 1 || stats.Processed != 2 || stats.Restarts != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+// panickyQueue 的 Poll 在前 n 次调用时直接 panic。
+type panickyQueue struct {
+	*ChanPoller
+	n atomic.Int64
+}
+
+func (q *panickyQueue) Poll(ctx context.Context, action Action) {
+	if q.n.Add(-1) >= 0 {
+		panic("poll")
+	}
+	q.ChanPoller.Poll(ctx, action)
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	q := &panickyQueue{ChanPoller: NewChanPoller(BUFFER_SIZE)}
+	q.n.Store(3)
+	sup := NewSupervisor(q, func(*Request) error { return nil }, SupervisorConfig{MinWorkers: 2})
+	sup.Start(context.Background())
+	req := Request("1")
+	f, err := q.Submit(context.Background(), &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := sup.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if stats := sup.Stats(); stats.Restarts != 3 {
+		t.Errorf("want 3 restarts, but %+v", stats)
+	}
+}
+
+func TestSupervisorShutdownDeadline(t *testing.T) {
+	cp := NewChanPoller(BUFFER_SIZE)
+	release := make(chan struct{})
+	sup := NewSupervisor(cp, func(*Request) error {
+		<-release
+		return nil
+	}, SupervisorConfig{})
+	sup.Start(context.Background())
+	req := Request("1")
+	if _, err := cp.Submit(context.Background(), &req); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "action start", func() bool { return sup.Stats().InFlight == 1 })
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelFunc()
+	if err := sup.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want DeadlineExceeded, but %v", err)
+	}
+	close(release)
+}
+
+func TestSupervisorShutdownWithoutStart(t *testing.T) {
+	cp := NewChanPoller(BUFFER_SIZE)
+	sup := NewSupervisor(cp, func(*Request) error { return nil }, SupervisorConfig{})
+	if err := sup.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	req := Request("late")
+	if _, err := cp.Submit(context.Background(), &req); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("want ErrQueueClosed after Shutdown, but %v", err)
+	}
+}
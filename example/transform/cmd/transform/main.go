@@ -0,0 +1,144 @@
+// transform 读取参数中的文件（没有参数时读取标准输入），依次进行 -subst、-case、
+// -caesar/-rot13、-vigenere 和 -eol 指定的变换后写到标准输出。
+//
+//	echo 'Hello, World!' | transform -rot13 -case upper
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example/transform"
+)
+
+var (
+	Subst    = flag.String("subst", "", `character substitutions, such as "ä=ae,ö=oe"`)
+	CaseMode = flag.String("case", "", "convert case: upper, lower or fold")
+	Caesar   = flag.Int("caesar", 0, "shift ASCII letters by n")
+	Rot13    = flag.Bool("rot13", false, "same as -caesar 13")
+	Vigenere = flag.String("vigenere", "", "Vigenere cipher key")
+	Decrypt  = flag.Bool("decrypt", false, "decrypt instead of encrypt for -caesar and -vigenere")
+	EOL      = flag.String("eol", "", "normalize line endings: lf or crlf")
+	Count    = flag.Bool("count", false, "print input and output byte counts to stderr")
+	Hash     = flag.String("hash", "", "print the hash of the output to stderr: md5, sha1, sha256 or crc32")
+)
+
+func buildChain() ([]transform.Transformer, error) {
+	var chain []transform.Transformer
+	if *Subst != "" {
+		table, err := transform.ParseSubstitutions(*Subst)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, transform.Substitute(table))
+	}
+	switch *CaseMode {
+	case "":
+	case "upper":
+		chain = append(chain, transform.Case(transform.Upper))
+	case "lower":
+		chain = append(chain, transform.Case(transform.Lower))
+	case "fold":
+		chain = append(chain, transform.Case(transform.Fold))
+	default:
+		return nil, fmt.Errorf("unknown case mode %q", *CaseMode)
+	}
+	shift := *Caesar
+	if *Rot13 {
+		shift = 13
+	}
+	if *Decrypt {
+		shift = -shift
+	}
+	if shift != 0 {
+		chain = append(chain, transform.Caesar(shift))
+	}
+	if *Vigenere != "" {
+		t, err := transform.Vigenere(*Vigenere, *Decrypt)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, t)
+	}
+	switch *EOL {
+	case "":
+	case "lf":
+		chain = append(chain, transform.NormalizeLineEndings(transform.LF))
+	case "crlf":
+		chain = append(chain, transform.NormalizeLineEndings(transform.CRLF))
+	default:
+		return nil, fmt.Errorf("unknown line ending %q", *EOL)
+	}
+	return chain, nil
+}
+
+func newHash(name string) (hash.Hash, error) {
+	switch name {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "crc32":
+		return crc32.NewIEEE(), nil
+	}
+	return nil, fmt.Errorf("unknown hash %q", name)
+}
+
+func run() error {
+	chain, err := buildChain()
+	if err != nil {
+		return err
+	}
+	var in io.Reader = os.Stdin
+	if flag.NArg() > 0 {
+		readers := make([]io.Reader, flag.NArg())
+		for i, name := range flag.Args() {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			readers[i] = f
+		}
+		in = io.MultiReader(readers...)
+	}
+	counted := &transform.CountingReader{R: in}
+	out := &transform.CountingWriter{W: os.Stdout}
+	var w io.Writer = out
+	var hashed transform.HashingWriter
+	if *Hash != "" {
+		h, err := newHash(*Hash)
+		if err != nil {
+			return err
+		}
+		hashed = transform.HashingWriter{W: out, H: h}
+		w = hashed
+	}
+	if _, err := io.Copy(w, transform.NewReader(counted, chain...)); err != nil {
+		return err
+	}
+	if *Count {
+		fmt.Fprintf(os.Stderr, "in: %d bytes, out: %d bytes\n", counted.N(), out.N())
+	}
+	if *Hash != "" {
+		fmt.Fprintf(os.Stderr, "%s: %x\n", *Hash, hashed.Sum())
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "transform:", err)
+		os.Exit(1)
+	}
+}
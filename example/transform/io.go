@@ -0,0 +1,87 @@
+package transform
+
+import (
+	"hash"
+	"io"
+	"sync/atomic"
+
+	xtransform "golang.org/x/text/transform"
+)
+
+// NewReader 返回依次应用 t 中各个变换的 Reader。
+func NewReader(r io.Reader, t ...Transformer) io.Reader {
+	return xtransform.NewReader(r, Chain(t...))
+}
+
+// NewWriter 返回依次应用 t 中各个变换后写入 w 的 Writer，
+// 必须调用 Close 才能写出缓冲区中剩余的数据，Close 不会关闭 w。
+func NewWriter(w io.Writer, t ...Transformer) io.WriteCloser {
+	return xtransform.NewWriter(w, Chain(t...))
+}
+
+// CountingReader 统计从 R 中读出的字节数。
+type CountingReader struct {
+	R io.Reader
+	n atomic.Int64
+}
+
+// Read 即使 R 同时返回了数据和错误，也会把数据计入。
+func (cr *CountingReader) Read(p []byte) (int, error) {
+	n, err := cr.R.Read(p)
+	cr.n.Add(int64(n))
+	return n, err
+}
+
+// N 返回目前为止读出的字节数，可以在其他 goroutine 中调用。
+func (cr *CountingReader) N() int64 { return cr.n.Load() }
+
+// CountingWriter 统计写入 W 的字节数，W 为 nil 时只计数。
+type CountingWriter struct {
+	W io.Writer
+	n atomic.Int64
+}
+
+func (cw *CountingWriter) Write(p []byte) (int, error) {
+	n, err := len(p), error(nil)
+	if cw.W != nil {
+		n, err = cw.W.Write(p)
+	}
+	cw.n.Add(int64(n))
+	return n, err
+}
+
+// N 返回目前为止写入的字节数，可以在其他 goroutine 中调用。
+func (cw *CountingWriter) N() int64 { return cw.n.Load() }
+
+// HashingReader 在读取的同时计算读出的数据的哈希值。
+type HashingReader struct {
+	R io.Reader
+	H hash.Hash
+}
+
+func (hr HashingReader) Read(p []byte) (int, error) {
+	n, err := hr.R.Read(p)
+	hr.H.Write(p[:n])
+	return n, err
+}
+
+// Sum 返回目前为止读出的数据的哈希值。
+func (hr HashingReader) Sum() []byte { return hr.H.Sum(nil) }
+
+// HashingWriter 在写入 W 的同时计算写入的数据的哈希值，W 为 nil 时只计算哈希值。
+type HashingWriter struct {
+	W io.Writer
+	H hash.Hash
+}
+
+func (hw HashingWriter) Write(p []byte) (int, error) {
+	n, err := len(p), error(nil)
+	if hw.W != nil {
+		n, err = hw.W.Write(p)
+	}
+	hw.H.Write(p[:n])
+	return n, err
+}
+
+// Sum 返回目前为止写入的数据的哈希值。
+func (hw HashingWriter) Sum() []byte { return hw.H.Sum(nil) }
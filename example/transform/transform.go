@@ -0,0 +1,218 @@
+// Package transform 提供可以组合的流式文本变换。每种变换都是一个
+// golang.org/x/text/transform.Transformer，NewReader 和 NewWriter 负责缓冲和
+// 处理跨越 Read、Write 边界的 UTF-8 字符，因此可以处理任意大小的输入。
+package transform
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	xtransform "golang.org/x/text/transform"
+)
+
+// Transformer 是 golang.org/x/text/transform.Transformer 的别名。
+type Transformer = xtransform.Transformer
+
+// Chain 按顺序组合多个 Transformer。
+func Chain(t ...Transformer) Transformer {
+	return xtransform.Chain(t...)
+}
+
+// byteMapper 是逐字节变换的 Transformer，输出与输入一样长。
+type byteMapper struct {
+	f   func(pos int, c byte) (byte, bool) // 第二个返回值表示是否推进 pos
+	pos int
+}
+
+func (bm *byteMapper) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	n := min(len(dst), len(src))
+	for i := range n {
+		c, advance := bm.f(bm.pos, src[i])
+		dst[i] = c
+		if advance {
+			bm.pos++
+		}
+	}
+	if n < len(src) {
+		err = xtransform.ErrShortDst
+	}
+	return n, n, err
+}
+
+func (bm *byteMapper) Reset() { bm.pos = 0 }
+
+// shiftLetter 将 ASCII 字母 c 在字母表中循环移动 shift 位，其他字节不变。
+func shiftLetter(c byte, shift int) (byte, bool) {
+	var base byte
+	switch {
+	case 'a' <= c && c <= 'z':
+		base = 'a'
+	case 'A' <= c && c <= 'Z':
+		base = 'A'
+	default:
+		return c, false
+	}
+	shift %= 26
+	if shift < 0 {
+		shift += 26
+	}
+	return base + byte((int(c-base)+shift)%26), true
+}
+
+// Caesar 将 ASCII 字母循环移动 shift 位，Caesar(13) 即 ROT13，Caesar(-n) 解密 Caesar(n)。
+func Caesar(shift int) Transformer {
+	return &byteMapper{f: func(_ int, c byte) (byte, bool) {
+		return shiftLetter(c, shift)
+	}}
+}
+
+// Vigenere 使用由 ASCII 字母组成的 key 进行维吉尼亚加密，decrypt 为 true 时解密。
+// 只有字母会消耗 key，其他字节原样输出。
+func Vigenere(key string, decrypt bool) (Transformer, error) {
+	shifts := make([]int, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		c := key[i] | 0x20 // 转为小写
+		if c < 'a' || c > 'z' {
+			return nil, fmt.Errorf("invalid Vigenere key %q", key)
+		}
+		shift := int(c - 'a')
+		if decrypt {
+			shift = -shift
+		}
+		shifts = append(shifts, shift)
+	}
+	if len(shifts) == 0 {
+		return nil, fmt.Errorf("empty Vigenere key")
+	}
+	return &byteMapper{f: func(pos int, c byte) (byte, bool) {
+		return shiftLetter(c, shifts[pos%len(shifts)])
+	}}, nil
+}
+
+// CaseMode 是 Case 的变换方式。
+type CaseMode int
+
+const (
+	Upper CaseMode = iota
+	Lower
+	// Fold 是用于不区分大小写比较的大小写折叠，如 "ß" 变为 "ss"。
+	Fold
+)
+
+// Case 返回按 Unicode 规则转换大小写的 Transformer。
+func Case(mode CaseMode) Transformer {
+	switch mode {
+	case Upper:
+		return cases.Upper(language.Und)
+	case Lower:
+		return cases.Lower(language.Und)
+	}
+	return cases.Fold()
+}
+
+// LineEnding 是换行符的风格。
+type LineEnding string
+
+const (
+	LF   LineEnding = "\n"
+	CRLF LineEnding = "\r\n"
+)
+
+// lineEndings 将 "\r\n"、"\r" 和 "\n" 统一为 eol。
+type lineEndings struct {
+	eol []byte
+}
+
+// NormalizeLineEndings 将 "\r\n"、单独的 "\r" 和 "\n" 统一为 eol。
+func NormalizeLineEndings(eol LineEnding) Transformer {
+	return lineEndings{[]byte(eol)}
+}
+
+func (le lineEndings) Reset() {}
+
+func (le lineEndings) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		i := bytes.IndexAny(src[nSrc:], "\r\n")
+		if i < 0 {
+			i = len(src) - nSrc
+		}
+		// 先复制换行符之前的部分
+		n := copy(dst[nDst:], src[nSrc:nSrc+i])
+		nDst, nSrc = nDst+n, nSrc+n
+		if n < i {
+			return nDst, nSrc, xtransform.ErrShortDst
+		}
+		if nSrc == len(src) {
+			break
+		}
+		width := 1
+		if src[nSrc] == '\r' {
+			if nSrc+1 == len(src) && !atEOF {
+				// 需要看到下一个字节才能确定是不是 "\r\n"
+				return nDst, nSrc, xtransform.ErrShortSrc
+			}
+			if nSrc+1 < len(src) && src[nSrc+1] == '\n' {
+				width = 2
+			}
+		}
+		if len(dst)-nDst < len(le.eol) {
+			return nDst, nSrc, xtransform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], le.eol)
+		nSrc += width
+	}
+	return nDst, nSrc, nil
+}
+
+// substitution 按 table 逐字符替换。
+type substitution struct {
+	table map[rune]string
+}
+
+// Substitute 将 table 中的字符替换为对应的字符串，其他字符不变。
+// 无效的 UTF-8 字节原样输出。
+func Substitute(table map[rune]string) Transformer {
+	return substitution{table}
+}
+
+func (s substitution) Reset() {}
+
+func (s substitution) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if r == utf8.RuneError && size == 1 && !atEOF && !utf8.FullRune(src[nSrc:]) {
+			// 不完整的 UTF-8 字符，等待更多输入
+			return nDst, nSrc, xtransform.ErrShortSrc
+		}
+		out := src[nSrc : nSrc+size]
+		if rep, ok := s.table[r]; ok && !(r == utf8.RuneError && size == 1) {
+			out = []byte(rep)
+		}
+		if len(dst)-nDst < len(out) {
+			return nDst, nSrc, xtransform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], out)
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
+
+// ParseSubstitutions 解析形如 "a=b,ä=ae" 的替换表，每一项的左边必须是一个字符。
+func ParseSubstitutions(s string) (map[rune]string, error) {
+	table := make(map[rune]string)
+	if s == "" {
+		return table, nil
+	}
+	for _, item := range bytes.Split([]byte(s), []byte(",")) {
+		from, to, ok := bytes.Cut(item, []byte("="))
+		if !ok || utf8.RuneCount(from) != 1 {
+			return nil, fmt.Errorf("invalid substitution %q", item)
+		}
+		r, _ := utf8.DecodeRune(from)
+		table[r] = string(to)
+	}
+	return table, nil
+}
@@ -0,0 +1,147 @@
+package transform
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// readAll 分别用一次读一个字节、数据和 EOF 一起返回两种方式读取，两者的结果必须相同。
+func readAll(t *testing.T, in string, chain ...Transformer) string {
+	t.Helper()
+	var results []string
+	for _, wrap := range []func(io.Reader) io.Reader{iotest.OneByteReader, iotest.DataErrReader, iotest.HalfReader} {
+		for _, tr := range chain {
+			tr.Reset()
+		}
+		b, err := io.ReadAll(NewReader(wrap(strings.NewReader(in)), chain...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, string(b))
+	}
+	for _, r := range results[1:] {
+		if r != results[0] {
+			t.Fatalf("results differ between readers: %q", results)
+		}
+	}
+	return results[0]
+}
+
+func TestCaesar(t *testing.T) {
+	if got := readAll(t, "Lbh penpxrq gur pbqr!", Caesar(13)); got != "You cracked the code!" {
+		t.Errorf("rot13: unexpected %q", got)
+	}
+	if got := readAll(t, "xyz ABC", Caesar(3)); got != "abc DEF" {
+		t.Errorf("caesar 3: unexpected %q", got)
+	}
+	if got := readAll(t, "abc DEF", Caesar(-29)); got != "xyz ABC" {
+		t.Errorf("caesar -29: unexpected %q", got)
+	}
+}
+
+func TestVigenere(t *testing.T) {
+	enc, err := Vigenere("LEMON", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const plain, cipher = "attack at dawn!", "lxfopv ef rnhr!"
+	if got := readAll(t, plain, enc); got != cipher {
+		t.Errorf("encrypt: want %q, but %q", cipher, got)
+	}
+	dec, _ := Vigenere("lemon", true)
+	if got := readAll(t, cipher, dec); got != plain {
+		t.Errorf("decrypt: want %q, but %q", plain, got)
+	}
+	for _, key := range []string{"", "ab1"} {
+		if _, err := Vigenere(key, false); err == nil {
+			t.Errorf("key %q: want error", key)
+		}
+	}
+}
+
+func TestCase(t *testing.T) {
+	testcases := []struct {
+		mode    CaseMode
+		in, out string
+	}{
+		{Upper, "héllo wörld", "HÉLLO WÖRLD"},
+		{Lower, "ΑΒΓ Straße", "αβγ straße"},
+		{Fold, "Straße", "strasse"},
+	}
+	for _, tc := range testcases {
+		if got := readAll(t, tc.in, Case(tc.mode)); got != tc.out {
+			t.Errorf("mode %d: want %q, but %q", tc.mode, tc.out, got)
+		}
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	const in = "a\r\nb\rc\nd\r\r\ne\r"
+	if got := readAll(t, in, NormalizeLineEndings(LF)); got != "a\nb\nc\nd\n\ne\n" {
+		t.Errorf("lf: unexpected %q", got)
+	}
+	if got := readAll(t, in, NormalizeLineEndings(CRLF)); got != "a\r\nb\r\nc\r\nd\r\n\r\ne\r\n" {
+		t.Errorf("crlf: unexpected %q", got)
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	table, err := ParseSubstitutions("ä=ae,ö=oe,世=world,x=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, "Käse Öl schön 世界 xyz \xff", Substitute(table)); got != "Kaese Öl schoen world界 yz \xff" {
+		t.Errorf("unexpected %q", got)
+	}
+	if _, err := ParseSubstitutions("ab=c"); err == nil {
+		t.Error("want error for multi-character key")
+	}
+}
+
+func TestChain(t *testing.T) {
+	table, _ := ParseSubstitutions("ß=ss")
+	got := readAll(t, "Große\r\nStraße", Substitute(table), Case(Upper), Caesar(1), NormalizeLineEndings(LF))
+	if got != "HSPTTF\nTUSBTTF" {
+		t.Errorf("unexpected %q", got)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Case(Upper), NormalizeLineEndings(CRLF))
+	for _, s := range []string{"é", "\r", "\n", "\xc3", "\xa9\r"} {
+		if _, err := io.WriteString(w, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "É\r\nÉ\r\n" {
+		t.Errorf("unexpected %q", got)
+	}
+}
+
+func TestTaps(t *testing.T) {
+	const in = "hello, world"
+	cr := &CountingReader{R: iotest.DataErrReader(strings.NewReader(in))}
+	hr := HashingReader{R: cr, H: sha256.New()}
+	cw := &CountingWriter{}
+	hw := HashingWriter{W: cw, H: sha256.New()}
+	if _, err := io.Copy(hw, hr); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(in))
+	want := hex.EncodeToString(sum[:])
+	if cr.N() != int64(len(in)) || cw.N() != int64(len(in)) {
+		t.Errorf("unexpected counts %d %d", cr.N(), cw.N())
+	}
+	if hex.EncodeToString(hr.Sum()) != want || hex.EncodeToString(hw.Sum()) != want {
+		t.Errorf("unexpected hashes %x %x", hr.Sum(), hw.Sum())
+	}
+}
@@ -0,0 +1,294 @@
+package wordfreq
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example/ext_sort"
+)
+
+// EnglishStopwords 和 ChineseStopwords 是常见的停用词，可以合并后作为 Config.Stopwords。
+var (
+	EnglishStopwords = []string{"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "if", "in", "is", "it", "of", "on", "or", "that", "the", "this", "to", "was", "with"}
+	ChineseStopwords = []string{"的", "了", "是", "在", "和", "也", "就", "都", "而", "及", "与", "着", "或", "之", "这", "那", "我", "你", "他", "她", "它"}
+)
+
+// LoadStopwords 读取每行一个的停用词，忽略空行和以 # 开头的行。
+func LoadStopwords(r io.Reader) (map[string]bool, error) {
+	m := make(map[string]bool)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		w := strings.TrimSpace(sc.Text())
+		if w != "" && !strings.HasPrefix(w, "#") {
+			m[strings.ToLower(w)] = true
+		}
+	}
+	return m, sc.Err()
+}
+
+// Config 是 NewCounter 的参数，零值字段使用默认值。
+type Config struct {
+	// NGram 是统计的 n-gram 的长度，默认 1，即统计单个词。
+	// 中日韩文字之间直接相连，其他词之间用空格连接。
+	NGram int
+	// Stopwords 中的词不会被统计，也会打断 n-gram。其中的词需要是小写的，
+	// 即使 KeepCase 为 true，比较时也不区分大小写。
+	Stopwords map[string]bool
+	// KeepCase 为 true 时区分大小写。
+	KeepCase bool
+	// MaxMemoryTerms 是内存中最多保存的不同的词的个数，超过时写入临时文件，默认 1 << 20。
+	MaxMemoryTerms int
+	// MaxTermBytes 是写入临时文件的词的最大字节数，更长的词会被截断，默认 64。
+	MaxTermBytes int
+	// TempDir 是临时文件所在的目录，默认为 os.TempDir()。
+	TempDir string
+	// MergeFanIn 是对临时文件做外部排序时每次合并的分段数，也是同时在内存中的记录数，默认 16，
+	// 小于 2 时视为 2。
+	MergeFanIn int
+}
+
+// WordCount 是一个词和它出现的次数。
+type WordCount struct {
+	Term  string
+	Count int64
+}
+
+// Counter 统计词频，并发使用不安全。使用完毕后需要调用 Close 删除临时文件。
+type Counter struct {
+	cfg    Config
+	counts map[string]int64
+	spill  *os.File // 每条记录是 MaxTermBytes 字节的词（不足时补 0）和 8 字节的次数
+	total  int64
+}
+
+func NewCounter(cfg Config) *Counter {
+	cfg.NGram = max(cfg.NGram, 1)
+	if cfg.MaxMemoryTerms <= 0 {
+		cfg.MaxMemoryTerms = 1 << 20
+	}
+	if cfg.MaxTermBytes <= 0 {
+		cfg.MaxTermBytes = 64
+	}
+	if cfg.MergeFanIn <= 0 {
+		cfg.MergeFanIn = 16
+	}
+	return &Counter{cfg: cfg, counts: make(map[string]int64)}
+}
+
+// Total 返回统计过的 n-gram 的总数。
+func (c *Counter) Total() int64 {
+	return c.total
+}
+
+// Add 读取 r 直到 EOF 并统计其中的词。多次调用 Add 时，不同 Reader 之间的 n-gram 不会相连。
+func (c *Counter) Add(r io.Reader) error {
+	tz := NewTokenizer(r)
+	tz.Lower = !c.cfg.KeepCase
+	window := make([]Token, 0, c.cfg.NGram)
+	for {
+		tok, err := tz.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if tok.Break {
+			window = window[:0]
+		}
+		stop := tok.Text
+		if c.cfg.KeepCase {
+			stop = strings.ToLower(stop)
+		}
+		if c.cfg.Stopwords[stop] {
+			window = window[:0]
+			continue
+		}
+		if len(window) == c.cfg.NGram {
+			copy(window, window[1:])
+			window = window[:len(window)-1]
+		}
+		if window = append(window, tok); len(window) == c.cfg.NGram {
+			if err := c.inc(joinTokens(window)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func joinTokens(toks []Token) string {
+	if len(toks) == 1 {
+		return toks[0].Text
+	}
+	var sb strings.Builder
+	for i, t := range toks {
+		if i > 0 && !(t.CJK && toks[i-1].CJK) {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(t.Text)
+	}
+	return sb.String()
+}
+
+func (c *Counter) inc(term string) error {
+	c.total++
+	c.counts[term]++
+	if len(c.counts) > c.cfg.MaxMemoryTerms {
+		return c.flush()
+	}
+	return nil
+}
+
+func (c *Counter) recordSize() int {
+	return c.cfg.MaxTermBytes + 8
+}
+
+// flush 将内存中的统计结果追加到临时文件中。
+func (c *Counter) flush() error {
+	if len(c.counts) == 0 {
+		return nil
+	}
+	if c.spill == nil {
+		f, err := os.CreateTemp(c.cfg.TempDir, "wordfreq*")
+		if err != nil {
+			return err
+		}
+		c.spill = f
+	}
+	if _, err := c.spill.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	w := bufio.NewWriter(c.spill)
+	rec := make([]byte, c.recordSize())
+	for term, n := range c.counts {
+		clear(rec)
+		copy(rec, truncate(term, c.cfg.MaxTermBytes))
+		binary.BigEndian.PutUint64(rec[c.cfg.MaxTermBytes:], uint64(n))
+		if _, err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	clear(c.counts)
+	return w.Flush()
+}
+
+// truncate 在不超过 n 字节的最后一个完整的 UTF-8 字符处截断 s。
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Top 返回出现次数最多的 n 个词，次数相同时按字典序排列；n <= 0 时返回所有的词。
+func (c *Counter) Top(n int) ([]WordCount, error) {
+	top := newTopN(n)
+	if c.spill == nil {
+		for term, count := range c.counts {
+			top.offer(WordCount{term, count})
+		}
+		return top.sorted(), nil
+	}
+	if err := c.flush(); err != nil {
+		return nil, err
+	}
+	// 按词排序后相同的词相邻，合并它们的次数
+	size := c.recordSize()
+	lt := func(a, b []byte) bool {
+		return bytes.Compare(a[:c.cfg.MaxTermBytes], b[:c.cfg.MaxTermBytes]) < 0
+	}
+	if err := ext_sort.ExtMergeSortNWay(c.spill, size, lt, max(c.cfg.MergeFanIn, 2)); err != nil {
+		return nil, err
+	}
+	if _, err := c.spill.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(c.spill)
+	rec := make([]byte, size)
+	var cur WordCount
+	for {
+		if _, err := io.ReadFull(r, rec); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		term := string(bytes.TrimRight(rec[:c.cfg.MaxTermBytes], "\x00"))
+		count := int64(binary.BigEndian.Uint64(rec[c.cfg.MaxTermBytes:]))
+		if term != cur.Term && cur.Count > 0 {
+			top.offer(cur)
+			cur = WordCount{}
+		}
+		cur.Term = term
+		cur.Count += count
+	}
+	if cur.Count > 0 {
+		top.offer(cur)
+	}
+	return top.sorted(), nil
+}
+
+// Close 删除临时文件。
+func (c *Counter) Close() error {
+	if c.spill == nil {
+		return nil
+	}
+	err := errors.Join(c.spill.Close(), os.Remove(c.spill.Name()))
+	c.spill = nil
+	return err
+}
+
+// topN 用小顶堆保存出现次数最多的 n 个词。
+type topN struct {
+	n int
+	h wcHeap
+}
+
+func newTopN(n int) *topN {
+	return &topN{n: n}
+}
+
+// less 判断 a 是否排在 b 之后。
+func (wc WordCount) less(b WordCount) bool {
+	if wc.Count != b.Count {
+		return wc.Count < b.Count
+	}
+	return wc.Term > b.Term
+}
+
+func (t *topN) offer(wc WordCount) {
+	if t.n <= 0 || len(t.h) < t.n {
+		heap.Push(&t.h, wc)
+	} else if t.h[0].less(wc) {
+		t.h[0] = wc
+		heap.Fix(&t.h, 0)
+	}
+}
+
+func (t *topN) sorted() []WordCount {
+	ret := []WordCount(t.h)
+	sort.Slice(ret, func(i, j int) bool { return ret[j].less(ret[i]) })
+	return ret
+}
+
+type wcHeap []WordCount
+
+func (h wcHeap) Len() int           { return len(h) }
+func (h wcHeap) Less(i, j int) bool { return h[i].less(h[j]) }
+func (h wcHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *wcHeap) Push(x any)        { *h = append(*h, x.(WordCount)) }
+func (h *wcHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
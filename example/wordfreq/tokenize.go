@@ -0,0 +1,110 @@
+// Package wordfreq 是 examples5_map.go 中 wordCount 的流式版本，从 io.Reader 中读取
+// 文本，按 Unicode 规则分词并统计词频，中日韩文字按字切分，可以统计 n-gram。
+// 不同的词太多、内存放不下时，部分统计结果会被写到临时文件中，最后用 ext_sort 排序合并。
+package wordfreq
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Token 是 Tokenizer 切分出的一个词。
+type Token struct {
+	Text string
+	// CJK 表示 Text 是一个中日韩文字。
+	CJK bool
+	// Break 表示这个词与上一个词之间有标点等分隔，n-gram 不会跨越分隔。
+	Break bool
+}
+
+// Tokenizer 从 io.Reader 中流式地切分词语。字母、数字和组合字符组成的连续序列是一个词，
+// 词中间的 ' 和 ’ 被保留（如 "don't"）；中日韩文字每个字是一个词；其他字符都是分隔符，
+// 其中空白以外的字符会打断 n-gram。
+type Tokenizer struct {
+	r *bufio.Reader
+	// 上一个词之后是否遇到了打断 n-gram 的字符
+	brk bool
+	// Lower 为 true 时词语会被转为小写。
+	Lower bool
+}
+
+func NewTokenizer(r io.Reader) *Tokenizer {
+	return &Tokenizer{r: bufio.NewReader(r), Lower: true}
+}
+
+// IsCJK 判断 r 是否是中日韩文字（汉字、假名和谚文）。
+func IsCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
+
+// Next 返回下一个词，没有更多的词时返回 io.EOF。
+func (t *Tokenizer) Next() (Token, error) {
+	var sb strings.Builder
+	for {
+		r, _, err := t.r.ReadRune()
+		if errors.Is(err, io.EOF) {
+			if sb.Len() > 0 {
+				return t.token(sb.String(), false), nil
+			}
+			return Token{}, io.EOF
+		} else if err != nil {
+			return Token{}, err
+		}
+		switch {
+		case IsCJK(r):
+			if sb.Len() > 0 {
+				t.r.UnreadRune()
+				return t.token(sb.String(), false), nil
+			}
+			return t.token(string(r), true), nil
+		case isWordRune(r):
+			sb.WriteRune(r)
+		case isApostrophe(r) && sb.Len() > 0:
+			// 只有后面紧跟字母时才是词的一部分
+			next, _, err := t.r.ReadRune()
+			if err == nil {
+				t.r.UnreadRune()
+			}
+			if err == nil && isWordRune(next) && !IsCJK(next) {
+				sb.WriteRune(r)
+				continue
+			}
+			tok := t.token(sb.String(), false)
+			t.brk = true
+			return tok, nil
+		default:
+			var tok Token
+			ok := sb.Len() > 0
+			if ok {
+				tok = t.token(sb.String(), false)
+			}
+			// 分隔符属于这个词之后
+			if !unicode.IsSpace(r) {
+				t.brk = true
+			}
+			if ok {
+				return tok, nil
+			}
+		}
+	}
+}
+
+func (t *Tokenizer) token(text string, cjk bool) Token {
+	if t.Lower {
+		text = strings.ToLower(text)
+	}
+	tok := Token{Text: text, CJK: cjk, Break: t.brk}
+	t.brk = false
+	return tok
+}
@@ -0,0 +1,151 @@
+package wordfreq
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func tokens(t *testing.T, s string) []string {
+	t.Helper()
+	tz := NewTokenizer(iotest.OneByteReader(strings.NewReader(s)))
+	var ret []string
+	for {
+		tok, err := tz.Next()
+		if errors.Is(err, io.EOF) {
+			return ret
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		text := tok.Text
+		if tok.Break {
+			text = "|" + text
+		}
+		ret = append(ret, text)
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want []string
+	}{
+		{"I ate a donut. Then I ate another donut.", []string{"i", "ate", "a", "donut", "|then", "i", "ate", "another", "donut"}},
+		{"Don't stop 'quoted' rock’n’roll 3.14", []string{"don't", "stop", "|quoted", "|rock’n’roll", "3", "|14"}},
+		{"我爱Go语言，真的！", []string{"我", "爱", "go", "语", "言", "|真", "的"}},
+		{"カタカナ 한국어 naïve café", []string{"カ", "タ", "カ", "ナ", "한", "국", "어", "naïve", "café"}},
+		{"  \n\t ", nil},
+	}
+	for _, tc := range testcases {
+		if got := tokens(t, tc.in); strings.Join(got, " ") != strings.Join(tc.want, " ") {
+			t.Errorf("%q: want %q, but %q", tc.in, tc.want, got)
+		}
+	}
+}
+
+func top(t *testing.T, c *Counter, n int) string {
+	t.Helper()
+	wcs, err := c.Top(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	for _, wc := range wcs {
+		fmt.Fprintf(&sb, "%s:%d ", wc.Term, wc.Count)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func TestCounter(t *testing.T) {
+	c := NewCounter(Config{})
+	defer c.Close()
+	if err := c.Add(strings.NewReader("I ate a donut. Then I ate another donut.")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := top(t, c, 3), "ate:2 donut:2 i:2"; got != want {
+		t.Errorf("want %q, but %q", want, got)
+	}
+	if c.Total() != 9 {
+		t.Errorf("want total 9, but %d", c.Total())
+	}
+}
+
+func TestCounterNGramStopwords(t *testing.T) {
+	stop, err := LoadStopwords(strings.NewReader("# comment\n的\n\nThe\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCounter(Config{NGram: 2, Stopwords: stop})
+	defer c.Close()
+	c.Add(strings.NewReader("自然语言的处理，自然语言。The big cat and the big cat"))
+	want := "big cat:2 然语:2 自然:2 语言:2 cat and:1 处理:1"
+	if got := top(t, c, 0); got != want {
+		t.Errorf("want %q, but %q", want, got)
+	}
+}
+
+func TestCounterKeepCaseStopwords(t *testing.T) {
+	stop := make(map[string]bool)
+	for _, w := range EnglishStopwords {
+		stop[w] = true
+	}
+	c := NewCounter(Config{Stopwords: stop, KeepCase: true})
+	defer c.Close()
+	c.Add(strings.NewReader("The Cat and the cat And THE CAT"))
+	want := "CAT:1 Cat:1 cat:1"
+	if got := top(t, c, 0); got != want {
+		t.Errorf("want %q, but %q", want, got)
+	}
+}
+
+func TestCounterSpill(t *testing.T) {
+	var sb strings.Builder
+	rnd := rand.New(rand.NewSource(1))
+	want := make(map[string]int64)
+	for range 5000 {
+		// a skewed distribution so that the top terms are well separated
+		w := fmt.Sprint("w", int(rnd.ExpFloat64()*20))
+		want[w]++
+		sb.WriteString(w + " ")
+	}
+	long := "a" + strings.Repeat("é", 40) // 81 bytes, truncated to 63 bytes in the spill file
+	sb.WriteString(long)
+
+	inMemory := NewCounter(Config{})
+	defer inMemory.Close()
+	spilled := NewCounter(Config{MaxMemoryTerms: 7, MaxTermBytes: 64, MergeFanIn: 3, TempDir: t.TempDir()})
+	defer spilled.Close()
+	for _, c := range []*Counter{inMemory, spilled} {
+		if err := c.Add(strings.NewReader(sb.String())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if spilled.spill == nil {
+		t.Fatal("want counts spilled to disk")
+	}
+	if a, b := top(t, inMemory, 10), top(t, spilled, 10); a != b {
+		t.Errorf("spilled result differs:\n%s\n%s", a, b)
+	}
+	all, err := spilled.Top(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, wc := range all {
+		if strings.HasPrefix(wc.Term, "aé") {
+			if wc.Term != long[:63] {
+				t.Errorf("unexpected truncation %q", wc.Term)
+			}
+			continue
+		}
+		if want[wc.Term] != wc.Count {
+			t.Errorf("%s: want %d, but %d", wc.Term, want[wc.Term], wc.Count)
+		}
+	}
+	if len(all) != len(want)+1 {
+		t.Errorf("want %d terms, but %d", len(want)+1, len(all))
+	}
+}
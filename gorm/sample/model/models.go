@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/RinkoTaketsuki/GolangLearning/example"
 	"github.com/RinkoTaketsuki/GolangLearning/gorm/utils"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -192,6 +194,17 @@ type Location struct {
 	X, Y int
 }
 
+// Coordinate 将 Location 转为 example.Coordinate，以便在数据库之外进行距离和范围查询。
+// 按照 WKT 中 POINT(x y) 的约定，X 是经度，Y 是纬度。
+func (loc Location) Coordinate() example.Coordinate {
+	return example.Coordinate{Lat: float64(loc.Y), Long: float64(loc.X)}
+}
+
+// LocationOf 是 Location.Coordinate 的逆操作，经纬度会被四舍五入为整数。
+func LocationOf(c example.Coordinate) Location {
+	return Location{X: int(math.Round(c.Long)), Y: int(math.Round(c.Lat))}
+}
+
 // 定义 SQL 中的数据类型
 func (loc Location) GormDataType() string {
 	return "geometry"
@@ -4,27 +4,14 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
-	"unicode/utf8"
 
+	"github.com/RinkoTaketsuki/GolangLearning/example/pretty"
 	"gorm.io/gorm"
 )
 
+// PrintBlock 输出用边框围起来的 str，宽度按 East Asian Width 计算，见 pretty.Box。
 func PrintBlock(str string) {
-	width := 0
-	for _, r := range str {
-		if utf8.RuneLen(r) >= 2 {
-			width += 2
-		} else {
-			width++
-		}
-	}
-	var sb strings.Builder
-	bar := "+" + strings.Repeat("-", width+2) + "+\n"
-	in := "| " + str + " |\n"
-	sb.WriteString(bar)
-	sb.WriteString(in)
-	sb.WriteString(bar)
-	fmt.Print(sb.String())
+	pretty.PrintBox(str)
 }
 
 func PrintStmt(stmt *gorm.Statement) {
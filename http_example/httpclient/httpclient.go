@@ -0,0 +1,23 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// New 返回调好参数的 http.Client。
+// Clients and Transports are safe for concurrent use by multiple goroutines
+// and for efficiency should only be created once and re-used.
+func New() *http.Client {
+	return &http.Client{
+		Timeout: 4 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:       http.DefaultMaxIdleConnsPerHost << 1,
+			IdleConnTimeout:    time.Minute,
+			DisableCompression: true,
+			// Disable HTTP/2
+			TLSNextProto: make(map[string]func(string, *tls.Conn) http.RoundTripper),
+		},
+	}
+}
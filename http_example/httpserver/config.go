@@ -0,0 +1,183 @@
+// Package httpserver 是可配置的 HTTP 服务器：配置可以来自命令行参数、环境变量和 JSON/YAML
+// 文件，支持 TLS 证书热加载、可选的 HTTP/2、按大小轮转的日志文件，以及在退出时等待进行中的请求完成。
+//
+//	cfg := httpserver.DefaultConfig()
+//	cfg.RegisterFlags(flag.CommandLine)
+//	if err := httpserver.Load(&cfg, flag.CommandLine, os.Args[1:], "SERVER_"); err != nil {
+//		log.Fatal(err)
+//	}
+//	srv, err := httpserver.New(cfg, handler, nil)
+//	...
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//	err = srv.Run(ctx)
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration 是在配置文件中写为 "5s"、"1m30s" 等字符串的 time.Duration。
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Config 是服务器本身的配置。应用的配置可以嵌入 Config，这时 YAML 需要 `yaml:",inline"` 标签，
+// JSON 会自动展开匿名字段。
+type Config struct {
+	// Addr 是监听的地址
+	Addr string `json:"addr" yaml:"addr"`
+	// CertFile 和 KeyFile 都不为空时使用 TLS，收到 SIGHUP 时重新加载
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file"`
+	// HTTP2 决定 TLS 连接是否可以协商 HTTP/2，不使用 TLS 时没有作用
+	HTTP2 bool `json:"http2" yaml:"http2"`
+
+	ReadTimeout  Duration `json:"read_timeout" yaml:"read_timeout"`
+	WriteTimeout Duration `json:"write_timeout" yaml:"write_timeout"`
+	IdleTimeout  Duration `json:"idle_timeout" yaml:"idle_timeout"`
+	// ShutdownTimeout 是退出时等待进行中的请求完成的最长时间，超时后强制关闭所有连接，0 表示一直等待
+	ShutdownTimeout Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	MaxHeaderBytes  int      `json:"max_header_bytes" yaml:"max_header_bytes"`
+
+	// LogFile 是日志文件的路径，为空时写到标准错误
+	LogFile string `json:"log_file" yaml:"log_file"`
+	// LogMaxSize 是日志文件轮转前的最大大小，单位为 MB，0 表示不轮转
+	LogMaxSize int `json:"log_max_size" yaml:"log_max_size"`
+	// LogMaxBackups 是轮转后保留的旧日志文件的个数
+	LogMaxBackups int `json:"log_max_backups" yaml:"log_max_backups"`
+}
+
+// DefaultConfig 返回默认的配置。
+func DefaultConfig() Config {
+	return Config{
+		Addr:            "0.0.0.0:17180",
+		ReadTimeout:     Duration(5 * time.Second),
+		WriteTimeout:    Duration(5 * time.Second),
+		IdleTimeout:     Duration(time.Minute),
+		ShutdownTimeout: Duration(10 * time.Second),
+		MaxHeaderBytes:  http.DefaultMaxHeaderBytes << 1,
+		LogFile:         "./server.log",
+		LogMaxSize:      100,
+		LogMaxBackups:   5,
+	}
+}
+
+// TLS 返回是否配置了证书。
+func (c *Config) TLS() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// RegisterFlags 在 fs 中注册 c 的所有字段对应的参数，参数的默认值是 c 当前的值。
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Addr, "addr", c.Addr, "http service address")
+	fs.StringVar(&c.CertFile, "cert", c.CertFile, "TLS certificate file, reloaded on SIGHUP")
+	fs.StringVar(&c.KeyFile, "key", c.KeyFile, "TLS private key file, reloaded on SIGHUP")
+	fs.BoolVar(&c.HTTP2, "http2", c.HTTP2, "allow HTTP/2 over TLS")
+	fs.DurationVar((*time.Duration)(&c.ReadTimeout), "read-timeout", time.Duration(c.ReadTimeout), "max duration for reading a request")
+	fs.DurationVar((*time.Duration)(&c.WriteTimeout), "write-timeout", time.Duration(c.WriteTimeout), "max duration for writing a response")
+	fs.DurationVar((*time.Duration)(&c.IdleTimeout), "idle-timeout", time.Duration(c.IdleTimeout), "max idle time of keep-alive connections")
+	fs.DurationVar((*time.Duration)(&c.ShutdownTimeout), "shutdown-timeout", time.Duration(c.ShutdownTimeout), "max time to drain in-flight requests on exit, 0 to wait forever")
+	fs.IntVar(&c.MaxHeaderBytes, "max-header-bytes", c.MaxHeaderBytes, "max size of request headers")
+	fs.StringVar(&c.LogFile, "log-file", c.LogFile, "log file, empty for stderr")
+	fs.IntVar(&c.LogMaxSize, "log-max-size", c.LogMaxSize, "rotate the log file after this many megabytes, 0 to disable")
+	fs.IntVar(&c.LogMaxBackups, "log-max-backups", c.LogMaxBackups, "number of rotated log files to keep")
+}
+
+// Load 解析 args 并把配置读入 cfg，优先级从低到高依次是：cfg 原有的值、配置文件、环境变量、命令行参数。
+// fs 中的参数必须绑定到 cfg 的字段上，例如通过 Config.RegisterFlags 注册。
+//
+// Load 会在 fs 中注册 -config 参数，它和环境变量 envPrefix+"CONFIG" 指定配置文件，
+// 配置文件的格式由扩展名决定，见 LoadFile。参数 -foo-bar 对应的环境变量是 envPrefix+"FOO_BAR"，
+// 取值的格式与命令行参数相同。
+func Load(cfg any, fs *flag.FlagSet, args []string, envPrefix string) error {
+	configFile := fs.String("config", "", fmt.Sprintf("JSON or YAML config file, also $%s", envName(envPrefix, "config")))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// 配置文件会覆盖参数绑定的字段，先记下命令行中出现的参数，最后再设置一次
+	explicit := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+	path := *configFile
+	if _, ok := explicit["config"]; !ok {
+		path = os.Getenv(envName(envPrefix, "config"))
+	}
+	if path != "" {
+		if err := LoadFile(path, cfg); err != nil {
+			return err
+		}
+	}
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" {
+			return
+		}
+		if v, ok := explicit[f.Name]; ok {
+			if err := fs.Set(f.Name, v); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", f.Name, err))
+			}
+		} else if v, ok := os.LookupEnv(envName(envPrefix, f.Name)); ok {
+			if err := fs.Set(f.Name, v); err != nil {
+				errs = append(errs, fmt.Errorf("$%s: %w", envName(envPrefix, f.Name), err))
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+func envName(prefix, flagName string) string {
+	return prefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// LoadFile 把配置文件 path 读入 cfg，扩展名为 .json 时按 JSON 解析，为 .yaml 或 .yml 时按 YAML 解析。
+// 文件中没有出现的字段保持原来的值，出现了 cfg 中没有的字段时返回错误。
+func LoadFile(path string, cfg any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		dec := json.NewDecoder(f)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		// 空文件不是错误
+		if err = dec.Decode(cfg); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	default:
+		return fmt.Errorf("%s: unknown config file format %q", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
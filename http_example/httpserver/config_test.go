@@ -0,0 +1,111 @@
+package httpserver
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// appConfig 模拟嵌入了 Config 的应用配置。
+type appConfig struct {
+	Config `yaml:",inline"`
+	Rate   float64 `json:"rate" yaml:"rate"`
+	Name   string  `json:"name" yaml:"name"`
+}
+
+func loadApp(t *testing.T, args []string, env map[string]string) (appConfig, error) {
+	t.Helper()
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	cfg := appConfig{Config: DefaultConfig(), Rate: 100, Name: "default"}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg.RegisterFlags(fs)
+	fs.Float64Var(&cfg.Rate, "rate", cfg.Rate, "")
+	fs.StringVar(&cfg.Name, "name", cfg.Name, "")
+	err := Load(&cfg, fs, args, "TEST_")
+	return cfg, err
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		yamlFile := writeFile(t, "server.yaml", `
+addr: 127.0.0.1:8080
+http2: true
+shutdown_timeout: 30s
+rate: 5
+name: from-file
+log_max_size: 7
+`)
+		// 命令行参数 > 环境变量 > 配置文件 > 默认值
+		cfg, err := loadApp(t, []string{"-config", yamlFile, "-name", "from-flag"}, map[string]string{
+			"TEST_RATE":         "20",
+			"TEST_NAME":         "from-env",
+			"TEST_READ_TIMEOUT": "2s",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Name != "from-flag" || cfg.Rate != 20 || cfg.Addr != "127.0.0.1:8080" || !cfg.HTTP2 || cfg.LogMaxSize != 7 {
+			t.Errorf("unexpected config %+v", cfg)
+		}
+		if cfg.ShutdownTimeout != Duration(30*time.Second) || cfg.ReadTimeout != Duration(2*time.Second) {
+			t.Errorf("unexpected timeouts %+v", cfg.Config)
+		}
+		if cfg.WriteTimeout != DefaultConfig().WriteTimeout || cfg.LogFile != DefaultConfig().LogFile {
+			t.Errorf("unset fields should keep their defaults: %+v", cfg.Config)
+		}
+	})
+	t.Run("json from env", func(t *testing.T) {
+		jsonFile := writeFile(t, "server.json", `{"addr": ":9090", "idle_timeout": "1m30s", "rate": 1.5}`)
+		cfg, err := loadApp(t, nil, map[string]string{"TEST_CONFIG": jsonFile})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Addr != ":9090" || cfg.IdleTimeout != Duration(90*time.Second) || cfg.Rate != 1.5 || cfg.Name != "default" {
+			t.Errorf("unexpected config %+v", cfg)
+		}
+	})
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		args []string
+		env  map[string]string
+		want string
+	}{
+		{"unknown field", []string{"-config", writeFile(t, "a.yaml", "adr: :80\n")}, nil, "adr"},
+		{"unknown json field", []string{"-config", writeFile(t, "a.json", `{"adr": ":80"}`)}, nil, "adr"},
+		{"bad duration", []string{"-config", writeFile(t, "b.json", `{"read_timeout": "soon"}`)}, nil, "soon"},
+		{"unknown format", []string{"-config", writeFile(t, "c.toml", "")}, nil, "unknown config file format"},
+		{"missing file", []string{"-config", filepath.Join(t.TempDir(), "none.yaml")}, nil, "none.yaml"},
+		{"bad env", nil, map[string]string{"TEST_RATE": "fast"}, "$TEST_RATE"},
+		{"bad flag", []string{"-rate", "fast"}, nil, "fast"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := loadApp(t, tc.args, tc.env)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("want error containing %q, but %v", tc.want, err)
+			}
+		})
+	}
+	// 空的 YAML 文件不是错误
+	if _, err := loadApp(t, []string{"-config", writeFile(t, "empty.yml", "")}, nil); err != nil {
+		t.Errorf("empty YAML file: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// RotatingFile 是按大小轮转的日志文件，可以并发写入。写入后文件会超过 maxSize 字节时，
+// 当前文件被重命名为 path.1，原来的 path.1 重命名为 path.2，依次类推，只保留 maxBackups 个旧文件。
+// 一次写入的内容不会被拆到两个文件中。轮转失败时内容仍然写入当前文件，Write 返回轮转的错误，
+// 文件再增长 maxSize 字节后才会再次尝试轮转。
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+	// limit 是下一次轮转的大小，轮转失败后会推迟
+	limit int64
+}
+
+// OpenRotatingFile 以追加的方式打开 path，maxSize 不是正数时不自动轮转。
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path, maxSize: maxSize, maxBackups: max(maxBackups, 0)}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.f, rf.size, rf.limit = f, info.Size(), rf.maxSize
+	return nil
+}
+
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return 0, os.ErrClosed
+	}
+	var rotateErr error
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.limit {
+		if rotateErr = rf.rotate(); rotateErr != nil {
+			if rf.f == nil {
+				return 0, rotateErr
+			}
+			// 文件已经重新打开，不丢掉这次写入，也不在之后的每次写入时重试
+			rf.limit = rf.size + rf.maxSize
+		}
+	}
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	return n, errors.Join(rotateErr, err)
+}
+
+// Rotate 立即轮转文件。
+func (rf *RotatingFile) Rotate() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return os.ErrClosed
+	}
+	return rf.rotate()
+}
+
+func (rf *RotatingFile) rotate() error {
+	// Close 失败时文件同样不能再用，继续轮转并重新打开
+	cerr := rf.f.Close()
+	rf.f = nil
+	var err error
+	if rf.maxBackups == 0 {
+		err = os.Remove(rf.path)
+	} else {
+		// 从最旧的开始重命名，path.maxBackups 被覆盖
+		for i := rf.maxBackups - 1; i > 0 && err == nil; i-- {
+			err = os.Rename(rf.backup(i), rf.backup(i+1))
+			if errors.Is(err, fs.ErrNotExist) {
+				err = nil
+			}
+		}
+		if err == nil {
+			err = os.Rename(rf.path, rf.backup(1))
+		}
+	}
+	err = errors.Join(cerr, err)
+	// 即使轮转失败也要重新打开文件，不能丢掉之后的日志
+	if oerr := rf.open(); oerr != nil {
+		return errors.Join(err, oerr)
+	}
+	if err != nil {
+		return fmt.Errorf("rotate %s: %w", rf.path, err)
+	}
+	return nil
+}
+
+func (rf *RotatingFile) backup(i int) string {
+	return fmt.Sprintf("%s.%d", rf.path, i)
+}
+
+// Reopen 关闭并重新打开文件，用于配合 logrotate 等在外部移动了日志文件的工具。
+func (rf *RotatingFile) Reopen() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return os.ErrClosed
+	}
+	err := rf.f.Close()
+	if oerr := rf.open(); oerr != nil {
+		rf.f = nil
+		return errors.Join(err, oerr)
+	}
+	return err
+}
+
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return os.ErrClosed
+	}
+	err := rf.f.Close()
+	rf.f = nil
+	return err
+}
@@ -0,0 +1,207 @@
+package httpserver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	// 已有的内容计入大小
+	if err := os.WriteFile(path, []byte("old00\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rf, err := OpenRotatingFile(path, 12, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 7; i++ {
+		// 每行 6 字节，每个文件正好放两行
+		if _, err := fmt.Fprintf(rf, "line%d\n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		path:        "line6\nline7\n",
+		path + ".1": "line4\nline5\n",
+		path + ".2": "line2\nline3\n",
+	} {
+		if got := readFile(t, name); got != want {
+			t.Errorf("%s: want %q, but %q", filepath.Base(name), want, got)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("want only 2 backups, but %s.3 exists: %v", path, err)
+	}
+	if _, err := rf.Write([]byte("x")); err == nil {
+		t.Error("want error writing to a closed file")
+	}
+}
+
+func TestRotatingFileRotateError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	// path.1 是非空的目录，重命名会失败
+	if err := os.MkdirAll(filepath.Join(path+".1", "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rf, err := OpenRotatingFile(path, 12, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	var errs int
+	for i := 1; i <= 6; i++ {
+		n, err := fmt.Fprintf(rf, "line%d\n", i)
+		if n != 6 {
+			t.Fatalf("line%d: want 6 bytes written, but %d", i, n)
+		}
+		if err != nil {
+			errs++
+		}
+	}
+	// 第 3 行和第 5 行触发轮转，失败后推迟到文件再增长 12 字节
+	if errs != 2 {
+		t.Errorf("want 2 rotation errors, but %d", errs)
+	}
+	if got, want := readFile(t, path), "line1\nline2\nline3\nline4\nline5\nline6\n"; got != want {
+		t.Errorf("want no lines lost, but %q", got)
+	}
+	// 恢复后的轮转正常进行
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	rf.Write([]byte("after\n"))
+	if got := readFile(t, path); got != "after\n" {
+		t.Errorf("want a new file after Rotate, but %q", got)
+	}
+}
+
+func TestRotatingFileCloseError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	rf, err := OpenRotatingFile(path, 12, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	rf.Write([]byte("line1\nline2\n"))
+	// 让第 3 行触发的轮转中 Close 失败
+	rf.f.Close()
+	if n, err := rf.Write([]byte("line3\n")); n != 6 || err == nil {
+		t.Errorf("want 6 bytes written and the Close error, but %d, %v", n, err)
+	}
+	if _, err := rf.Write([]byte("line4\n")); err != nil {
+		t.Errorf("want writes to succeed after the failed rotation, but %v", err)
+	}
+	if got, want := readFile(t, path), "line3\nline4\n"; got != want {
+		t.Errorf("want %q, but %q", want, got)
+	}
+	if got, want := readFile(t, path+".1"), "line1\nline2\n"; got != want {
+		t.Errorf("backup: want %q, but %q", want, got)
+	}
+}
+
+func TestRotatingFileNoBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	rf, err := OpenRotatingFile(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	// maxSize 为 0 时不自动轮转
+	rf.Write([]byte(strings.Repeat("a", 100)))
+	if got := readFile(t, path); len(got) != 100 {
+		t.Errorf("want 100 bytes, but %d", len(got))
+	}
+	if err := rf.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	rf.Write([]byte("b"))
+	if got := readFile(t, path); got != "b" {
+		t.Errorf("want the old content to be dropped, but %q", got)
+	}
+	if matches, _ := filepath.Glob(path + ".*"); len(matches) > 0 {
+		t.Errorf("unexpected backups %v", matches)
+	}
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.log")
+	rf, err := OpenRotatingFile(path, 1<<20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	rf.Write([]byte("before\n"))
+	// 模拟 logrotate 移走文件
+	if err := os.Rename(path, filepath.Join(dir, "moved.log")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	rf.Write([]byte("after\n"))
+	if got := readFile(t, path); got != "after\n" {
+		t.Errorf("want a new file after Reopen, but %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "moved.log")); got != "before\n" {
+		t.Errorf("moved file: %q", got)
+	}
+}
+
+func TestRotatingFileConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	rf, err := OpenRotatingFile(path, 1000, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				fmt.Fprintf(rf, "%d-%03d\n", g, i)
+			}
+		}()
+	}
+	wg.Wait()
+	rf.Close()
+	// 每行都完整地出现在某个文件中，每个文件都不超过上限
+	lines := 0
+	files, _ := filepath.Glob(path + "*")
+	for _, name := range files {
+		content := readFile(t, name)
+		if len(content) > 1000 {
+			t.Errorf("%s has %d bytes", filepath.Base(name), len(content))
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+			if len(line) != 5 {
+				t.Fatalf("%s: broken line %q", filepath.Base(name), line)
+			}
+			lines++
+		}
+	}
+	if lines != 800 {
+		t.Errorf("want 800 lines, but %d", lines)
+	}
+}
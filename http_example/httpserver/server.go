@@ -0,0 +1,177 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// CertReloader 保存从文件加载的 TLS 证书，可以在不重启服务器的情况下重新加载。
+type CertReloader struct {
+	certFile, keyFile string
+	cert              atomic.Pointer[tls.Certificate]
+}
+
+// NewCertReloader 加载 certFile 和 keyFile 中的证书和私钥。
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	cr := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := cr.Reload(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// Reload 重新加载证书，失败时继续使用原来的证书。
+func (cr *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	cr.cert.Store(&cert)
+	return nil
+}
+
+// GetCertificate 返回当前的证书，用作 tls.Config.GetCertificate。
+func (cr *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return cr.cert.Load(), nil
+}
+
+// Server 是按 Config 配置的 http.Server。
+type Server struct {
+	cfg    Config
+	srv    *http.Server
+	certs  *CertReloader
+	logger *log.Logger
+
+	mu    sync.Mutex
+	hooks []func() error
+}
+
+// New 返回使用 handler 处理请求的 Server，logger 为 nil 时使用 log 包默认的 Logger。
+// 配置了证书时会立即加载，证书无效时返回错误。
+func New(cfg Config, handler http.Handler, logger *log.Logger) (*Server, error) {
+	if logger == nil {
+		logger = log.Default()
+	}
+	s := &Server{cfg: cfg, logger: logger}
+	s.srv = &http.Server{
+		Addr:           cfg.Addr,
+		Handler:        handler,
+		ReadTimeout:    time.Duration(cfg.ReadTimeout),
+		WriteTimeout:   time.Duration(cfg.WriteTimeout),
+		IdleTimeout:    time.Duration(cfg.IdleTimeout),
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+		ErrorLog:       logger,
+	}
+	if cfg.TLS() {
+		var err error
+		if s.certs, err = NewCertReloader(cfg.CertFile, cfg.KeyFile); err != nil {
+			return nil, err
+		}
+		s.srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: s.certs.GetCertificate,
+		}
+	}
+	if !cfg.HTTP2 {
+		// 非空的 TLSNextProto 会关闭 HTTP/2
+		s.srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	return s, nil
+}
+
+// OnReload 注册 Reload 时调用的函数，例如重新打开日志文件。
+func (s *Server) OnReload(f func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, f)
+}
+
+// Reload 重新加载证书，并依次调用 OnReload 注册的函数，返回所有的错误。
+// 服务器运行时收到 SIGHUP 会调用 Reload。
+func (s *Server) Reload() error {
+	var errs []error
+	if s.certs != nil {
+		errs = append(errs, s.certs.Reload())
+	}
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+	for _, f := range hooks {
+		errs = append(errs, f())
+	}
+	return errors.Join(errs...)
+}
+
+// Run 监听 Config.Addr 并调用 Serve。
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve 在 ln 上处理请求，直到 ctx 结束或者出错。ctx 结束后不再接受新的连接，
+// 并等待进行中的请求完成，超过 Config.ShutdownTimeout 时强制关闭所有连接并返回错误。
+// 正常退出时返回 nil。Serve 运行期间收到 SIGHUP 时调用 Reload。
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	errc := make(chan error, 1)
+	go func() {
+		if s.certs != nil {
+			errc <- s.srv.ServeTLS(ln, "", "")
+		} else {
+			errc <- s.srv.Serve(ln)
+		}
+	}()
+	s.logger.Printf("listening on %s (tls: %v, http2: %v)", ln.Addr(), s.certs != nil, s.certs != nil && s.cfg.HTTP2)
+	for {
+		select {
+		case err := <-errc:
+			return err
+		case <-hup:
+			if err := s.Reload(); err != nil {
+				s.logger.Print("reload: ", err)
+			} else {
+				s.logger.Print("reloaded")
+			}
+		case <-ctx.Done():
+			return s.shutdown(errc)
+		}
+	}
+}
+
+// shutdown 等待进行中的请求完成后关闭服务器，errc 用于接收 Serve 的结果。
+func (s *Server) shutdown(errc <-chan error) error {
+	s.logger.Print("shutting down")
+	ctx := context.Background()
+	if d := time.Duration(s.cfg.ShutdownTimeout); d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	err := s.srv.Shutdown(ctx)
+	if err != nil {
+		// 超时后中断剩下的连接
+		s.srv.Close()
+		err = fmt.Errorf("shutdown: %w", err)
+	}
+	if serr := <-errc; !errors.Is(serr, http.ErrServerClosed) {
+		err = errors.Join(err, serr)
+	}
+	return err
+}
@@ -0,0 +1,269 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startServer 在随机端口上运行 s，返回服务器的地址和 Serve 的结果。
+func startServer(t *testing.T, ctx context.Context, s *Server) (string, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, ln) }()
+	return ln.Addr().String(), done
+}
+
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.ShutdownTimeout = Duration(5 * time.Second)
+	return cfg
+}
+
+var quietLogger = log.New(io.Discard, "", 0)
+
+func TestServeGracefulShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	s, err := New(testConfig(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	}), quietLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, done := startServer(t, ctx, s)
+
+	type result struct {
+		body string
+		err  error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			resc <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		resc <- result{string(b), err}
+	}()
+	<-started
+	cancel()
+	// 关闭期间不再接受新的连接
+	deadline := time.Now().Add(time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("listener still accepting connections after shutdown started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("Serve returned before the in-flight request finished: %v", err)
+	default:
+	}
+	close(release)
+	if res := <-resc; res.err != nil || res.body != "done" {
+		t.Errorf("in-flight request: %q %v", res.body, res.err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Serve: %v", err)
+	}
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.ShutdownTimeout = Duration(50 * time.Millisecond)
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	s, err := New(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}), quietLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, done := startServer(t, ctx, s)
+	errc := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr)
+		if err == nil {
+			resp.Body.Close()
+		}
+		errc <- err
+	}()
+	<-started
+	cancel()
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want deadline exceeded, but %v", err)
+	}
+	// 超时后连接被强制关闭
+	if err := <-errc; err == nil {
+		t.Error("want the in-flight request to fail")
+	}
+}
+
+// writeCert 生成序列号为 serial 的自签名证书，写到 dir 中的 cert.pem 和 key.pem。
+func writeCert(t *testing.T, dir string, serial int64) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, "cert.pem"), certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "key.pem"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// getTLS 用新的连接请求 addr，返回响应的协议版本和服务器证书的序列号。
+func getTLS(t *testing.T, addr string) (int, int64) {
+	t.Helper()
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	defer client.CloseIdleConnections()
+	resp, err := client.Get("https://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.ProtoMajor, resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestServeTLS(t *testing.T) {
+	dir := t.TempDir()
+	writeCert(t, dir, 1)
+	for _, http2 := range []bool{false, true} {
+		cfg := testConfig()
+		cfg.CertFile, cfg.KeyFile, cfg.HTTP2 = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), http2
+		s, err := New(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), quietLogger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		addr, done := startServer(t, ctx, s)
+		proto, serial := getTLS(t, addr)
+		if want := map[bool]int{false: 1, true: 2}[http2]; proto != want {
+			t.Errorf("http2 %v: want HTTP/%d, but HTTP/%d", http2, want, proto)
+		}
+		if serial != 1 {
+			t.Errorf("want certificate 1, but %d", serial)
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	}
+}
+
+func TestServeReload(t *testing.T) {
+	dir := t.TempDir()
+	writeCert(t, dir, 1)
+	cfg := testConfig()
+	cfg.CertFile, cfg.KeyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	s, err := New(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), quietLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hooked := make(chan struct{}, 1)
+	s.OnReload(func() error {
+		hooked <- struct{}{}
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, done := startServer(t, ctx, s)
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// 证书无效时 Reload 返回错误，继续使用原来的证书
+	if err := os.WriteFile(cfg.CertFile, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err == nil {
+		t.Error("want error reloading an invalid certificate")
+	}
+	<-hooked
+	if _, serial := getTLS(t, addr); serial != 1 {
+		t.Errorf("want certificate 1 after a failed reload, but %d", serial)
+	}
+
+	// SIGHUP 触发 Reload
+	writeCert(t, dir, 2)
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Skip("cannot send SIGHUP: ", err)
+	}
+	select {
+	case <-hooked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGHUP did not trigger Reload")
+	}
+	if _, serial := getTLS(t, addr); serial != 2 {
+		t.Errorf("want certificate 2 after SIGHUP, but %d", serial)
+	}
+}
+
+func TestNewInvalidCert(t *testing.T) {
+	cfg := testConfig()
+	cfg.CertFile, cfg.KeyFile = filepath.Join(t.TempDir(), "none.pem"), filepath.Join(t.TempDir(), "none.key")
+	if _, err := New(cfg, http.NotFoundHandler(), quietLogger); err == nil || !strings.Contains(err.Error(), "none.pem") {
+		t.Errorf("want error loading missing certificate, but %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example"
+)
+
+// RequestIDHeader 是携带请求 ID 的请求头和响应头。
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestID 返回 WithRequestID 保存在 ctx 中的请求 ID，没有时返回 ""。
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithRequestID 为每个请求分配 ID：请求头中已有合法的 X-Request-ID 时沿用它，否则生成随机的 ID。
+// ID 被保存到请求的 context 中，并通过响应头返回给客户端。
+func WithRequestID(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// validRequestID 只接受不太长的可打印 ASCII，避免客户端向日志注入内容。
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [12]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// NewAccessLogger 返回向 w 输出访问日志的 slog.Logger，format 为 "json" 或 "logfmt"。
+func NewAccessLogger(w io.Writer, format string) (*slog.Logger, error) {
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, nil)), nil
+	case "logfmt", "text":
+		return slog.New(slog.NewTextHandler(w, nil)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q, want json or logfmt", format)
+}
+
+// WithAccessLog 在每个请求结束后用 logger 记录一条访问日志，包括请求 ID、客户端 IP、方法、路径、
+// 匹配的路由、状态码、响应大小和耗时。5xx 记为 Error，4xx 记为 Warn，其余记为 Info。
+// 它应当放在 WithRequestID 之内、ServeMux 之外，这样所有请求（包括 404）都被记录。
+func WithAccessLog(logger *slog.Logger) Middleware {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := NewResponseRecorder(w)
+			defer func() {
+				level := slog.LevelInfo
+				switch status := rec.Status(); {
+				case status >= 500:
+					level = slog.LevelError
+				case status >= 400:
+					level = slog.LevelWarn
+				}
+				logger.LogAttrs(r.Context(), level, "request",
+					slog.String("request_id", RequestID(r.Context())),
+					slog.String("ip", ClientIP(r)),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("route", r.Pattern),
+					slog.Int("status", rec.Status()),
+					slog.Int64("size", rec.Size()),
+					slog.Duration("latency", rec.Elapsed()),
+				)
+			}()
+			handler.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// routeLabel 返回用作指标标签的路由。使用匹配的 pattern 而不是原始路径，避免路径参数使标签无限增长。
+func routeLabel(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+	return r.Pattern
+}
+
+// statusClass 返回 "2xx" 形式的状态码类别。
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// WithMetrics 把每个请求的数量、耗时和响应大小记录到 reg 中，可以通过 /metrics 查看：
+//   - http_requests_total{method, route, class}：按状态码类别计数；
+//   - http_request_duration_seconds{method, route}：耗时的直方图；
+//   - http_response_size_bytes{method, route}：响应大小的直方图。
+func WithMetrics(reg *example.Registry) Middleware {
+	requests := reg.Counter("http_requests_total", "Number of HTTP requests by status class.", "method", "route", "class")
+	durations := reg.Histogram("http_request_duration_seconds", "HTTP request latency in seconds.", nil, "method", "route")
+	sizes := reg.Histogram("http_response_size_bytes", "HTTP response body size in bytes.",
+		[]float64{100, 1 << 10, 10 << 10, 100 << 10, 1 << 20, 10 << 20}, "method", "route")
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := NewResponseRecorder(w)
+			start := time.Now()
+			defer func() {
+				route := routeLabel(r)
+				requests.With(r.Method, route, statusClass(rec.Status())).Inc()
+				durations.With(r.Method, route).Observe(time.Since(start).Seconds())
+				sizes.With(r.Method, route).Observe(float64(rec.Size()))
+			}()
+			handler.ServeHTTP(rec, r)
+		})
+	}
+}
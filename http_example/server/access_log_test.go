@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	testcases := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		size    int64
+	}{
+		{"empty", func(w http.ResponseWriter, r *http.Request) {}, 200, 0},
+		{"implicit 200", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "hello") }, 200, 5},
+		{"error", func(w http.ResponseWriter, r *http.Request) { http.Error(w, "gone", http.StatusGone) }, 410, 5},
+		{"1xx then 201", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusEarlyHints)
+			w.WriteHeader(http.StatusCreated)
+			w.WriteHeader(http.StatusInternalServerError) // 被 net/http 忽略
+		}, 201, 0},
+		{"ReadFrom", func(w http.ResponseWriter, r *http.Request) { io.Copy(w, strings.NewReader("abc")) }, 200, 3},
+	}
+	for _, tc := range testcases {
+		rec := NewResponseRecorder(httptest.NewRecorder())
+		tc.handler(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Status() != tc.status || rec.Size() != tc.size {
+			t.Errorf("%s: want %d %d, but %d %d", tc.name, tc.status, tc.size, rec.Status(), rec.Size())
+		}
+	}
+	rec := NewResponseRecorder(httptest.NewRecorder())
+	if NewResponseRecorder(rec) != rec {
+		t.Error("ResponseRecorder wrapped twice")
+	}
+	if err := http.NewResponseController(rec).Flush(); err != nil || !rec.Written() {
+		t.Errorf("Flush through ResponseController: %v", err)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewAccessLogger(&buf, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := example.NewRegistry(0)
+	mux := NewServeMux()
+	mux.Use(WithRequestID, WithAccessLog(logger), WithMetrics(reg))
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if RequestID(r.Context()) == "" {
+			t.Error("no request ID in context")
+		}
+		io.WriteString(w, "item "+r.PathValue("id"))
+	})
+
+	req := httptest.NewRequest("GET", "/items/42", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if got := w.Header().Get(RequestIDHeader); got != "abc-123" {
+		t.Errorf("want request ID to be propagated, but %q", got)
+	}
+	serve(mux, "GET", "/missing")
+	serve(mux, "GET", "/items/7")
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e map[string]any
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid JSON log line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("want 3 log entries, but %d", len(entries))
+	}
+	want := map[string]any{
+		"level": "INFO", "msg": "request", "request_id": "abc-123", "ip": "192.0.2.1",
+		"method": "GET", "path": "/items/42", "route": "GET /items/{id}", "status": 200.0, "size": 7.0,
+	}
+	for k, v := range want {
+		if entries[0][k] != v {
+			t.Errorf("%s: want %v, but %v", k, v, entries[0][k])
+		}
+	}
+	if _, ok := entries[0]["latency"].(float64); !ok {
+		t.Errorf("latency: want a number, but %v", entries[0]["latency"])
+	}
+	if entries[1]["level"] != "WARN" || entries[1]["status"] != 404.0 || entries[1]["route"] != "" {
+		t.Errorf("404 entry: %v", entries[1])
+	}
+	if id, _ := entries[2]["request_id"].(string); len(id) != 24 {
+		t.Errorf("want a generated request ID, but %q", id)
+	}
+
+	if got := reg.Counter("http_requests_total", "", "method", "route", "class").With("GET", "GET /items/{id}", "2xx").Value(); got != 2 {
+		t.Errorf("want 2 successful requests, but %v", got)
+	}
+	if got := reg.Counter("http_requests_total", "", "method", "route", "class").With("GET", "unmatched", "4xx").Value(); got != 1 {
+		t.Errorf("want 1 unmatched request, but %v", got)
+	}
+	metrics := serve(reg.Handler(), "GET", "/metrics").Body.String()
+	if !strings.Contains(metrics, `http_request_duration_seconds_count{method="GET",route="GET /items/{id}"} 2`) {
+		t.Errorf("latency histogram missing from /metrics:\n%s", metrics)
+	}
+}
+
+func TestAccessLogFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewAccessLogger(&buf, "logfmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := WithAccessLog(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		http.Error(w, "boom", http.StatusBadGateway)
+	}))
+	serve(h, "POST", "/x")
+	line := buf.String()
+	for _, s := range []string{"level=ERROR", "method=POST", "path=/x", "status=502", "size=5", "latency="} {
+		if !strings.Contains(line, s) {
+			t.Errorf("want %q in %q", s, line)
+		}
+	}
+	if _, err := NewAccessLogger(&buf, "xml"); err == nil {
+		t.Error("want error for unknown format")
+	}
+}
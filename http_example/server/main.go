@@ -1,49 +1,171 @@
 package main
 
 import (
-	"crypto/tls"
+	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log"
-	"net/http"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example"
+	"github.com/RinkoTaketsuki/GolangLearning/example/ipaddr"
+	"github.com/RinkoTaketsuki/GolangLearning/http_example/httpserver"
 )
 
+// Config 是服务器的全部配置，可以来自命令行参数、SERVER_ 开头的环境变量和 -config 指定的文件，见 httpserver.Load。
+type Config struct {
+	httpserver.Config `yaml:",inline"`
+
+	RateLimit     float64             `json:"rate" yaml:"rate"`
+	MaxConnsPerIP int                 `json:"conns_per_ip" yaml:"conns_per_ip"`
+	MetricsTTL    httpserver.Duration `json:"metrics_ttl" yaml:"metrics_ttl"`
+	AllowCIDRs    string              `json:"allow" yaml:"allow"`
+	DenyCIDRs     string              `json:"deny" yaml:"deny"`
+	ACLFile       string              `json:"acl" yaml:"acl"`
+	LogFormat     string              `json:"log_format" yaml:"log_format"`
+}
+
+// DefaultConfig 返回默认的配置。
+func DefaultConfig() Config {
+	return Config{
+		Config:        httpserver.DefaultConfig(),
+		RateLimit:     100,
+		MaxConnsPerIP: 8,
+		MetricsTTL:    httpserver.Duration(10 * time.Minute),
+		LogFormat:     "json",
+	}
+}
+
+// RegisterFlags 在 fs 中注册 c 的所有字段对应的参数。
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	c.Config.RegisterFlags(fs)
+	fs.Float64Var(&c.RateLimit, "rate", c.RateLimit, "max requests per second")
+	fs.IntVar(&c.MaxConnsPerIP, "conns-per-ip", c.MaxConnsPerIP, "max concurrent requests per client IP")
+	fs.DurationVar((*time.Duration)(&c.MetricsTTL), "metrics-ttl", time.Duration(c.MetricsTTL), "drop metric series idle for this long, 0 to keep forever")
+	fs.StringVar(&c.AllowCIDRs, "allow", c.AllowCIDRs, "comma separated CIDRs to allow; if set, other clients are denied unless allowed by -acl")
+	fs.StringVar(&c.DenyCIDRs, "deny", c.DenyCIDRs, "comma separated CIDRs to deny, overriding shorter -allow prefixes")
+	fs.StringVar(&c.ACLFile, "acl", c.ACLFile, "file of \"allow|deny <CIDR>\" and \"default allow|deny\" rules, applied before -allow and -deny")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "access log format, json or logfmt")
+}
+
 // sorted by initialization order
 var (
-	ListeningAddress = flag.String("addr", "0.0.0.0:17180", "http service address")
-	Middlewares      = []Middleware{
-		WithPanicRecovery,
-		WithLogger,
-		WithMetrics,
-	}
+	Cfg          = DefaultConfig()
+	LogFile      io.Writer
 	Logger       *log.Logger
+	AccessLog    *slog.Logger
+	Metrics      *example.Registry
+	ACL          *ipaddr.List
 	FullServeMux *ServeMux
-	Server       *http.Server
+	Server       *httpserver.Server
 )
 
-func init() {
-	flag.Parse()
-	logFile, err := os.OpenFile("./server.log", os.O_CREATE|os.O_APPEND, 0666)
-	if err != nil {
-		log.Fatal("OpenFile:", err)
+// setup 读取配置并创建 Server。它不放在 init 中，这样测试可以导入这个包而不解析参数。
+func setup(args []string) error {
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	Cfg.RegisterFlags(fs)
+	if err := httpserver.Load(&Cfg, fs, args, "SERVER_"); err != nil {
+		return err
+	}
+	// 限流器的构造函数对非法的参数会 panic
+	if !(Cfg.RateLimit > 0) {
+		return fmt.Errorf("-rate: must be positive, but %v", Cfg.RateLimit)
+	}
+	if Cfg.MaxConnsPerIP < 1 {
+		return fmt.Errorf("-conns-per-ip: must be positive, but %d", Cfg.MaxConnsPerIP)
+	}
+	LogFile = os.Stderr
+	if Cfg.LogFile != "" {
+		f, err := httpserver.OpenRotatingFile(Cfg.LogFile, int64(Cfg.LogMaxSize)<<20, Cfg.LogMaxBackups)
+		if err != nil {
+			return err
+		}
+		LogFile = f
 	}
-	Logger = log.New(logFile, "", log.LstdFlags)
+	Logger = log.New(LogFile, "", log.LstdFlags)
+	var err error
+	if AccessLog, err = NewAccessLogger(LogFile, Cfg.LogFormat); err != nil {
+		return fmt.Errorf("-log-format: %w", err)
+	}
+	Metrics = example.NewRegistry(time.Duration(Cfg.MetricsTTL))
+	if ACL, err = loadACL(); err != nil {
+		return fmt.Errorf("ACL: %w", err)
+	}
+	// 全局中间件，先注册的在外层：被过滤和限流的请求也会被记录到访问日志和指标中，
+	// 被过滤的请求不会消耗限流的令牌
 	FullServeMux = NewServeMux()
-	FullServeMux.ApplyMiddlewares(Middlewares...)
-	Server = &http.Server{
-		Addr:           *ListeningAddress,
-		Handler:        FullServeMux,
-		ReadTimeout:    5 * time.Second,
-		WriteTimeout:   5 * time.Second,
-		MaxHeaderBytes: http.DefaultMaxHeaderBytes << 1,
-		ErrorLog:       Logger,
-		// Disable HTTP/2
-		TLSNextProto: make(map[string]func(*http.Server, *tls.Conn, http.Handler)),
+	FullServeMux.Use(WithRequestID, WithAccessLog(AccessLog), WithMetrics(Metrics))
+	// 只有 default deny 的 -acl 文件没有任何规则，也需要过滤
+	if Cfg.ACLFile != "" || Cfg.AllowCIDRs != "" || Cfg.DenyCIDRs != "" {
+		FullServeMux.Use(WithIPFilter(ACL, ClientIP))
+	}
+	FullServeMux.Use(
+		// 令牌不会退还，先检查并发数，超过并发限制的请求不会消耗总速率
+		WithRateLimit(example.Chain(
+			example.NewKeyedSemaphore(Cfg.MaxConnsPerIP),
+			example.NewTokenBucket(Cfg.RateLimit, max(int(Cfg.RateLimit), 1)),
+		), ClientIP),
+		WithPanicRecovery(LogReporter(Logger), Metrics),
+	)
+	if Server, err = httpserver.New(Cfg.Config, FullServeMux, Logger); err != nil {
+		return err
+	}
+	// 收到 SIGHUP 时重新打开日志文件，配合外部的 logrotate
+	if f, ok := LogFile.(*httpserver.RotatingFile); ok {
+		Server.OnReload(f.Reopen)
+	}
+	return nil
+}
+
+// loadACL 根据 -acl、-allow 和 -deny 构造客户端 IP 的访问控制列表。-acl 文件中的 default
+// 规则优先；没有时，设置了 -allow 或文件中有 allow 规则就默认拒绝，否则默认允许。
+func loadACL() (*ipaddr.List, error) {
+	allow, err := ipaddr.ParsePrefixes(Cfg.AllowCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("-allow: %w", err)
+	}
+	deny, err := ipaddr.ParsePrefixes(Cfg.DenyCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("-deny: %w", err)
+	}
+	acl := ipaddr.NewList(len(allow) == 0)
+	if Cfg.ACLFile != "" {
+		f, err := os.Open(Cfg.ACLFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if acl, err = ipaddr.ParseList(f, len(allow) == 0); err != nil {
+			return nil, fmt.Errorf("%s: %w", Cfg.ACLFile, err)
+		}
 	}
+	acl.Allow(allow...)
+	acl.Deny(deny...)
+	return acl, nil
 }
 
 func main() {
-	FullServeMux.HandleFunc("/", HandlerQR)
-	Logger.Fatal("ListenAndServe:", Server.ListenAndServe())
+	if err := setup(os.Args[1:]); err != nil {
+		log.Fatal("setup: ", err)
+	}
+	FullServeMux.HandleFunc("GET /", HandlerQR)
+	FullServeMux.Handle("GET /metrics", Metrics.Handler())
+	// SIGINT 和 SIGTERM 触发优雅退出，再收到一次时 stop 已经恢复默认行为，进程直接退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	if err := Server.Run(ctx); err != nil {
+		Logger.Fatal("Run: ", err)
+	}
+	Logger.Print("server stopped")
+	if f, ok := LogFile.(*httpserver.RotatingFile); ok {
+		f.Close()
+	}
 }
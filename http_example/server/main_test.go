@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RinkoTaketsuki/GolangLearning/http_example/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "server.yaml")
+	if err := os.WriteFile(configFile, []byte("addr: 127.0.0.1:0\nrate: 3\ndeny: 10.0.0.0/8\nlog_format: logfmt\nmetrics_ttl: 1m\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SERVER_CONNS_PER_IP", "2")
+	defer func() { Cfg = DefaultConfig() }()
+	if err := setup([]string{"-config", configFile, "-log-file", filepath.Join(dir, "server.log"), "-rate", "7"}); err != nil {
+		t.Fatal(err)
+	}
+	defer LogFile.(*httpserver.RotatingFile).Close()
+	if Cfg.Addr != "127.0.0.1:0" || Cfg.RateLimit != 7 || Cfg.MaxConnsPerIP != 2 || Cfg.LogFormat != "logfmt" || time.Duration(Cfg.MetricsTTL) != time.Minute {
+		t.Errorf("unexpected config %+v", Cfg)
+	}
+	FullServeMux.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {})
+	req := httptest.NewRequest("GET", "/ping", nil)
+	req.RemoteAddr = "10.1.2.3:1234"
+	w := httptest.NewRecorder()
+	FullServeMux.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("want 403 for a denied client, but %d", w.Code)
+	}
+	if w := serve(FullServeMux, "GET", "/ping"); w.Code != http.StatusOK {
+		t.Errorf("want 200, but %d", w.Code)
+	}
+}
+
+func TestSetupInvalidLimits(t *testing.T) {
+	defer func() { Cfg = DefaultConfig() }()
+	for _, args := range [][]string{{"-rate", "0"}, {"-rate", "-1"}, {"-conns-per-ip", "0"}} {
+		Cfg = DefaultConfig()
+		err := setup(append(args, "-log-file", ""))
+		if err == nil || !strings.Contains(err.Error(), args[0]) {
+			t.Errorf("%v: want error about %s, but %v", args, args[0], err)
+		}
+	}
+}
+
+func TestSetupDeniedClientsKeepTokens(t *testing.T) {
+	defer func() { Cfg = DefaultConfig() }()
+	if err := setup([]string{"-rate", "1", "-deny", "10.0.0.0/8", "-log-file", ""}); err != nil {
+		t.Fatal(err)
+	}
+	FullServeMux.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {})
+	for _, tc := range []struct {
+		addr string
+		want int
+	}{
+		// 被拒绝的客户端不会消耗唯一的令牌
+		{"10.1.2.3:1234", http.StatusForbidden},
+		{"10.1.2.3:1234", http.StatusForbidden},
+		{"192.0.2.1:1234", http.StatusOK},
+		{"192.0.2.1:1234", http.StatusTooManyRequests},
+	} {
+		req := httptest.NewRequest("GET", "/ping", nil)
+		req.RemoteAddr = tc.addr
+		w := httptest.NewRecorder()
+		FullServeMux.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: want %d, but %d", tc.addr, tc.want, w.Code)
+		}
+	}
+}
+
+func TestSetupACLFile(t *testing.T) {
+	defer func() { Cfg = DefaultConfig() }()
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		file           string
+		allowed, other int
+	}{
+		// 只有 allow 规则的文件是白名单
+		{"allow 10.0.0.0/8\n", http.StatusOK, http.StatusForbidden},
+		{"default allow\nallow 10.0.0.0/8\n", http.StatusOK, http.StatusOK},
+		{"deny 192.0.2.0/24\n", http.StatusOK, http.StatusForbidden},
+		{"default deny\n", http.StatusForbidden, http.StatusForbidden},
+	} {
+		aclFile := filepath.Join(dir, "acl.txt")
+		if err := os.WriteFile(aclFile, []byte(tc.file), 0644); err != nil {
+			t.Fatal(err)
+		}
+		Cfg = DefaultConfig()
+		if err := setup([]string{"-acl", aclFile, "-log-file", ""}); err != nil {
+			t.Fatal(err)
+		}
+		FullServeMux.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {})
+		for addr, want := range map[string]int{"10.1.2.3:1234": tc.allowed, "192.0.2.1:1234": tc.other} {
+			req := httptest.NewRequest("GET", "/ping", nil)
+			req.RemoteAddr = addr
+			w := httptest.NewRecorder()
+			FullServeMux.ServeHTTP(w, req)
+			if w.Code != want {
+				t.Errorf("%q: %s: want %d, but %d", tc.file, addr, want, w.Code)
+			}
+		}
+	}
+}
@@ -1,41 +1,56 @@
 package main
 
 import (
+	"net"
 	"net/http"
-	"runtime/debug"
-	"time"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example"
+	"github.com/RinkoTaketsuki/GolangLearning/example/ipaddr"
 )
 
 type Middleware func(http.Handler) http.Handler
 
-func WithPanicRecovery(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				Logger.Println(string(debug.Stack()))
-			}
-		}()
-		handler.ServeHTTP(w, r)
-	})
+// ClientIP 返回请求的客户端 IP，不考虑 X-Forwarded-For 等代理头。
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
 
-func WithLogger(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		Logger.Printf("path:%s process start...\n", r.URL.Path)
-		defer func() {
-			Logger.Printf("path:%s process end...\n", r.URL.Path)
-		}()
-		handler.ServeHTTP(w, r)
-	})
+// WithRateLimit 使用与 Poller 相同的 example.Limiter 限流，key 为 nil 时按 ClientIP
+// 限流。不允许执行的请求会直接收到 429。
+func WithRateLimit(l example.Limiter, key func(*http.Request) string) Middleware {
+	if key == nil {
+		key = ClientIP
+	}
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			release, ok := l.TryAcquire(key(r))
+			if !ok {
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			defer release()
+			handler.ServeHTTP(w, r)
+		})
+	}
 }
 
-func WithMetrics(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		defer func() {
-			Logger.Printf("path:%s elapsed:%fs\n", r.URL.Path, time.Since(start).Seconds())
-		}()
-		time.Sleep(1 * time.Second)
-		handler.ServeHTTP(w, r)
-	})
+// WithIPFilter 按 l 过滤客户端 IP，key 为 nil 时使用 ClientIP。被拒绝的请求直接收到 403。
+func WithIPFilter(l *ipaddr.List, key func(*http.Request) string) Middleware {
+	if key == nil {
+		key = ClientIP
+	}
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !l.AllowedString(key(r)) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
 }
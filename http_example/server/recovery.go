@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example"
+)
+
+// PanicReport 描述 handler 中发生的一次 panic。
+type PanicReport struct {
+	Time      time.Time
+	RequestID string
+	Method    string
+	Path      string
+	// Route 是匹配的路由，没有匹配时为空
+	Route string
+	Value any
+	Stack []byte
+	// Aborted 表示 panic 时响应头已经发出，连接被中断而不是返回 500
+	Aborted bool
+}
+
+// PanicReporter 接收 WithPanicRecovery 捕获的 panic，可以把它们写入日志或发送到错误收集服务。
+// ReportPanic 在处理请求的 goroutine 中同步调用，耗时的操作应当自己异步执行。
+type PanicReporter interface {
+	ReportPanic(ctx context.Context, p *PanicReport)
+}
+
+// PanicReporterFunc 把函数适配为 PanicReporter。
+type PanicReporterFunc func(ctx context.Context, p *PanicReport)
+
+func (f PanicReporterFunc) ReportPanic(ctx context.Context, p *PanicReport) {
+	f(ctx, p)
+}
+
+// LogReporter 返回把 panic 和调用栈写入 l 的 PanicReporter。
+func LogReporter(l *log.Logger) PanicReporter {
+	return PanicReporterFunc(func(_ context.Context, p *PanicReport) {
+		l.Printf("panic: %v [request_id=%s method=%s path=%s route=%q aborted=%t]\n%s",
+			p.Value, p.RequestID, p.Method, p.Path, p.Route, p.Aborted, p.Stack)
+	})
+}
+
+// WithPanicRecovery 捕获 handler 中的 panic，交给 reporter，并按路由计入 reg 中的
+// http_panics_total（reg 为 nil 时不计数）：
+//   - 响应头还没有发出时，丢弃 handler 设置的响应头，返回 500 和 JSON 格式的错误，
+//     Accept 中有 text/html 而没有 application/json 时返回 HTML；
+//   - 响应头已经发出时无法再修改状态码，以 http.ErrAbortHandler 重新 panic，
+//     由 net/http 中断连接，客户端能够发现响应不完整；
+//   - http.ErrAbortHandler 本身表示 handler 主动中断连接，不报告，直接重新 panic。
+//
+// 它应当放在 WithRequestID 和 WithAccessLog 之内，这样报告中有请求 ID，访问日志中记录的是 500。
+func WithPanicRecovery(reporter PanicReporter, reg *example.Registry) Middleware {
+	var panics *example.CounterVec
+	if reg != nil {
+		panics = reg.Counter("http_panics_total", "Number of panics recovered from HTTP handlers.", "route")
+	}
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := NewResponseRecorder(w)
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				report := &PanicReport{
+					Time:      time.Now(),
+					RequestID: RequestID(r.Context()),
+					Method:    r.Method,
+					Path:      r.URL.Path,
+					Route:     r.Pattern,
+					Value:     v,
+					Stack:     debug.Stack(),
+					Aborted:   rec.Written(),
+				}
+				if panics != nil {
+					panics.With(routeLabel(r)).Inc()
+				}
+				if reporter != nil {
+					reporter.ReportPanic(r.Context(), report)
+				}
+				if report.Aborted {
+					panic(http.ErrAbortHandler)
+				}
+				writePanicResponse(rec, r, report.RequestID)
+			}()
+			handler.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// writePanicResponse 清除 handler 已经设置的响应头（请求 ID 除外），返回 500 和错误信息。
+func writePanicResponse(w http.ResponseWriter, r *http.Request, requestID string) {
+	h := w.Header()
+	for k := range h {
+		if k != http.CanonicalHeaderKey(RequestIDHeader) {
+			delete(h, k)
+		}
+	}
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "no-store")
+	msg := http.StatusText(http.StatusInternalServerError)
+	if accept := r.Header.Get("Accept"); strings.Contains(accept, "text/html") && !strings.Contains(accept, "application/json") {
+		h.Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><title>%[1]s</title></head><body><h1>%[1]s</h1><p>Request ID: <code>%[2]s</code></p></body></html>\n",
+			msg, html.EscapeString(requestID))
+		return
+	}
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(struct {
+		Error     string `json:"error"`
+		RequestID string `json:"request_id,omitempty"`
+	}{msg, requestID})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RinkoTaketsuki/GolangLearning/example"
+)
+
+// recorderReporter 保存收到的所有报告。
+type recorderReporter struct {
+	mu      sync.Mutex
+	reports []*PanicReport
+}
+
+func (rr *recorderReporter) ReportPanic(_ context.Context, p *PanicReport) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.reports = append(rr.reports, p)
+}
+
+func (rr *recorderReporter) last() *PanicReport {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	if len(rr.reports) == 0 {
+		return nil
+	}
+	return rr.reports[len(rr.reports)-1]
+}
+
+func newPanicMux(reporter PanicReporter, reg *example.Registry) *ServeMux {
+	mux := NewServeMux()
+	mux.Use(WithRequestID, WithPanicRecovery(reporter, reg))
+	mux.HandleFunc("GET /before", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", "100")
+		panic("before WriteHeader")
+	})
+	mux.HandleFunc("GET /after/{n}", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "partial")
+		http.NewResponseController(w).Flush()
+		panic(errors.New("after WriteHeader"))
+	})
+	mux.HandleFunc("GET /abort", func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+	return mux
+}
+
+func TestPanicBeforeWriteHeader(t *testing.T) {
+	reporter := &recorderReporter{}
+	reg := example.NewRegistry(0)
+	mux := newPanicMux(reporter, reg)
+
+	req := httptest.NewRequest("GET", "/before", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("want 500 JSON, but %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("Content-Length") != "" || w.Header().Get(RequestIDHeader) != "req-1" {
+		t.Errorf("headers set by the handler should be dropped except the request ID: %v", w.Header())
+	}
+	var body struct {
+		Error     string `json:"error"`
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.RequestID != "req-1" || body.Error != "Internal Server Error" {
+		t.Errorf("unexpected body %q: %v", w.Body.String(), err)
+	}
+
+	p := reporter.last()
+	if p == nil || p.Value != "before WriteHeader" || p.RequestID != "req-1" || p.Route != "GET /before" || p.Aborted {
+		t.Fatalf("unexpected report %+v", p)
+	}
+	if !strings.Contains(string(p.Stack), "recovery_test.go") {
+		t.Errorf("stack does not contain the panicking handler:\n%s", p.Stack)
+	}
+
+	req = httptest.NewRequest("GET", "/before", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set(RequestIDHeader, "<script>")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("want 500 HTML, but %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if strings.Contains(w.Body.String(), "<script>") || !strings.Contains(w.Body.String(), "&lt;script&gt;") {
+		t.Errorf("request ID not escaped: %s", w.Body.String())
+	}
+
+	if got := reg.Counter("http_panics_total", "", "route").With("GET /before").Value(); got != 2 {
+		t.Errorf("want 2 panics counted for the route, but %v", got)
+	}
+}
+
+func TestPanicAfterWriteHeader(t *testing.T) {
+	reporter := &recorderReporter{}
+	reg := example.NewRegistry(0)
+	mux := newPanicMux(reporter, reg)
+
+	// 响应头已经发出，只能中断连接
+	func() {
+		defer func() {
+			if v := recover(); v != http.ErrAbortHandler {
+				t.Errorf("want panic with http.ErrAbortHandler, but %v", v)
+			}
+		}()
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/after/1", nil))
+	}()
+	if p := reporter.last(); p == nil || !p.Aborted || p.Route != "GET /after/{n}" {
+		t.Errorf("unexpected report %+v", p)
+	}
+
+	// 通过真实的连接检查客户端能够发现响应不完整
+	srv := httptest.NewServer(mux)
+	srv.Config.ErrorLog = nil
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL + "/after/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "partial" || err == nil {
+		t.Errorf("want a truncated 200 response, but %d %q %v", resp.StatusCode, body, err)
+	}
+	if got := reg.Counter("http_panics_total", "", "route").With("GET /after/{n}").Value(); got != 2 {
+		t.Errorf("want 2 panics counted for the route, but %v", got)
+	}
+}
+
+func TestPanicAbortHandler(t *testing.T) {
+	reporter := &recorderReporter{}
+	mux := newPanicMux(reporter, nil)
+	func() {
+		defer func() {
+			if v := recover(); v != http.ErrAbortHandler {
+				t.Errorf("want http.ErrAbortHandler to be re-panicked, but %v", v)
+			}
+		}()
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abort", nil))
+	}()
+	if p := reporter.last(); p != nil {
+		t.Errorf("http.ErrAbortHandler should not be reported: %+v", p)
+	}
+}
+
+func TestPanicAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewAccessLogger(&buf, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := NewServeMux()
+	// 访问日志在恢复中间件外层，记录的是恢复后写出的 500
+	mux.Use(WithRequestID, WithAccessLog(logger), WithPanicRecovery(&recorderReporter{}, nil))
+	mux.HandleFunc("GET /boom", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/boom", nil))
+
+	var entry struct {
+		Level  string `json:"level"`
+		Route  string `json:"route"`
+		Status int    `json:"status"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if entry.Status != http.StatusInternalServerError || entry.Route != "GET /boom" || entry.Level != "ERROR" {
+		t.Errorf("unexpected access log %s", buf.String())
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ResponseRecorder 包装 http.ResponseWriter，记录响应的状态码、body 的字节数和开始的时间，
+// 供访问日志、指标和 panic 恢复使用。它实现了 Unwrap，http.ResponseController 可以透过它
+// 使用底层 ResponseWriter 的 Flush、Hijack 和超时设置。
+type ResponseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int64
+	start  time.Time
+}
+
+// NewResponseRecorder 返回包装 w 的 ResponseRecorder。w 已经是 *ResponseRecorder 时直接返回它，
+// 这样多个中间件共享同一个记录，开始时间以最外层为准。
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	if rec, ok := w.(*ResponseRecorder); ok {
+		return rec
+	}
+	return &ResponseRecorder{ResponseWriter: w, start: time.Now()}
+}
+
+func (rec *ResponseRecorder) WriteHeader(code int) {
+	// 1xx 是中间响应，之后还会有最终的状态码
+	if rec.status == 0 && (code < 100 || code > 199) {
+		rec.status = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *ResponseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.size += int64(n)
+	return n, err
+}
+
+// ReadFrom 让 io.Copy 仍然可以使用底层连接的 sendfile 等优化。
+func (rec *ResponseRecorder) ReadFrom(r io.Reader) (int64, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	var (
+		n   int64
+		err error
+	)
+	if rf, ok := rec.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(rec.ResponseWriter, r)
+	}
+	rec.size += n
+	return n, err
+}
+
+// Flush 实现 http.Flusher，底层不支持时什么都不做。
+func (rec *ResponseRecorder) Flush() {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	http.NewResponseController(rec.ResponseWriter).Flush()
+}
+
+// Hijack 实现 http.Hijacker，被接管的连接上的数据不会被记录。
+func (rec *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(rec.ResponseWriter).Hijack()
+}
+
+func (rec *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Status 返回响应的状态码。handler 没有写入任何东西时返回 200，与 net/http 的行为一致。
+func (rec *ResponseRecorder) Status() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+// Written 返回响应头是否已经发出，之后不能再修改状态码和响应头。
+func (rec *ResponseRecorder) Written() bool {
+	return rec.status != 0
+}
+
+// Size 返回已经写入的 body 的字节数。
+func (rec *ResponseRecorder) Size() int64 {
+	return rec.size
+}
+
+// Elapsed 返回从创建 ResponseRecorder 到现在经过的时间。
+func (rec *ResponseRecorder) Elapsed() time.Duration {
+	return time.Since(rec.start)
+}
@@ -1,32 +1,250 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// ServeMux 在私有的 http.ServeMux 上增加了路由分组、前缀挂载和分层的中间件。
+// 路由使用 Go 1.22 的模式语法 "[METHOD ][HOST]/[PATH]"，路径参数通过 r.PathValue 读取。
+//
+// 中间件分三层，都与注册的先后顺序无关：
+//   - ServeMux.Use 注册的全局中间件包裹整个 mux，404、405 和 OPTIONS 响应也会经过它们；
+//   - Group.Use 注册的中间件作用于分组及其子分组中的所有路由；
+//   - Handle 的 middlewares 参数只作用于这一个路由。
+//
+// 同一层中先注册的中间件在外层。一个路径只注册了部分方法时，其他方法的请求得到 405，
+// OPTIONS 请求得到 204，两者都带有 Allow 头。
 type ServeMux struct {
-	*http.ServeMux
+	root *Group
+
+	mu          sync.Mutex
+	routes      []route
+	middlewares []Middleware
+	// built 是根据当前的路由和中间件构造的 handler，每次修改后重新构造
+	built atomic.Pointer[builtMux]
+}
+
+// route 是一个注册的路由，pattern 已经加上了分组的前缀。
+type route struct {
+	pattern     string
+	method      string
+	handler     http.Handler
+	group       *Group
+	middlewares []Middleware
+}
+
+// Group 是有共同路径前缀和中间件的一组路由，由 ServeMux.Group 或 Group.Group 创建。
+type Group struct {
+	mux         *ServeMux
+	parent      *Group
+	prefix      string
 	middlewares []Middleware
 }
 
 func NewServeMux() *ServeMux {
-	return &ServeMux{ServeMux: http.DefaultServeMux}
+	mux := &ServeMux{}
+	mux.root = &Group{mux: mux}
+	mux.rebuild()
+	return mux
 }
 
-func (mux *ServeMux) ApplyMiddlewares(m ...Middleware) {
+// Use 注册全局中间件。
+func (mux *ServeMux) Use(m ...Middleware) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
 	mux.middlewares = append(mux.middlewares, m...)
+	mux.rebuild()
 }
 
-func (mux *ServeMux) Handle(pattern string, handler http.Handler) {
-	for _, m := range mux.middlewares {
-		handler = m(handler)
+// Handle 注册 pattern 的 handler，middlewares 只作用于这个路由。pattern 与已有的路由冲突时 panic。
+func (mux *ServeMux) Handle(pattern string, handler http.Handler, middlewares ...Middleware) {
+	mux.root.Handle(pattern, handler, middlewares...)
+}
+
+func (mux *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), middlewares ...Middleware) {
+	mux.root.HandleFunc(pattern, handler, middlewares...)
+}
+
+// Group 返回路径前缀为 prefix 的分组。
+func (mux *ServeMux) Group(prefix string, middlewares ...Middleware) *Group {
+	return mux.root.Group(prefix, middlewares...)
+}
+
+// Mount 把 handler 挂载到 prefix 下，见 Group.Mount。
+func (mux *ServeMux) Mount(prefix string, handler http.Handler, middlewares ...Middleware) {
+	mux.root.Mount(prefix, handler, middlewares...)
+}
+
+func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mux.built.Load().handler.ServeHTTP(w, r)
+}
+
+// Group 返回 g 的子分组，路径前缀为 g 的前缀加上 prefix，middlewares 是子分组的中间件。
+// prefix 必须以 / 开头，结尾的 / 被忽略。
+func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{mux: g.mux, parent: g, prefix: g.prefix + cleanPrefix(prefix), middlewares: slices.Clone(middlewares)}
+}
+
+// Use 为分组注册中间件，对已经注册和之后注册的路由都有效。
+func (g *Group) Use(m ...Middleware) {
+	g.mux.mu.Lock()
+	defer g.mux.mu.Unlock()
+	g.middlewares = append(g.middlewares, m...)
+	g.mux.rebuild()
+}
+
+// Handle 注册 pattern 的 handler，pattern 的路径部分被加上分组的前缀，例如前缀为 /api 的分组中
+// "GET /users/{id}" 注册为 "GET /api/users/{id}"。pattern 与已有的路由冲突时 panic。
+func (g *Group) Handle(pattern string, handler http.Handler, middlewares ...Middleware) {
+	if handler == nil {
+		panic("nil handler")
 	}
-	mux.ServeMux.Handle(pattern, handler)
+	method, host, path := splitPattern(pattern)
+	g.mux.addRoute(route{
+		pattern:     joinPattern(method, host, g.prefix+path),
+		method:      method,
+		handler:     handler,
+		group:       g,
+		middlewares: slices.Clone(middlewares),
+	})
 }
 
-func (mux *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+func (g *Group) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), middlewares ...Middleware) {
 	if handler == nil {
 		panic("nil handler")
 	}
-	mux.Handle(pattern, http.HandlerFunc(handler))
+	g.Handle(pattern, http.HandlerFunc(handler), middlewares...)
+}
+
+// Mount 把 handler 挂载到 prefix 下：所有方法的 prefix 及其下的请求都交给 handler，
+// 请求的路径去掉了分组和 prefix 的前缀，可以用来挂载另一个 ServeMux 或 http.FileServer。
+// prefix 不能包含路径参数。
+func (g *Group) Mount(prefix string, handler http.Handler, middlewares ...Middleware) {
+	p := cleanPrefix(prefix)
+	full := g.prefix + p
+	if strings.ContainsAny(full, "{}") {
+		panic(fmt.Sprintf("Mount: prefix %q contains wildcards", full))
+	}
+	g.Handle(p+"/", http.StripPrefix(full, handler), middlewares...)
+}
+
+// cleanPrefix 检查并规范化分组的前缀，返回不以 / 结尾的前缀，根路径返回 ""。
+func cleanPrefix(prefix string) string {
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		panic(fmt.Sprintf("prefix %q does not start with /", prefix))
+	}
+	return strings.TrimRight(prefix, "/")
+}
+
+// splitPattern 把 "[METHOD ][HOST]/[PATH]" 拆为方法、主机和路径三部分。
+func splitPattern(pattern string) (method, host, path string) {
+	rest := pattern
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		method, rest = pattern[:i], strings.TrimLeft(pattern[i:], " \t")
+	}
+	i := strings.IndexByte(rest, '/')
+	if i < 0 {
+		panic(fmt.Sprintf("pattern %q has no path", pattern))
+	}
+	return method, rest[:i], rest[i:]
+}
+
+func joinPattern(method, host, path string) string {
+	if method == "" {
+		return host + path
+	}
+	return method + " " + host + path
+}
+
+func (mux *ServeMux) addRoute(rt route) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.routes = append(mux.routes, rt)
+	defer func() {
+		// 与已有的路由冲突时撤销注册，再把 http.ServeMux 的 panic 传给调用者
+		if err := recover(); err != nil {
+			mux.routes = mux.routes[:len(mux.routes)-1]
+			panic(err)
+		}
+	}()
+	mux.rebuild()
+}
+
+// chain 用 middlewares 包裹 h，middlewares[0] 在最外层。
+func chain(h http.Handler, middlewares []Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// rebuild 根据当前的路由和中间件构造新的 http.ServeMux，调用时需要持有 mux.mu。
+func (mux *ServeMux) rebuild() {
+	b := &builtMux{mux: http.NewServeMux()}
+	methods := map[string]bool{}
+	for _, rt := range mux.routes {
+		h := chain(rt.handler, rt.middlewares)
+		for g := rt.group; g != nil; g = g.parent {
+			h = chain(h, g.middlewares)
+		}
+		b.mux.Handle(rt.pattern, h)
+		if rt.method != "" {
+			methods[rt.method] = true
+		}
+	}
+	if methods[http.MethodGet] {
+		methods[http.MethodHead] = true
+	}
+	for m := range methods {
+		b.methods = append(b.methods, m)
+	}
+	slices.Sort(b.methods)
+	b.handler = chain(b, mux.middlewares)
+	mux.built.Store(b)
+}
+
+// builtMux 在 http.ServeMux 的基础上处理 405 和 OPTIONS。
+type builtMux struct {
+	mux *http.ServeMux
+	// methods 是所有路由中出现过的方法，用于计算 Allow 头
+	methods []string
+	// handler 是被全局中间件包裹的 builtMux
+	handler http.Handler
+}
+
+func (b *builtMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// pattern 为空说明没有匹配的路由，http.ServeMux 会返回 404 或 405
+	if _, pattern := b.mux.Handler(r); pattern == "" {
+		if allow := b.allowed(r); len(allow) > 0 {
+			w.Header().Set("Allow", strings.Join(append(allow, http.MethodOptions), ", "))
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+	}
+	b.mux.ServeHTTP(w, r)
+}
+
+// allowed 返回 r 的路径可以使用的方法。
+func (b *builtMux) allowed(r *http.Request) []string {
+	var allow []string
+	for _, m := range b.methods {
+		if m == r.Method || m == http.MethodOptions {
+			continue
+		}
+		probe := r.WithContext(r.Context())
+		probe.Method = m
+		if _, pattern := b.mux.Handler(probe); pattern != "" {
+			allow = append(allow, m)
+		}
+	}
+	return allow
 }
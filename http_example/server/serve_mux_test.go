@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// tag 返回在响应头 X-Trace 中追加 name 的中间件，用来检查中间件的顺序。
+func tag(name string) Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func text(s string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, s+r.PathValue("id"))
+	}
+}
+
+func serve(h http.Handler, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestServeMuxRouting(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("GET /{$}", text("index"))
+	api := mux.Group("/api/", tag("api"))
+	api.HandleFunc("GET /users/{id}", text("get "), tag("route"))
+	api.HandleFunc("DELETE /users/{id}", text("delete "))
+	v2 := api.Group("/v2")
+	v2.HandleFunc("POST /users", text("create"))
+
+	sub := NewServeMux()
+	sub.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "sub "+r.URL.Path)
+	})
+	mux.Mount("/sub", sub, tag("mount"))
+
+	// 中间件在路由之后注册也应该生效
+	mux.Use(tag("global1"), tag("global2"))
+	v2.Use(tag("v2"))
+
+	testcases := []struct {
+		method, target string
+		status         int
+		body, trace    string
+		allow          string
+	}{
+		{"GET", "/", 200, "index", "global1,global2", ""},
+		{"GET", "/api/users/42", 200, "get 42", "global1,global2,api,route", ""},
+		{"HEAD", "/api/users/42", 200, "get 42", "global1,global2,api,route", ""}, // ResponseRecorder 不丢弃 HEAD 的响应体
+		{"DELETE", "/api/users/7", 200, "delete 7", "global1,global2,api", ""},
+		{"POST", "/api/v2/users", 200, "create", "global1,global2,api,v2", ""},
+		{"GET", "/sub/hello", 200, "sub /hello", "global1,global2,mount", ""},
+		{"PUT", "/api/users/1", 405, "Method Not Allowed\n", "global1,global2", "DELETE, GET, HEAD, OPTIONS"},
+		{"OPTIONS", "/api/users/1", 204, "", "global1,global2", "DELETE, GET, HEAD, OPTIONS"},
+		{"GET", "/api/v2/users", 405, "Method Not Allowed\n", "global1,global2", "POST, OPTIONS"},
+		{"GET", "/nowhere", 404, "404 page not found\n", "global1,global2", ""},
+	}
+	for _, tc := range testcases {
+		w := serve(mux, tc.method, tc.target)
+		desc := tc.method + " " + tc.target
+		if w.Code != tc.status || w.Body.String() != tc.body {
+			t.Errorf("%s: want %d %q, but %d %q", desc, tc.status, tc.body, w.Code, w.Body.String())
+		}
+		if trace := strings.Join(w.Header().Values("X-Trace"), ","); trace != tc.trace {
+			t.Errorf("%s: want middlewares %s, but %s", desc, tc.trace, trace)
+		}
+		if allow := w.Header().Get("Allow"); allow != tc.allow {
+			t.Errorf("%s: want Allow %q, but %q", desc, tc.allow, allow)
+		}
+	}
+}
+
+func TestServeMuxIsolation(t *testing.T) {
+	a, b := NewServeMux(), NewServeMux()
+	a.HandleFunc("/only-a", text("a"))
+	if w := serve(b, "GET", "/only-a"); w.Code != http.StatusNotFound {
+		t.Errorf("route leaked to another ServeMux: %d", w.Code)
+	}
+	// 同一个 pattern 可以在不同的 ServeMux 中注册
+	b.HandleFunc("/only-a", text("b"))
+	if w := serve(a, "GET", "/only-a"); w.Body.String() != "a" {
+		t.Errorf("want a, but %q", w.Body.String())
+	}
+	if w := serve(http.DefaultServeMux, "GET", "/only-a"); w.Code != http.StatusNotFound {
+		t.Errorf("route registered in http.DefaultServeMux: %d", w.Code)
+	}
+}
+
+func TestServeMuxConflict(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("GET /x/{id}", text("x"))
+	func() {
+		defer func() {
+			if err := recover(); err == nil || !strings.Contains(fmt.Sprint(err), "conflicts") {
+				t.Errorf("want conflict panic, but %v", err)
+			}
+		}()
+		mux.Group("/x").HandleFunc("GET /{name}", text("y"))
+	}()
+	// 冲突的路由被撤销，mux 仍然可用
+	mux.HandleFunc("GET /y", text("y"))
+	if w := serve(mux, "GET", "/x/1"); w.Body.String() != "x1" {
+		t.Errorf("want x1, but %q", w.Body.String())
+	}
+}
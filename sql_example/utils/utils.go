@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"runtime"
 
+	"github.com/RinkoTaketsuki/GolangLearning/example/pretty"
 	"github.com/VividCortex/mysqlerr"
 	"github.com/go-sql-driver/mysql"
 )
@@ -108,51 +109,32 @@ func (db *DB) QueryResultPrintln(query string, args ...any) {
 	columnTypes, err := rows.ColumnTypes()
 	ErrHandler(err)
 
-	maxColNameLen := 0
-	maxColTypeNameLen := 0
-	for _, ct := range columnTypes {
-		colNameLen := len(ct.Name())
-		colTypeNameLen := len(ct.DatabaseTypeName())
-		if colNameLen > maxColNameLen {
-			maxColNameLen = colNameLen
-		}
-		if colTypeNameLen > maxColTypeNameLen {
-			maxColTypeNameLen = colTypeNameLen
-		}
+	header := make([]string, len(columnTypes))
+	for i, ct := range columnTypes {
+		header[i] = fmt.Sprintf("%s (%s)", ct.Name(), ct.DatabaseTypeName())
 	}
-	maxColNameLen = (((maxColNameLen + 1) / 8) + 1) * 8
-	maxColTypeNameLen = ((maxColTypeNameLen / 8) + 1) * 8
+	table := pretty.NewTable(header...)
 
 	vals := make([]any, len(columnTypes))
 	for i := range vals {
-		vals[i] = new(sql.RawBytes) // 使用 %s 输出支持 UTF-8 字符
+		vals[i] = new(sql.RawBytes) // RawBytes 可以接收任意类型的列
 	}
-
-	for rowCnt := 1; rows.Next(); rowCnt++ {
+	cells := make([]any, len(vals))
+	for rows.Next() {
 		ErrHandler(rows.Scan(vals...))
-		fmt.Println("----------", rowCnt, "----------")
 		for i, val := range vals {
-			data := *(val.(*sql.RawBytes))
-			if data == nil {
-				for n, _ := fmt.Printf("%s:", columnTypes[i].Name()); n < maxColNameLen; n++ {
-					fmt.Printf(" ")
-				}
-				for n, _ := fmt.Printf("%s", columnTypes[i].DatabaseTypeName()); n < maxColTypeNameLen; n++ {
-					fmt.Printf(" ")
-				}
-				fmt.Println("<NULL>")
+			// RawBytes 在下一次 Next 时失效，需要复制为 string
+			if data := *(val.(*sql.RawBytes)); data == nil {
+				cells[i] = "<NULL>"
 			} else {
-				for n, _ := fmt.Printf("%s:", columnTypes[i].Name()); n < maxColNameLen; n++ {
-					fmt.Printf(" ")
-				}
-				for n, _ := fmt.Printf("%s", columnTypes[i].DatabaseTypeName()); n < maxColTypeNameLen; n++ {
-					fmt.Printf(" ")
-				}
-				fmt.Printf("[ %s ]\n", data)
+				cells[i] = string(data)
 			}
 		}
+		table.AddRow(cells...)
 	}
 	ErrHandler(rows.Err())
+	fmt.Print(table)
+	fmt.Printf("%d rows\n", table.Len())
 }
 
 func (db *DB) QueryOneRow(prepare bool, query string, args []any, dest ...any) (has bool) {